@@ -19,14 +19,18 @@ import (
 	"github.com/runar-rkmedia/gabyoall/api/bboltStorage"
 	_ "github.com/runar-rkmedia/gabyoall/api/docs"
 	"github.com/runar-rkmedia/gabyoall/api/handlers"
+	"github.com/runar-rkmedia/gabyoall/api/importer"
 	"github.com/runar-rkmedia/gabyoall/api/requestContext"
 	"github.com/runar-rkmedia/gabyoall/api/scheduler"
 	"github.com/runar-rkmedia/gabyoall/api/types"
 	"github.com/runar-rkmedia/gabyoall/api/utils"
 	"github.com/runar-rkmedia/gabyoall/auth"
 	"github.com/runar-rkmedia/gabyoall/cmd"
+	"github.com/runar-rkmedia/gabyoall/compare"
 	"github.com/runar-rkmedia/gabyoall/frontend"
 	"github.com/runar-rkmedia/gabyoall/logger"
+	"github.com/runar-rkmedia/gabyoall/metrics"
+	"github.com/runar-rkmedia/gabyoall/requests"
 )
 
 var (
@@ -143,15 +147,18 @@ func main() {
 		Interval:   time.Second * 15,
 	}, logger.GetLogger("self-check"), 0)
 
+	runMetrics := metrics.NewMetrics()
 	if true {
 
 		s := scheduler.NewScheduler(l, &db, cmd.GetConfig(l))
+		s.Metrics = runMetrics
 
 		s.Run()
 	}
 	address := fmt.Sprintf("%s:%d", cfg.Address, cfg.Port)
 	handler := http.NewServeMux()
 	statsviz.Register(handler)
+	handler.Handle("/metrics", runMetrics)
 	handler.Handle("/debug/pprof/", http.HandlerFunc(pprof.Index))
 	handler.Handle("/debug/pprof/cmdline", http.HandlerFunc(pprof.Cmdline))
 	handler.Handle("/debug/pprof/profile", http.HandlerFunc(pprof.Profile))
@@ -293,6 +300,10 @@ func EndpointsHandler(ctx requestContext.Context) http.HandlerFunc {
 				if err := rc.ValidateBytes(body, &input); err != nil {
 					return
 				}
+				if err := input.Validate(); err != nil {
+					rc.WriteErr(err, requestContext.CodeErrInputValidation)
+					return
+				}
 				e, err := ctx.DB.CreateEndpoint(input)
 				rc.WriteAuto(e, err, requestContext.CodeErrDBCreateEndpoint)
 				return
@@ -315,6 +326,10 @@ func EndpointsHandler(ctx requestContext.Context) http.HandlerFunc {
 				if err := rc.ValidateBytes(body, &input); err != nil {
 					return
 				}
+				if err := input.Validate(); err != nil {
+					rc.WriteErr(err, requestContext.CodeErrInputValidation)
+					return
+				}
 				e, err := ctx.DB.UpdateEndpoint(paths[1], input)
 				rc.WriteAuto(e, err, requestContext.CodeErrDBUpdateEndpoint)
 				return
@@ -342,6 +357,28 @@ func EndpointsHandler(ctx requestContext.Context) http.HandlerFunc {
 				rc.WriteAuto(result, nil, "err-dry-dynamic")
 				return
 			}
+		case "import":
+			// Import a Postman or Insomnia-collection
+			if isPost && len(paths) == 1 {
+				if body == nil {
+					rc.WriteError("Body was nil", requestContext.CodeErrReadBody)
+					return
+				}
+				result, err := importer.Parse(body)
+				if err != nil {
+					rc.WriteErr(err, requestContext.CodeErrImport)
+					return
+				}
+				summary, err := importer.Store(ctx.DB, result)
+				if err != nil && summary.Created() {
+					// The user needs to know what was created, to either use or delete those
+					summary.Error = err.Error()
+					rc.WriteOutput(summary, http.StatusBadGateway)
+					return
+				}
+				rc.WriteAuto(summary, err, requestContext.CodeErrImport)
+				return
+			}
 		case "request":
 			// Create request
 			if isPost && len(paths) == 1 {
@@ -349,6 +386,10 @@ func EndpointsHandler(ctx requestContext.Context) http.HandlerFunc {
 				if err := rc.ValidateBytes(body, &input); err != nil {
 					return
 				}
+				if err := input.Validate(); err != nil {
+					rc.WriteErr(err, requestContext.CodeErrInputValidation)
+					return
+				}
 				e, err := ctx.DB.CreateRequest(input)
 				rc.WriteAuto(e, err, requestContext.CodeErrDBCreateRequest)
 				return
@@ -372,6 +413,10 @@ func EndpointsHandler(ctx requestContext.Context) http.HandlerFunc {
 					// rc.WriteErr(err, requestContext.CodeErrDBUpdateRequest)
 					return
 				}
+				if err := input.Validate(); err != nil {
+					rc.WriteErr(err, requestContext.CodeErrInputValidation)
+					return
+				}
 				e, err := ctx.DB.UpdateRequest(paths[1], input)
 				rc.WriteAuto(e, err, requestContext.CodeErrDBUpdateRequest)
 				return
@@ -382,6 +427,41 @@ func EndpointsHandler(ctx requestContext.Context) http.HandlerFunc {
 				rc.WriteAuto(e, err, requestContext.CodeErrDBDeleteRequest)
 				return
 			}
+			// Set golden response of request, from a stored run
+			if isPost && len(paths) == 3 && paths[2] == "golden" {
+				var input types.GoldenPayload
+				if err := rc.ValidateBytes(body, &input); err != nil {
+					return
+				}
+				var hash *requests.Hash
+				if input.Hash != "" {
+					h, err := requests.ParseHash(input.Hash)
+					if err != nil {
+						rc.WriteErr(err, requestContext.CodeErrInputValidation)
+						return
+					}
+					hash = &h
+				}
+				stat, err := ctx.DB.CompactStat(input.StatID)
+				if err != nil {
+					rc.WriteErr(err, requestContext.CodeErrRequest)
+					return
+				}
+				golden, err := requests.NewGoldenResponse(stat.ResponseHashMap, hash, input.StatID)
+				if err != nil {
+					rc.WriteErr(err, requestContext.CodeErrInputValidation)
+					return
+				}
+				e, err := ctx.DB.SetGoldenResponse(paths[1], golden)
+				rc.WriteAuto(e, err, requestContext.CodeErrDBUpdateRequest)
+				return
+			}
+			// Remove golden response of request
+			if isDelete && len(paths) == 3 && paths[2] == "golden" {
+				e, err := ctx.DB.SetGoldenResponse(paths[1], nil)
+				rc.WriteAuto(e, err, requestContext.CodeErrDBUpdateRequest)
+				return
+			}
 		case "stat":
 			// List stats
 			if isGet && len(paths) == 1 {
@@ -396,6 +476,90 @@ func EndpointsHandler(ctx requestContext.Context) http.HandlerFunc {
 				}{err}, err, requestContext.CodeErrRequest)
 				return
 			}
+			// Compare two stats, like before and after a deploy
+			if isGet && len(paths) == 2 && paths[1] == "compare" {
+				q := r.URL.Query()
+				var runs [2]compare.Run
+				for i, id := range []string{q.Get("a"), q.Get("b")} {
+					if id == "" {
+						rc.WriteErr(fmt.Errorf("both the query-parameters a and b are required"), requestContext.CodeErrInputValidation)
+						return
+					}
+					e, err := ctx.DB.CompactStat(id)
+					if err != nil {
+						rc.WriteErr(err, requestContext.CodeErrRequest)
+						return
+					}
+					runs[i] = compare.NewRun(id, e.Histograms, e.Throughput.Elapsed())
+				}
+				rc.WriteOutput(compare.Compare(runs[0], runs[1]), http.StatusOK)
+				return
+			}
+			// Get stat
+			if isGet && len(paths) == 2 {
+				e, err := ctx.DB.CompactStat(paths[1])
+				rc.WriteAuto(e, err, requestContext.CodeErrRequest)
+				return
+			}
+			// Self-contained html-report of a stat
+			if isGet && len(paths) == 3 && paths[2] == "report" {
+				e, err := ctx.DB.CompactStat(paths[1])
+				if err != nil {
+					rc.WriteErr(err, requestContext.CodeErrRequest)
+					return
+				}
+				title := e.Title
+				if title == "" {
+					title = e.RunID
+				}
+				// The config is stored with the run when it starts, so older runs have none
+				var config interface{}
+				if len(e.Config) > 0 {
+					config = e.Config
+				}
+				b, err := e.HTMLReport(title, config).MarshalHTML()
+				if err != nil {
+					rc.WriteErr(err, requestContext.CodeErrRequest)
+					return
+				}
+				rc.Rw.Header().Set("Content-Type", "text/html; charset=utf-8")
+				rc.Rw.WriteHeader(http.StatusOK)
+				rc.Rw.Write(b)
+				return
+			}
+			// Diff between two response-variants of a stat
+			if isGet && len(paths) == 3 && paths[2] == "diff" {
+				e, err := ctx.DB.CompactStat(paths[1])
+				if err != nil {
+					rc.WriteErr(err, requestContext.CodeErrRequest)
+					return
+				}
+				q := r.URL.Query()
+				a, err := requests.ParseHash(q.Get("a"))
+				if err != nil {
+					rc.WriteErr(err, requestContext.CodeErrInputValidation)
+					return
+				}
+				b, err := requests.ParseHash(q.Get("b"))
+				if err != nil {
+					rc.WriteErr(err, requestContext.CodeErrInputValidation)
+					return
+				}
+				d, err := e.ResponseHashMap.Diff(a, b)
+				rc.WriteAuto(d, err, requestContext.CodeErrInputValidation)
+				return
+			}
+			// Summary of all response-variants of a stat
+			if isGet && len(paths) == 3 && paths[2] == "variants" {
+				e, err := ctx.DB.CompactStat(paths[1])
+				if err != nil {
+					rc.WriteErr(err, requestContext.CodeErrRequest)
+					return
+				}
+				summary, err := e.ResponseHashMap.Summarize()
+				rc.WriteAuto(summary, err, requestContext.CodeErrRequest)
+				return
+			}
 		case "schedule":
 			// Create schedule
 			if isPost && len(paths) == 1 {
@@ -403,6 +567,10 @@ func EndpointsHandler(ctx requestContext.Context) http.HandlerFunc {
 				if err := rc.ValidateBytes(body, &input); err != nil {
 					return
 				}
+				if err := input.Config.Validate(); err != nil {
+					rc.WriteErr(err, requestContext.CodeErrInputValidation)
+					return
+				}
 				e, err := ctx.DB.CreateSchedule(input)
 				rc.WriteAuto(e, err, requestContext.CodeErrDBCreateSchedule)
 				return
@@ -413,6 +581,10 @@ func EndpointsHandler(ctx requestContext.Context) http.HandlerFunc {
 				if err := rc.ValidateBytes(body, &input); err != nil {
 					return
 				}
+				if err := input.Config.Validate(); err != nil {
+					rc.WriteErr(err, requestContext.CodeErrInputValidation)
+					return
+				}
 				e, err := ctx.DB.UpdateSchedule(paths[1], types.Schedule{SchedulePayload: input})
 				rc.WriteAuto(e, err, requestContext.CodeErrDBUpdateSchedule)
 				return
@@ -423,6 +595,20 @@ func EndpointsHandler(ctx requestContext.Context) http.HandlerFunc {
 				rc.WriteAuto(es, err, requestContext.CodeErrSchedule)
 				return
 			}
+			// Score of each run of the schedule over time
+			if isGet && len(paths) == 3 && paths[2] == "trend" {
+				if _, err := ctx.DB.Schedule(paths[1]); err != nil {
+					rc.WriteErr(err, requestContext.CodeErrSchedule)
+					return
+				}
+				stats, err := ctx.DB.CompactStats()
+				if err != nil {
+					rc.WriteErr(err, requestContext.CodeErrRequest)
+					return
+				}
+				rc.WriteOutput(types.NewScheduleTrend(paths[1], stats), http.StatusOK)
+				return
+			}
 			// Get schedule
 			if isGet && len(paths) == 2 {
 				es, err := ctx.DB.Schedule(paths[1])
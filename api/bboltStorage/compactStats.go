@@ -66,6 +66,11 @@ func (s *BBolter) CleanCompactStats() error {
 	return err
 }
 
+func (s *BBolter) CompactStat(id string) (e types.StatEntity, err error) {
+	err = s.GetItem(BucketStats, id, &e)
+	return
+}
+
 func (s *BBolter) CompactStats() (es map[string]types.StatEntity, err error) {
 	es = map[string]types.StatEntity{}
 	err = s.View(func(tx *bolt.Tx) error {
@@ -20,8 +20,10 @@ func (s *BBolter) CreateEndpoint(p types.EndpointPayload) (types.EndpointEntity,
 	e := types.EndpointEntity{
 		Endpoint: types.Endpoint{
 			Endpoint: requests.Endpoint{
-				Url:     p.Url,
-				Headers: http.Header(p.Headers),
+				Url:           p.Url,
+				Headers:       http.Header(p.Headers),
+				Normalization: p.Normalization,
+				SLO:           p.SLO,
 			},
 			Config: p.Config,
 		},
@@ -80,8 +82,10 @@ func (s *BBolter) UpdateEndpoint(id string, p types.EndpointPayload) (types.Endp
 	}
 	endpoint := types.Endpoint{
 		Endpoint: requests.Endpoint{
-			Url:     p.Url,
-			Headers: p.Headers,
+			Url:           p.Url,
+			Headers:       p.Headers,
+			Normalization: p.Normalization,
+			SLO:           p.SLO,
 		},
 		Config: p.Config,
 	}
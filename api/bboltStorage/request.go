@@ -17,14 +17,21 @@ func (s *BBolter) Request(id string) (e types.RequestEntity, err error) {
 func (s *BBolter) CreateRequest(p types.RequestPayload) (types.RequestEntity, error) {
 	e := types.RequestEntity{
 		Request: requests.Request{
-			Body:          p.Body,
-			Query:         p.Query,
-			Variables:     p.Variables,
-			Headers:       p.Headers,
-			OperationName: p.OperationName,
-			Method:        p.Method,
+			Body:           p.Body,
+			Query:          p.Query,
+			Variables:      p.Variables,
+			Headers:        p.Headers,
+			OperationName:  p.OperationName,
+			Method:         p.Method,
+			Capture:        p.Capture,
+			Assertions:     p.Assertions,
+			ResponseSchema: p.ResponseSchema,
+			Normalization:  p.Normalization,
+			SLO:            p.SLO,
 		},
 		Config: p.Config,
+		Label:  p.Label,
+		Tags:   p.Tags,
 		Entity: s.NewEntity(),
 	}
 
@@ -49,14 +56,21 @@ func (s *BBolter) UpdateRequest(id string, p types.RequestPayload) (types.Reques
 	}
 	request := types.RequestEntity{
 		Request: requests.Request{
-			Body:          p.Body,
-			Query:         p.Query,
-			Variables:     p.Variables,
-			Headers:       p.Headers,
-			OperationName: p.OperationName,
-			Method:        p.Method,
+			Body:           p.Body,
+			Query:          p.Query,
+			Variables:      p.Variables,
+			Headers:        p.Headers,
+			OperationName:  p.OperationName,
+			Method:         p.Method,
+			Capture:        p.Capture,
+			Assertions:     p.Assertions,
+			ResponseSchema: p.ResponseSchema,
+			Normalization:  p.Normalization,
+			SLO:            p.SLO,
 		},
 		Config: p.Config,
+		Label:  p.Label,
+		Tags:   p.Tags,
 	}
 	err := s.Update(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket(BucketRequests)
@@ -73,6 +87,12 @@ func (s *BBolter) UpdateRequest(id string, p types.RequestPayload) (types.Reques
 		if err != nil {
 			return err
 		}
+		if request.Label != "" {
+			j.Label = request.Label
+		}
+		if request.Tags != nil {
+			j.Tags = request.Tags
+		}
 		if p.Config != nil {
 			if j.Config == nil {
 				j.Config = &types.Config{}
@@ -150,6 +170,22 @@ func (s *BBolter) softDeleteRequest(id string, delete *bool) (j types.RequestEnt
 	return
 }
 
+func (s *BBolter) SetGoldenResponse(id string, golden *requests.GoldenResponse) (j types.RequestEntity, err error) {
+	err = s.updater(id, BucketRequests, func(b []byte) ([]byte, error) {
+		if err := s.Unmarshal(b, &j); err != nil {
+			return nil, err
+		}
+		now := time.Now()
+		j.Golden = golden
+		j.UpdatedAt = &now
+		return s.Marshal(j)
+	})
+	if err == nil {
+		s.PublishChange(PubTypeRequest, PubVerbUpdate, j)
+	}
+	return
+}
+
 func (s *BBolter) SoftDeleteRequest(id string) (j types.RequestEntity, err error) {
 	return s.softDeleteRequest(id, nil)
 }
@@ -0,0 +1,61 @@
+package bboltStorage
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/runar-rkmedia/gabyoall/api/types"
+	"github.com/runar-rkmedia/gabyoall/internal"
+	"github.com/runar-rkmedia/gabyoall/logger"
+)
+
+func TestBBolter_CreateRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+	}{
+		{
+			"assertions with object- and array-equals",
+			`{"label": "user", "assertions": [
+				{"kind": "jmes-path", "jmes_path": "data.user", "equals": {"name": "john"}},
+				{"kind": "jmes-path", "jmes_path": "data.roles", "equals": ["admin"]}
+			]}`,
+		},
+		{
+			"inline object-schema",
+			`{"label": "user", "responseSchema": {"schema": {"type": "object", "required": ["data"]}, "sampleRate": 0.5}}`,
+		},
+		{
+			"inline schema as a json-string",
+			`{"label": "user", "responseSchema": {"schema": "{\"type\": \"object\"}"}}`,
+		},
+	}
+	db, err := NewBbolt(logger.GetLogger("test"), filepath.Join(t.TempDir(), "db.bbolt"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var p types.RequestPayload
+			if err := json.Unmarshal([]byte(tt.payload), &p); err != nil {
+				t.Fatal(err)
+			}
+			if err := p.Validate(); err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			created, err := db.CreateRequest(p)
+			if err != nil {
+				t.Fatalf("CreateRequest() error = %v", err)
+			}
+			got, err := db.Request(created.ID)
+			if err != nil {
+				t.Fatalf("Request() error = %v", err)
+			}
+			if err := internal.Compare("Request()", got.Request, created.Request); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
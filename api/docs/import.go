@@ -0,0 +1,28 @@
+// swagger:route POST /import import importCollection
+// Imports a Postman (v2.1) collection or an Insomnia (v4) export as endpoints and requests.
+// Anything which could not be imported, like pre-request-scripts, is listed in the summary.
+// responses:
+//   200: importResponse
+//   400: apiError
+//   500: apiError
+
+package docs
+
+import (
+	"github.com/runar-rkmedia/gabyoall/api/importer"
+)
+
+// Summary of the import
+// swagger:response importResponse
+type importResponse struct {
+	// in:body
+	Body importer.Summary
+}
+
+// swagger:parameters importCollection
+type importCollection struct {
+	// The exported collection, as json.
+	// in: body
+	// required: true
+	Body interface{}
+}
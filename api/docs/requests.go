@@ -16,6 +16,20 @@
 //   200: okResponse
 //   404: apiError
 //   500: apiError
+
+// swagger:route POST /request/{id}/golden request setGoldenResponse
+// Sets the golden response of the request, from a stored run.
+// Each later run compares its normalized responses to it, and mismatches fail as GoldenMismatch.
+// responses:
+//   200: requestResponse
+//   400: apiError
+//   404: apiError
+
+// swagger:route DELETE /request/{id}/golden request deleteGoldenResponse
+// Removes the golden response of the request.
+// responses:
+//   200: requestResponse
+//   404: apiError
 package docs
 
 import (
@@ -51,3 +65,18 @@ type createRequest struct {
 	// required: true
 	Body types.RequestPayload
 }
+
+// swagger:parameters setGoldenResponse
+type setGoldenResponse struct {
+	// in: path
+	ID string `json:"id"`
+	// in: body
+	// required: true
+	Body types.GoldenPayload
+}
+
+// swagger:parameters deleteGoldenResponse
+type deleteGoldenResponse struct {
+	// in: path
+	ID string `json:"id"`
+}
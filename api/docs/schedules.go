@@ -23,6 +23,13 @@
 //   200: scheduleResponse
 //   404: apiError
 //   500: apiError
+
+// swagger:route GET /schedule/{id}/trend schedule getScheduleTrend
+// Returns the Apdex-score, SLO-compliance, error-rate and percentiles of each run of the schedule, oldest first.
+// responses:
+//   200: scheduleTrendResponse
+//   404: apiError
+//   500: apiError
 package docs
 
 import (
@@ -65,3 +72,16 @@ type updateSchedule struct {
 	// required: true
 	Body types.SchedulePayload
 }
+
+// The runs of a schedule over time
+// swagger:response scheduleTrendResponse
+type scheduleTrendResponse struct {
+	// in:body
+	Body types.ScheduleTrend
+}
+
+// swagger:parameters getScheduleTrend
+type getScheduleTrendParams struct {
+	// in: path
+	ID string `json:"id"`
+}
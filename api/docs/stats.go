@@ -11,10 +11,43 @@
 //   404: apiError
 //   500: apiError
 
+// swagger:route GET /stat/{id}/diff stat getStatDiff
+// Returns a structured diff between two response-variants of a stat, by their hashes in the responseHashMap.
+// responses:
+//   200: statDiffResponse
+//   400: apiError
+//   404: apiError
+
+// swagger:route GET /stat/{id}/variants stat getStatVariants
+// Clusters all response-variants of a stat by which json-paths differ from the most common variant.
+// responses:
+//   200: statVariantsResponse
+//   404: apiError
+
+// swagger:route GET /stat/{id}/report stat getStatReport
+// Returns a self-contained html-report of a stat, with a summary, latency- and throughput-charts and the sampled error-responses.
+// The config of the schedule which started the run is included, with its secrets redacted.
+// produces:
+// - text/html
+// responses:
+//   200: statReportResponse
+//   404: apiError
+
+// swagger:route GET /stat/compare stat compareStats
+// Compares two stats, like before and after a deploy.
+// Reports the deltas of throughput, error-rate and latency-percentiles,
+// and whether the latencies of the successful requests differ significantly (Mann-Whitney U).
+// responses:
+//   200: statCompareResponse
+//   400: apiError
+//   404: apiError
+
 package docs
 
 import (
 	"github.com/runar-rkmedia/gabyoall/api/types"
+	"github.com/runar-rkmedia/gabyoall/compare"
+	"github.com/runar-rkmedia/gabyoall/requests"
 )
 
 // Lists stats registered
@@ -39,3 +72,69 @@ type getStatParams struct {
 	// example: abc123
 	ID string `json:"id"`
 }
+
+// Returns the diff between two response-variants
+// swagger:response statDiffResponse
+type statDiffResponse struct {
+	// in:body
+	Body requests.ResponseDiff
+}
+
+// Returns the response-variants, clustered by which json-paths differ
+// swagger:response statVariantsResponse
+type statVariantsResponse struct {
+	// in:body
+	Body requests.VariantSummary
+}
+
+// swagger:parameters getStatVariants
+type getStatVariantsParams struct {
+	// in: path
+	ID string `json:"id"`
+}
+
+// swagger:parameters getStatDiff
+type getStatDiffParams struct {
+	// in: path
+	ID string `json:"id"`
+	// Hash of the first response, as in the responseHashMap
+	// in: query
+	// required: true
+	A string `json:"a"`
+	// Hash of the second response, as in the responseHashMap
+	// in: query
+	// required: true
+	B string `json:"b"`
+}
+
+// Returns the comparison of two stats
+// swagger:response statCompareResponse
+type statCompareResponse struct {
+	// in:body
+	Body compare.Result
+}
+
+// swagger:parameters compareStats
+type compareStatsParams struct {
+	// ID of the first stat, typically from before the change
+	// in: query
+	// required: true
+	A string `json:"a"`
+	// ID of the second stat
+	// in: query
+	// required: true
+	B string `json:"b"`
+}
+
+// A self-contained html-report
+// swagger:response statReportResponse
+type statReportResponse struct {
+	// in:body
+	Body string
+}
+
+// swagger:parameters getStatReport
+type getStatReportParams struct {
+	// in: path
+	ID string `json:"id"`
+}
@@ -0,0 +1,186 @@
+// Package importer converts collections from other http-clients into endpoints and requests.
+//
+// Currently, Postman (v2.1) collections and Insomnia (v4) exports are supported.
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/runar-rkmedia/gabyoall/api/types"
+)
+
+type Format string
+
+const (
+	FormatPostman  Format = "postman"
+	FormatInsomnia Format = "insomnia"
+)
+
+// Result of a parsed collection. Nothing is stored until the caller does so.
+type Result struct {
+	Endpoints []types.EndpointPayload
+	Requests  []Request
+	Summary   Summary
+}
+
+// Request is a request along with the url of the endpoint it should be run against.
+type Request struct {
+	types.RequestPayload
+	Url string
+}
+
+// Summary describes what was imported, and what had to be left out.
+type Summary struct {
+	Format Format `json:"format"`
+	// Name of the collection / workspace
+	Name      string             `json:"name,omitempty"`
+	Endpoints []ImportedEndpoint `json:"endpoints"`
+	Requests  []ImportedRequest  `json:"requests"`
+	// Features used in the collection which could not be imported, like pre-request-scripts.
+	Unsupported []string `json:"unsupported,omitempty"`
+	// Set if the import failed partway. The endpoints and requests which were created before it are listed.
+	Error string `json:"error,omitempty"`
+}
+
+type ImportedEndpoint struct {
+	ID  string `json:"id"`
+	Url string `json:"url"`
+}
+
+type ImportedRequest struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+	// The endpoint which the request was imported with. Use these to create schedules.
+	EndpointID string `json:"endpointID"`
+}
+
+func (s *Summary) unsupported(path []string, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	if len(path) > 0 {
+		msg = strings.Join(path, "/") + ": " + msg
+	}
+	s.Unsupported = append(s.Unsupported, msg)
+}
+
+// Parse detects the format of the collection, and converts it.
+func Parse(b []byte) (Result, error) {
+	var probe struct {
+		Info struct {
+			Schema string `json:"schema"`
+		} `json:"info"`
+		Type         string `json:"_type"`
+		ExportFormat int    `json:"__export_format"`
+	}
+	if err := json.Unmarshal(b, &probe); err != nil {
+		return Result{}, fmt.Errorf("failed to unmarshal collection: %w", err)
+	}
+	switch {
+	case strings.Contains(probe.Info.Schema, "getpostman.com"):
+		return ParsePostman(b)
+	case probe.Type == "export":
+		if probe.ExportFormat < 4 {
+			return Result{}, fmt.Errorf("insomnia-exports must be of format 4 or newer, got %d", probe.ExportFormat)
+		}
+		return ParseInsomnia(b)
+	}
+	return Result{}, fmt.Errorf("unrecognized collection-format. Expected a Postman v2.1-collection or an Insomnia-export")
+}
+
+var (
+	// Matches both postman-style {{var}} and insomnia-style {{ _.var }}
+	variableRegex = regexp.MustCompile(`{{\s*(?:_\.)?([\w.-]+)\s*}}`)
+	// Postman dynamic variables, like {{$guid}}
+	dynamicVariableRegex  = regexp.MustCompile(`{{\s*\$[\w.-]+\s*}}`)
+	graphqlOperationRegex = regexp.MustCompile(`(?:query|mutation|subscription)\s+(\w+)`)
+	identifierRegex       = regexp.MustCompile(`^[a-zA-Z_]\w*$`)
+)
+
+// Store creates the endpoints and requests in the result.
+// Every payload is validated before the first one is created. If creating one fails anyway,
+// the summary lists those which were created before it, along with the error.
+func Store(db types.Storage, r Result) (Summary, error) {
+	summary := r.Summary
+	for _, p := range r.Endpoints {
+		if err := p.Validate(); err != nil {
+			return summary, fmt.Errorf("endpoint %s is not valid: %w", p.Url, err)
+		}
+	}
+	for _, p := range r.Requests {
+		if err := p.Validate(); err != nil {
+			return summary, fmt.Errorf("request %s is not valid: %w", p.Label, err)
+		}
+	}
+	endpointIDs := map[string]string{}
+	for _, p := range r.Endpoints {
+		e, err := db.CreateEndpoint(p)
+		if err != nil {
+			return summary, fmt.Errorf("failed to create endpoint %s: %w", p.Url, err)
+		}
+		endpointIDs[p.Url] = e.ID
+		summary.Endpoints = append(summary.Endpoints, ImportedEndpoint{e.ID, p.Url})
+	}
+	for _, p := range r.Requests {
+		e, err := db.CreateRequest(p.RequestPayload)
+		if err != nil {
+			return summary, fmt.Errorf("failed to create request %s: %w", p.Label, err)
+		}
+		summary.Requests = append(summary.Requests, ImportedRequest{e.ID, p.Label, endpointIDs[p.Url]})
+	}
+	return summary, nil
+}
+
+// Created reports whether any endpoints or requests were created
+func (s Summary) Created() bool {
+	return len(s.Endpoints) > 0 || len(s.Requests) > 0
+}
+
+// Converts variables into go-templates, which are rendered with the config-vars.
+// Double quotes are avoided, so that the variables can be used within json-strings.
+func convertVariables(s string) string {
+	return variableRegex.ReplaceAllStringFunc(s, func(v string) string {
+		name := variableRegex.FindStringSubmatch(v)[1]
+		if identifierRegex.MatchString(name) {
+			return "{{ .Vars." + name + " }}"
+		}
+		return "{{ index .Vars `" + name + "` }}"
+	})
+}
+
+func (r *Result) addEndpoint(url string, vars map[string]string) {
+	for _, e := range r.Endpoints {
+		if e.Url == url {
+			return
+		}
+	}
+	e := types.EndpointPayload{Url: url}
+	if len(vars) > 0 && strings.Contains(url, "{{") {
+		e.Config = &types.Config{Vars: &vars}
+	}
+	r.Endpoints = append(r.Endpoints, e)
+}
+
+func (r *Result) checkDynamicVariables(path []string, s string) {
+	for _, v := range dynamicVariableRegex.FindAllString(s, -1) {
+		r.Summary.unsupported(path, "dynamic variable %s is not supported", v)
+	}
+}
+
+// Reads a graphql-body, where variables are a json-encoded string.
+func graphqlRequest(p *types.RequestPayload, query, variables string) error {
+	p.Query = query
+	if m := graphqlOperationRegex.FindStringSubmatch(query); len(m) > 1 {
+		p.OperationName = m[1]
+	}
+	if strings.TrimSpace(variables) == "" {
+		return nil
+	}
+	return json.Unmarshal([]byte(variables), &p.Variables)
+}
+
+// Only json-bodies can be sent by the requests.
+func isJsonBody(s string) bool {
+	return json.Valid([]byte(variableRegex.ReplaceAllString(s, `0`)))
+}
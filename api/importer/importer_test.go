@@ -0,0 +1,258 @@
+package importer
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/runar-rkmedia/gabyoall/api/types"
+	"github.com/runar-rkmedia/gabyoall/internal"
+	"github.com/runar-rkmedia/gabyoall/requests"
+)
+
+const postmanCollectionJson = `{
+  "info": {
+    "name": "Files",
+    "schema": "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"
+  },
+  "variable": [{"key": "baseUrl", "value": "https://example.com"}],
+  "auth": {"type": "bearer", "bearer": [{"key": "token", "value": "{{token}}"}]},
+  "item": [
+    {
+      "name": "Graphql",
+      "item": [
+        {
+          "name": "List files",
+          "event": [{"listen": "prerequest", "script": {"exec": ["pm.environment.set('a', 1)"]}}],
+          "request": {
+            "method": "post",
+            "header": [{"key": "X-Tenant", "value": "{{tenant}}"}, {"key": "X-Off", "value": "1", "disabled": true}],
+            "body": {"mode": "graphql", "graphql": {"query": "query Files($name: String){files(name: $name){id}}", "variables": "{\"name\": \"{{name}}\"}"}},
+            "url": {"raw": "{{baseUrl}}/graphql"}
+          }
+        }
+      ]
+    },
+    {
+      "name": "Health",
+      "request": {
+        "method": "GET",
+        "auth": {"type": "basic", "basic": [{"key": "username", "value": "john"}, {"key": "password", "value": "secret"}]},
+        "body": {"mode": "formdata"},
+        "url": "{{baseUrl}}/health?t={{$timestamp}}"
+      }
+    }
+  ]
+}`
+
+const insomniaExportJson = `{
+  "_type": "export",
+  "__export_format": 4,
+  "resources": [
+    {"_id": "wrk_1", "_type": "workspace", "name": "Files"},
+    {"_id": "env_1", "_type": "environment", "parentId": "wrk_1", "name": "Base", "data": {"baseUrl": "https://example.com"}},
+    {"_id": "env_2", "_type": "environment", "parentId": "env_1", "name": "Prod", "data": {"baseUrl": "https://prod.example.com"}},
+    {"_id": "fld_1", "_type": "request_group", "parentId": "wrk_1", "name": "Files"},
+    {"_id": "fld_2", "_type": "request_group", "parentId": "fld_1", "name": "Write"},
+    {
+      "_id": "req_1", "_type": "request", "parentId": "fld_2", "name": "Create file",
+      "method": "put", "url": "{{ _.baseUrl }}/files",
+      "body": {"mimeType": "application/json", "text": "{\"name\": \"{{ _.name }}\"}"},
+      "authentication": {"type": "bearer", "token": "abc"}
+    },
+    {"_id": "grpc_1", "_type": "grpc_request", "parentId": "wrk_1", "name": "Stream"}
+  ]
+}`
+
+func TestParse(t *testing.T) {
+	vars := func(kv ...string) *map[string]string {
+		m := map[string]string{}
+		for i := 0; i < len(kv)-1; i += 2 {
+			m[kv[i]] = kv[i+1]
+		}
+		return &m
+	}
+	tests := []struct {
+		name  string
+		input string
+		want  Result
+	}{
+		{
+			"Should import postman-collections",
+			postmanCollectionJson,
+			Result{
+				Endpoints: []types.EndpointPayload{
+					{Url: `{{ .Vars.baseUrl }}/graphql`, Config: &types.Config{Vars: vars("baseUrl", "https://example.com")}},
+					{Url: `{{ .Vars.baseUrl }}/health?t={{$timestamp}}`, Config: &types.Config{Vars: vars("baseUrl", "https://example.com")}},
+				},
+				Requests: []Request{
+					{
+						RequestPayload: types.RequestPayload{
+							Label:         "List files",
+							Tags:          []string{"Graphql"},
+							Method:        "POST",
+							Query:         "query Files($name: String){files(name: $name){id}}",
+							OperationName: "Files",
+							Variables:     map[string]interface{}{"name": `{{ .Vars.name }}`},
+							Headers:       map[string]string{"X-Tenant": `{{ .Vars.tenant }}`},
+							Config: &types.Config{
+								Vars: vars("baseUrl", "https://example.com"),
+								Auth: &types.AuthConfig{Kind: "bearer", Token: `{{ .Vars.token }}`},
+							},
+						},
+						Url: `{{ .Vars.baseUrl }}/graphql`,
+					},
+					{
+						RequestPayload: types.RequestPayload{
+							Label:  "Health",
+							Tags:   []string{},
+							Method: "GET",
+							Config: &types.Config{
+								Vars: vars("baseUrl", "https://example.com"),
+								Auth: &types.AuthConfig{Kind: "basic", ImpersionationCredentials: types.ImpersionationCredentials{Username: "john", Password: "secret"}},
+							},
+						},
+						Url: `{{ .Vars.baseUrl }}/health?t={{$timestamp}}`,
+					},
+				},
+				Summary: Summary{
+					Format: FormatPostman,
+					Name:   "Files",
+					Unsupported: []string{
+						"Graphql/List files: pre-request-scripts are not supported",
+						"Health: dynamic variable {{$timestamp}} is not supported",
+						"Health: body-mode 'formdata' is not supported, and was dropped",
+					},
+				},
+			},
+		},
+		{
+			"Should import insomnia-exports",
+			insomniaExportJson,
+			Result{
+				Endpoints: []types.EndpointPayload{
+					{Url: `{{ .Vars.baseUrl }}/files`, Config: &types.Config{Vars: vars("baseUrl", "https://example.com")}},
+				},
+				Requests: []Request{
+					{
+						RequestPayload: types.RequestPayload{
+							Label:  "Create file",
+							Tags:   []string{"Files", "Write"},
+							Method: "PUT",
+							Body:   `{"name": "{{ .Vars.name }}"}`,
+							Config: &types.Config{
+								Vars: vars("baseUrl", "https://example.com"),
+								Auth: &types.AuthConfig{Kind: "bearer", Token: "abc"},
+							},
+						},
+						Url: `{{ .Vars.baseUrl }}/files`,
+					},
+				},
+				Summary: Summary{
+					Format: FormatInsomnia,
+					Name:   "Files",
+					Unsupported: []string{
+						"Prod: sub-environments are not supported, only the base-environment is used",
+						"Stream: resources of type 'grpc_request' are not supported",
+					},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse([]byte(tt.input))
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if err := internal.Compare("Parse()", got, tt.want); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+// Only implements creating endpoints and requests. Fails to create the request with the label failLabel.
+type fakeStorage struct {
+	types.Storage
+	failLabel string
+	created   []string
+}
+
+func (f *fakeStorage) CreateEndpoint(p types.EndpointPayload) (e types.EndpointEntity, err error) {
+	e.ID = fmt.Sprintf("e%d", len(f.created))
+	f.created = append(f.created, e.ID)
+	return e, nil
+}
+
+func (f *fakeStorage) CreateRequest(p types.RequestPayload) (e types.RequestEntity, err error) {
+	if p.Label == f.failLabel {
+		return e, fmt.Errorf("disk full")
+	}
+	e.ID = fmt.Sprintf("r%d", len(f.created))
+	f.created = append(f.created, e.ID)
+	return e, nil
+}
+
+func TestStore(t *testing.T) {
+	result := func(requests ...types.RequestPayload) Result {
+		r := Result{Endpoints: []types.EndpointPayload{{Url: "https://example.com"}}}
+		for _, p := range requests {
+			r.Requests = append(r.Requests, Request{p, "https://example.com"})
+		}
+		return r
+	}
+	tests := []struct {
+		name        string
+		failLabel   string
+		result      Result
+		wantErr     bool
+		wantCreated []string
+		want        Summary
+	}{
+		{
+			"Should create every endpoint and request",
+			"",
+			result(types.RequestPayload{Label: "a"}, types.RequestPayload{Label: "b"}),
+			false,
+			[]string{"e0", "r1", "r2"},
+			Summary{
+				Endpoints: []ImportedEndpoint{{"e0", "https://example.com"}},
+				Requests:  []ImportedRequest{{"r1", "a", "e0"}, {"r2", "b", "e0"}},
+			},
+		},
+		{
+			"Should create nothing if a payload is invalid",
+			"",
+			result(types.RequestPayload{Label: "a"}, types.RequestPayload{Label: "b", Assertions: []requests.Assertion{{Kind: "nope"}}}),
+			true,
+			nil,
+			Summary{},
+		},
+		{
+			"Should list what was created before a failure",
+			"b",
+			result(types.RequestPayload{Label: "a"}, types.RequestPayload{Label: "b"}),
+			true,
+			[]string{"e0", "r1"},
+			Summary{
+				Endpoints: []ImportedEndpoint{{"e0", "https://example.com"}},
+				Requests:  []ImportedRequest{{"r1", "a", "e0"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &fakeStorage{failLabel: tt.failLabel}
+			got, err := Store(db, tt.result)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Store() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err := internal.Compare("Store()", got, tt.want); err != nil {
+				t.Error(err)
+			}
+			if err := internal.Compare("created", db.created, tt.wantCreated); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
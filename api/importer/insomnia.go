@@ -0,0 +1,182 @@
+package importer
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/runar-rkmedia/gabyoall/api/types"
+)
+
+// See https://github.com/Kong/insomnia/blob/develop/packages/insomnia/src/common/import.ts
+type insomniaExport struct {
+	Type         string             `json:"_type"`
+	ExportFormat int                `json:"__export_format"`
+	Resources    []insomniaResource `json:"resources"`
+}
+
+type insomniaResource struct {
+	ID       string `json:"_id"`
+	ParentID string `json:"parentId"`
+	// request, request_group, workspace, environment, cookie_jar, api_spec, grpc_request ...
+	Type           string                 `json:"_type"`
+	Name           string                 `json:"name"`
+	Url            string                 `json:"url"`
+	Method         string                 `json:"method"`
+	Body           insomniaBody           `json:"body"`
+	Headers        []insomniaKeyValue     `json:"headers"`
+	Authentication insomniaAuth           `json:"authentication"`
+	Data           map[string]interface{} `json:"data"`
+}
+
+type insomniaBody struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type insomniaKeyValue struct {
+	Name     string `json:"name"`
+	Value    string `json:"value"`
+	Disabled bool   `json:"disabled"`
+}
+
+type insomniaAuth struct {
+	Type     string `json:"type"`
+	Disabled bool   `json:"disabled"`
+	Token    string `json:"token"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// ParseInsomnia converts an Insomnia-export of format 4.
+func ParseInsomnia(b []byte) (Result, error) {
+	var e insomniaExport
+	r := Result{}
+	if err := json.Unmarshal(b, &e); err != nil {
+		return r, err
+	}
+	r.Summary.Format = FormatInsomnia
+	byID := map[string]insomniaResource{}
+	for _, res := range e.Resources {
+		byID[res.ID] = res
+	}
+	// Only the base-environments (direct children of the workspace) are used.
+	// Sub-environments are alternatives to each other, and we cannot know which one the user wants.
+	vars := map[string]string{}
+	for _, res := range e.Resources {
+		switch res.Type {
+		case "workspace":
+			if r.Summary.Name == "" {
+				r.Summary.Name = res.Name
+			}
+		case "environment":
+			if parent, ok := byID[res.ParentID]; !ok || parent.Type != "workspace" {
+				r.Summary.unsupported([]string{res.Name}, "sub-environments are not supported, only the base-environment is used")
+				continue
+			}
+			for k, v := range res.Data {
+				if s, ok := v.(string); ok {
+					vars[k] = s
+				} else {
+					b, _ := json.Marshal(v)
+					vars[k] = string(b)
+				}
+			}
+		}
+	}
+	for _, res := range e.Resources {
+		switch res.Type {
+		case "request":
+			r.insomniaRequest(insomniaPath(byID, res), res, vars)
+		case "workspace", "environment", "request_group", "cookie_jar", "api_spec":
+		default:
+			r.Summary.unsupported([]string{res.Name}, "resources of type '%s' are not supported", res.Type)
+		}
+	}
+	return r, nil
+}
+
+// Returns the names of the folders leading to the resource, and the resource itself
+func insomniaPath(byID map[string]insomniaResource, res insomniaResource) []string {
+	path := []string{res.Name}
+	parent, ok := byID[res.ParentID]
+	// Guard against cyclic parents.
+	for i := 0; ok && parent.Type == "request_group" && i < 100; i++ {
+		path = append([]string{parent.Name}, path...)
+		parent, ok = byID[parent.ParentID]
+	}
+	return path
+}
+
+func (r *Result) insomniaRequest(path []string, res insomniaResource, vars map[string]string) {
+	url := convertVariables(res.Url)
+	p := types.RequestPayload{
+		Label:  res.Name,
+		Tags:   path[:len(path)-1],
+		Method: strings.ToUpper(res.Method),
+		Config: &types.Config{},
+	}
+	if len(vars) > 0 {
+		p.Config.Vars = &vars
+	}
+	for _, h := range res.Headers {
+		if h.Disabled || h.Name == "" {
+			continue
+		}
+		if p.Headers == nil {
+			p.Headers = map[string]string{}
+		}
+		p.Headers[h.Name] = convertVariables(h.Value)
+	}
+	switch {
+	case res.Body.MimeType == "application/graphql":
+		var gql struct {
+			Query     string      `json:"query"`
+			Variables interface{} `json:"variables"`
+		}
+		if err := json.Unmarshal([]byte(res.Body.Text), &gql); err != nil {
+			r.Summary.unsupported(path, "graphql-body could not be parsed: %s", err)
+			break
+		}
+		variables := ""
+		if gql.Variables != nil {
+			b, _ := json.Marshal(gql.Variables)
+			variables = string(b)
+		}
+		if err := graphqlRequest(&p, gql.Query, convertVariables(variables)); err != nil {
+			r.Summary.unsupported(path, "graphql-variables could not be parsed: %s", err)
+		}
+	case strings.TrimSpace(res.Body.Text) == "":
+		if res.Body.MimeType != "" && !strings.Contains(res.Body.MimeType, "json") {
+			r.Summary.unsupported(path, "body of type '%s' is not supported, and was dropped", res.Body.MimeType)
+		}
+	case isJsonBody(res.Body.Text):
+		p.Body = convertVariables(res.Body.Text)
+	default:
+		r.Summary.unsupported(path, "body is not json, and was dropped")
+	}
+	if !res.Authentication.Disabled {
+		switch res.Authentication.Type {
+		case "", "none":
+		case "bearer":
+			p.Config.Auth = &types.AuthConfig{
+				Kind:  "bearer",
+				Token: types.Secret(convertVariables(res.Authentication.Token)),
+			}
+		case "basic":
+			p.Config.Auth = &types.AuthConfig{
+				Kind: "basic",
+				ImpersionationCredentials: types.ImpersionationCredentials{
+					Username: convertVariables(res.Authentication.Username),
+					Password: types.Secret(convertVariables(res.Authentication.Password)),
+				},
+			}
+		default:
+			r.Summary.unsupported(path, "authentication of type '%s' is not supported", res.Authentication.Type)
+		}
+	}
+	if strings.Contains(res.Url, "{%") || strings.Contains(res.Body.Text, "{%") {
+		r.Summary.unsupported(path, "template-tags ({%% ... %%}) are not supported")
+	}
+	r.addEndpoint(url, vars)
+	r.Requests = append(r.Requests, Request{p, url})
+}
@@ -0,0 +1,258 @@
+package importer
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/runar-rkmedia/gabyoall/api/types"
+)
+
+// See https://schema.getpostman.com/json/collection/v2.1.0/collection.json
+type postmanCollection struct {
+	Info struct {
+		Name   string `json:"name"`
+		Schema string `json:"schema"`
+	} `json:"info"`
+	Item     []postmanItem     `json:"item"`
+	Variable []postmanKeyValue `json:"variable"`
+	Auth     *postmanAuth      `json:"auth"`
+	Event    []postmanEvent    `json:"event"`
+}
+
+type postmanItem struct {
+	Name string `json:"name"`
+	// If set, the item is a folder
+	Item    []postmanItem   `json:"item"`
+	Request *postmanRequest `json:"request"`
+	// Auth is only set on folders. Requests have their auth within the request.
+	Auth     *postmanAuth      `json:"auth"`
+	Event    []postmanEvent    `json:"event"`
+	Variable []postmanKeyValue `json:"variable"`
+}
+
+type postmanRequest struct {
+	Method string            `json:"method"`
+	Header []postmanKeyValue `json:"header"`
+	Body   *postmanBody      `json:"body"`
+	Url    postmanUrl        `json:"url"`
+	Auth   *postmanAuth      `json:"auth"`
+}
+
+type postmanBody struct {
+	Mode    string `json:"mode"`
+	Raw     string `json:"raw"`
+	GraphQL struct {
+		Query     string `json:"query"`
+		Variables string `json:"variables"`
+	} `json:"graphql"`
+}
+
+type postmanUrl struct {
+	Raw string `json:"raw"`
+}
+
+type postmanKeyValue struct {
+	Key      string      `json:"key"`
+	Value    interface{} `json:"value"`
+	Disabled bool        `json:"disabled"`
+}
+
+type postmanAuth struct {
+	Type   string            `json:"type"`
+	Bearer []postmanKeyValue `json:"bearer"`
+	Basic  []postmanKeyValue `json:"basic"`
+}
+
+type postmanEvent struct {
+	Listen string `json:"listen"`
+	Script struct {
+		Exec []string `json:"exec"`
+	} `json:"script"`
+}
+
+// A request may be just the url as a string
+func (r *postmanRequest) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		r.Method = "GET"
+		r.Url.Raw = s
+		return nil
+	}
+	type alias postmanRequest
+	var a alias
+	if err := json.Unmarshal(b, &a); err != nil {
+		return err
+	}
+	*r = postmanRequest(a)
+	return nil
+}
+
+// The url may be either a string, or an object
+func (u *postmanUrl) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		u.Raw = s
+		return nil
+	}
+	var o struct {
+		Raw string `json:"raw"`
+	}
+	if err := json.Unmarshal(b, &o); err != nil {
+		return err
+	}
+	u.Raw = o.Raw
+	return nil
+}
+
+func (kv postmanKeyValue) String() string {
+	if s, ok := kv.Value.(string); ok {
+		return s
+	}
+	if kv.Value == nil {
+		return ""
+	}
+	b, _ := json.Marshal(kv.Value)
+	return string(b)
+}
+
+func postmanLookup(kvs []postmanKeyValue, key string) string {
+	for _, kv := range kvs {
+		if kv.Key == key {
+			return kv.String()
+		}
+	}
+	return ""
+}
+
+// ParsePostman converts a Postman v2.1-collection
+func ParsePostman(b []byte) (Result, error) {
+	var c postmanCollection
+	r := Result{}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return r, err
+	}
+	r.Summary.Format = FormatPostman
+	r.Summary.Name = c.Info.Name
+	if !strings.Contains(c.Info.Schema, "v2.1") {
+		r.Summary.unsupported(nil, "schema %s is not supported, only v2.1. Attempted to import anyway", c.Info.Schema)
+	}
+	vars := map[string]string{}
+	for _, v := range c.Variable {
+		if v.Disabled {
+			continue
+		}
+		vars[v.Key] = v.String()
+	}
+	r.checkEvents(nil, c.Event)
+	r.postmanItems(nil, c.Item, c.Auth, vars)
+	return r, nil
+}
+
+func (r *Result) checkEvents(path []string, events []postmanEvent) {
+	for _, e := range events {
+		if len(e.Script.Exec) == 0 || strings.TrimSpace(strings.Join(e.Script.Exec, "")) == "" {
+			continue
+		}
+		switch e.Listen {
+		case "prerequest":
+			r.Summary.unsupported(path, "pre-request-scripts are not supported")
+		case "test":
+			r.Summary.unsupported(path, "test-scripts are not supported")
+		default:
+			r.Summary.unsupported(path, "scripts for event '%s' are not supported", e.Listen)
+		}
+	}
+}
+
+func (r *Result) postmanItems(path []string, items []postmanItem, auth *postmanAuth, vars map[string]string) {
+	for _, item := range items {
+		itemPath := append(append([]string{}, path...), item.Name)
+		r.checkEvents(itemPath, item.Event)
+		itemVars := vars
+		if len(item.Variable) > 0 {
+			itemVars = map[string]string{}
+			for k, v := range vars {
+				itemVars[k] = v
+			}
+			for _, v := range item.Variable {
+				itemVars[v.Key] = v.String()
+			}
+		}
+		if item.Request == nil {
+			folderAuth := auth
+			if item.Auth != nil {
+				folderAuth = item.Auth
+			}
+			r.postmanItems(itemPath, item.Item, folderAuth, itemVars)
+			continue
+		}
+		r.postmanRequest(itemPath, *item.Request, auth, itemVars)
+	}
+}
+
+func (r *Result) postmanRequest(path []string, req postmanRequest, auth *postmanAuth, vars map[string]string) {
+	url := convertVariables(req.Url.Raw)
+	r.checkDynamicVariables(path, req.Url.Raw)
+	p := types.RequestPayload{
+		Label:  path[len(path)-1],
+		Tags:   path[:len(path)-1],
+		Method: strings.ToUpper(req.Method),
+		Config: &types.Config{},
+	}
+	if len(vars) > 0 {
+		p.Config.Vars = &vars
+	}
+	for _, h := range req.Header {
+		if h.Disabled {
+			continue
+		}
+		if p.Headers == nil {
+			p.Headers = map[string]string{}
+		}
+		r.checkDynamicVariables(path, h.String())
+		p.Headers[h.Key] = convertVariables(h.String())
+	}
+	if req.Body != nil {
+		switch req.Body.Mode {
+		case "raw":
+			r.checkDynamicVariables(path, req.Body.Raw)
+			if strings.TrimSpace(req.Body.Raw) != "" && !isJsonBody(req.Body.Raw) {
+				r.Summary.unsupported(path, "body is not json, and was dropped")
+				break
+			}
+			p.Body = convertVariables(req.Body.Raw)
+		case "graphql":
+			if err := graphqlRequest(&p, req.Body.GraphQL.Query, convertVariables(req.Body.GraphQL.Variables)); err != nil {
+				r.Summary.unsupported(path, "graphql-variables could not be parsed: %s", err)
+			}
+		case "":
+		default:
+			r.Summary.unsupported(path, "body-mode '%s' is not supported, and was dropped", req.Body.Mode)
+		}
+	}
+	if req.Auth != nil {
+		auth = req.Auth
+	}
+	if auth != nil {
+		switch auth.Type {
+		case "noauth", "":
+		case "bearer":
+			p.Config.Auth = &types.AuthConfig{
+				Kind:  "bearer",
+				Token: types.Secret(convertVariables(postmanLookup(auth.Bearer, "token"))),
+			}
+		case "basic":
+			p.Config.Auth = &types.AuthConfig{
+				Kind: "basic",
+				ImpersionationCredentials: types.ImpersionationCredentials{
+					Username: convertVariables(postmanLookup(auth.Basic, "username")),
+					Password: types.Secret(convertVariables(postmanLookup(auth.Basic, "password"))),
+				},
+			}
+		default:
+			r.Summary.unsupported(path, "auth of type '%s' is not supported", auth.Type)
+		}
+	}
+	r.addEndpoint(url, vars)
+	r.Requests = append(r.Requests, Request{p, url})
+}
@@ -48,6 +48,7 @@ const (
 	CodeErrSchedule         ErrorCodes = "Error: Database Create Schedule"
 	CodeErrDBCreateRequest  ErrorCodes = "Error: Database Create Request"
 	CodeErrDBCreateSchedule ErrorCodes = "Error: Database Create Schedule"
+	CodeErrImport           ErrorCodes = "Error: Import of collection"
 )
 
 type ApiError struct {
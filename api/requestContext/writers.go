@@ -34,7 +34,7 @@ func WriteError(msg string, code ErrorCodes, r *http.Request, rw http.ResponseWr
 		statusCode = http.StatusNotFound
 	case CodeErrReadBody, CodeErrDBCreateEndpoint:
 		statusCode = http.StatusBadGateway
-	case CodeErrUnmarshal, CodeErrMarhal, CodeErrJmesPath, CodeErrJmesPathMarshal, CodeErrInputValidation, CodeErrIDNonValid, CodeErrIDTooLong, CodeErrIDEmpty:
+	case CodeErrUnmarshal, CodeErrImport, CodeErrMarhal, CodeErrJmesPath, CodeErrJmesPathMarshal, CodeErrInputValidation, CodeErrIDNonValid, CodeErrIDTooLong, CodeErrIDEmpty:
 		statusCode = http.StatusBadRequest
 	}
 	return WriteOutput(true, statusCode, ae, r, rw)
@@ -1,8 +1,9 @@
 package scheduler
 
 import (
+	"encoding/json"
 	"fmt"
-	"strings"
+	"net/http"
 	"sync"
 	"time"
 
@@ -10,8 +11,11 @@ import (
 	"github.com/runar-rkmedia/gabyoall/auth"
 	"github.com/runar-rkmedia/gabyoall/cmd"
 	"github.com/runar-rkmedia/gabyoall/logger"
+	"github.com/runar-rkmedia/gabyoall/metrics"
 	"github.com/runar-rkmedia/gabyoall/printer"
 	"github.com/runar-rkmedia/gabyoall/requests"
+	"github.com/runar-rkmedia/gabyoall/sinks"
+	"github.com/runar-rkmedia/gabyoall/tracing"
 	"github.com/runar-rkmedia/gabyoall/utils"
 	"github.com/runar-rkmedia/gabyoall/worker"
 )
@@ -22,6 +26,8 @@ type Scheduler struct {
 	interval  time.Duration
 	config    *cmd.Config
 	isRunning bool
+	// If set, the requests of each run are recorded for the metrics-endpoint
+	Metrics *metrics.Metrics
 	sync.Mutex
 }
 
@@ -124,27 +130,31 @@ func (s *Scheduler) RunSchedule(v types.ScheduleEntity) error {
 		s.l.Error().Msg("RequestCount must be positive")
 		return fmt.Errorf("RequestCount must be positive")
 	}
+	request := renderTemplates(l, &config, rq.Request)
 	ts := requests.NewTimeSeriesWithLabel(time.Now())
-	endpoint := requests.NewEndpoint(s.l, ep.Url, &ts)
+	endpoint := requests.NewEndpoint(s.l, utils.RunTemplating(l, ep.Url, "url", config), &ts)
+	endpoint.ClassificationRules = config.ClassificationRules
+	endpoint.Normalization = ep.Normalization
+	endpoint.PartialDataOk = config.PartialDataOk
 	var token string
 	// TODO: renew the tokenPayload as needed
-	// var tokenPayload *auth.TokenPayload
+	var tokenPayload *auth.TokenPayload
 	var validityStringer printer.ValidityStringer
+	var cookies []*http.Cookie
 	if token == "" {
-		err, token, _, validityStringer = auth.Retrieve(l, config.Auth)
+		err, token, tokenPayload, validityStringer, cookies = auth.Retrieve(l, config.Auth)
 		if err != nil {
 			return fmt.Errorf("failed to perform authentication: %w", err)
 		}
 	}
+	if tokenPayload != nil {
+		s.Metrics.SetTokenExpiry(tokenPayload.Expires)
+	}
 	if token != "" {
-		authPrefix := ""
-		if strings.ToLower(config.Auth.Kind) == "bearer" {
-			authPrefix = "Bearer "
-		}
 		if config.Auth.HeaderKey == "" {
 			config.Auth.HeaderKey = "Authorization"
 		}
-		endpoint.Headers.Add(config.Auth.HeaderKey, authPrefix+token)
+		endpoint.Headers.Add(config.Auth.HeaderKey, auth.HeaderPrefix(config.Auth.Kind)+token)
 	}
 	out, _ := cmd.NewOutput(l, "", config.Url, rq.Request, nil)
 	startTime := time.Now()
@@ -157,7 +167,7 @@ func (s *Scheduler) RunSchedule(v types.ScheduleEntity) error {
 	)
 	print.Animate()
 
-	wt := worker.WorkThing{}
+	wt := worker.WorkThing{Cookies: cookies}
 	l = logger.With(s.l.With().
 		Int("Concurrency", config.Concurrency).
 		Int("Request-Count", config.RequestCount).
@@ -172,12 +182,38 @@ func (s *Scheduler) RunSchedule(v types.ScheduleEntity) error {
 	if warn != nil {
 		l.Warn().Err(warn).Str("ID", runId).Msg("Failed to create id, used fallback-method instead")
 	}
-	ch, jobCh := wt.Run(endpoint, config, rq.Request)
+	s.Metrics.TrackRun(ts.Throughput)
+	defer s.Metrics.UntrackRun(ts.Throughput)
+	var exporter *tracing.Exporter
+	if s.config != nil && s.config.OtlpEndpoint != "" {
+		if exporter, err = tracing.NewExporter(s.l, s.config.OtlpEndpoint, runId); err != nil {
+			l.Error().Err(err).Msg("Failed to set up the otlp-exporter, continuing without it")
+		}
+	}
+	var pusher *sinks.Pusher
+	if s.config != nil && len(s.config.Sinks) > 0 {
+		if sinkList, err := sinks.NewSinks(s.config.Sinks); err != nil {
+			l.Error().Err(err).Msg("Failed to set up the sinks, continuing without them")
+		} else {
+			pusher = sinks.NewPusher(s.l, sinkList, time.Second, map[string]string{"run_id": runId, "schedule_id": v.ID})
+		}
+	}
+	ch, jobCh := wt.Run(endpoint, config, request)
 	defer close(jobCh)
 	defer close(ch)
 	successes := 0
 	stats := requests.NewCompactRequestStatistics(runId, &ts)
 	stats.TotalRequests = config.RequestCount
+	stats.Samples = requests.NewSamples(config.Samples, config.Auth.HeaderKey)
+	stats.ScheduleID = v.ID
+	stats.Title = ep.Url
+	if rq.OperationName != "" {
+		stats.Title += " " + rq.OperationName
+	}
+	if stats.Config, err = json.Marshal(config.Redacted()); err != nil {
+		l.Error().Err(err).Msg("Failed to marshal the config of the run")
+	}
+	stats.SetSLO(requests.PickSLO(ep.SLO, rq.SLO))
 	lastSave := time.Now()
 	debug := s.l.HasDebug()
 	didSave := false
@@ -187,6 +223,9 @@ func (s *Scheduler) RunSchedule(v types.ScheduleEntity) error {
 			successes++
 		}
 		stats.AddStat(stat)
+		s.Metrics.Observe(stat)
+		exporter.Export(stat)
+		pusher.Observe(stat)
 		now := time.Now()
 		if stats.CompletedRequests == config.RequestCount || now.Sub(lastSave) > time.Second {
 			lastSave = now
@@ -202,6 +241,13 @@ func (s *Scheduler) RunSchedule(v types.ScheduleEntity) error {
 			didSave = true
 		}
 	}
+	stats.Calculate()
+	if err := exporter.Close(); err != nil {
+		l.Warn().Err(err).Msg("Failed to export all spans")
+	}
+	if err := pusher.Close(); err != nil {
+		l.Warn().Err(err).Msg("Failed to close the sinks")
+	}
 	if didSave {
 		s.db.UpdateCompactStats(runId, startedAt, stats)
 	} else {
@@ -220,6 +266,16 @@ func (s *Scheduler) RunSchedule(v types.ScheduleEntity) error {
 	return nil
 }
 
+// Renders the templated fields of the auth-config, like the ones created by importing collections.
+// The request itself is rendered for each request, as it may use the variables of the virtual user.
+func renderTemplates(l logger.AppLogger, config *cmd.Config, request requests.Request) requests.Request {
+	request.Vars = config.Vars
+	config.Auth.Token = utils.RunTemplating(l, config.Auth.Token, "token", config)
+	config.Auth.Username = utils.RunTemplating(l, config.Auth.Username, "username", config)
+	config.Auth.Password = utils.RunTemplating(l, config.Auth.Password, "password", config)
+	return request
+}
+
 func NewScheduler(l logger.AppLogger, db types.Storage, config *cmd.Config) *Scheduler {
 	s := Scheduler{
 		l:        l,
@@ -2,6 +2,7 @@ package types
 
 import (
 	"github.com/runar-rkmedia/gabyoall/cmd"
+	"github.com/runar-rkmedia/gabyoall/requests"
 )
 
 type DynamicAuth struct {
@@ -38,7 +39,8 @@ type AuthConfig struct {
 	// With dynamic, a series of requests can be made before the stress-test is performed.
 	// The output can be piped into eachother, and then into the requests made during the stress-test.
 	Dynamic DynamicAuth `json:"dynamic,omitempty"`
-	// Bearer or Dynamic
+	// Bearer, Basic or Dynamic.
+	// With Basic, the Username and Password from ImpersionationCredentials are used.
 	Kind string `json:"kind,omitempty"`
 	// Used to impersonate (currently only works with keycloak)
 	ImpersionationCredentials ImpersionationCredentials `json:"impersionation_credentials,omitempty"`
@@ -74,6 +76,27 @@ type Config struct {
 	// Number of requests to be performaed
 	RequestCount *int     `json:"request_count,omitempty"`
 	Secrets      *Secrets `json:"secrets,omitempty"`
+	// Variables available during templating, as {{ .Vars.key }}
+	Vars *map[string]string `json:"vars,omitempty"`
+	// Resets the cookies and variables of each virtual user (worker) every n requests,
+	// to simulate new users arriving.
+	ResetSessionEvery *int `json:"reset_session_every,omitempty"`
+	// Rules for putting failed requests into named buckets. The first matching rule wins.
+	// Rules on a request are evaluated before the rules on its endpoint.
+	ClassificationRules *[]requests.ClassificationRule `json:"classification_rules,omitempty"`
+	// If set, GraphQL-responses with errors are successful as long as they have partial data.
+	PartialDataOk *bool `json:"partial_data_ok,omitempty"`
+}
+
+// Validate checks the parts of the config which the struct-validator cannot.
+func (c *Config) Validate() error {
+	if c == nil {
+		return nil
+	}
+	if c.ClassificationRules != nil {
+		return requests.ValidateClassificationRules(*c.ClassificationRules)
+	}
+	return nil
 }
 
 // MergeWith will overwrite values with values in argument c.
@@ -90,6 +113,28 @@ func (c Config) MergeInto(config cmd.Config) cmd.Config {
 	if c.ResponseData != nil {
 		config.ResponseData = *c.ResponseData
 	}
+	if c.ResetSessionEvery != nil {
+		config.ResetSessionEvery = *c.ResetSessionEvery
+	}
+	if c.PartialDataOk != nil {
+		config.PartialDataOk = *c.PartialDataOk
+	}
+	if c.ClassificationRules != nil {
+		// The more specific config is merged last, so its rules should be evaluated first.
+		rules := make([]requests.ClassificationRule, 0, len(*c.ClassificationRules)+len(config.ClassificationRules))
+		rules = append(rules, *c.ClassificationRules...)
+		config.ClassificationRules = append(rules, config.ClassificationRules...)
+	}
+	if c.Vars != nil {
+		vars := map[string]string{}
+		for k, v := range config.Vars {
+			vars[k] = v
+		}
+		for k, v := range *c.Vars {
+			vars[k] = v
+		}
+		config.Vars = vars
+	}
 	if c.Auth != nil {
 		if c.Auth.Endpoint != "" {
 			config.Auth.Endpoint = c.Auth.Endpoint
@@ -123,7 +168,7 @@ func (c Config) MergeInto(config cmd.Config) cmd.Config {
 		if c.Auth.ClientSecret != "" && !IsRedacted(string(c.Auth.ClientSecret)) {
 			config.Auth.ClientSecret = string(c.Auth.ClientSecret)
 		}
-		if c.Auth.ImpersionationCredentials.Password != "" && IsRedacted(string(c.Auth.ImpersionationCredentials.Password)) {
+		if c.Auth.ImpersionationCredentials.Password != "" && !IsRedacted(string(c.Auth.ImpersionationCredentials.Password)) {
 			config.Auth.ImpersionationCredentials.Password = string(c.Auth.ImpersionationCredentials.Password)
 		}
 		if c.Auth.ImpersionationCredentials.UserIDToImpersonate != "" {
@@ -3,6 +3,7 @@ package types
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/runar-rkmedia/gabyoall/logger"
@@ -29,7 +30,25 @@ type EndpointPayload struct {
 	Url     string              `json:"url,omitempty" validate:"required,uri"`
 	Headers map[string][]string `json:"headers,omitempty" validate:"dive,max=1000"`
 	Config  *Config             `json:"config,omitempty"`
+	// Rules for normalizing responses before they are hashed, like dropping timestamps.
+	Normalization []requests.NormalizationRule `json:"normalization,omitempty"`
+	// Objective which each run is scored against, unless the request has its own
+	SLO *requests.SLO `json:"slo,omitempty"`
 }
+
+// Validate checks the parts of the payload which the struct-validator cannot.
+func (p EndpointPayload) Validate() error {
+	if err := requests.ValidateNormalizationRules(p.Normalization); err != nil {
+		return err
+	}
+	if p.SLO != nil {
+		if err := p.SLO.Validate(); err != nil {
+			return err
+		}
+	}
+	return p.Config.Validate()
+}
+
 type RequestPayload struct {
 	Body          string                 `json:"body,omitempty"`
 	Query         string                 `json:"query,omitempty"`
@@ -38,6 +57,55 @@ type RequestPayload struct {
 	OperationName string                 `json:"operationName,required"`
 	Method        string                 `json:"method"`
 	Config        *Config                `json:"config,omitempty"`
+	// Human-readable name of the request
+	Label string `json:"label,omitempty"`
+	// Used for grouping requests, for instance by the folders of an imported collection
+	Tags []string `json:"tags,omitempty"`
+	// Values to capture from json-responses into the variables of the virtual user, as name: jmes-path.
+	Capture map[string]string `json:"capture,omitempty" validate:"dive,max=1000"`
+	// Checks to perform on each response, in addition to the status-code.
+	Assertions []requests.Assertion `json:"assertions,omitempty" validate:"dive"`
+	// JSON Schema which json-responses are validated against. Only inline schemas are supported.
+	ResponseSchema *requests.ResponseSchema `json:"responseSchema,omitempty"`
+	// Rules for normalizing responses before they are hashed. Applied after the rules of the endpoint.
+	Normalization []requests.NormalizationRule `json:"normalization,omitempty"`
+	// Objective which each run is scored against. Overrides the SLO of the endpoint.
+	SLO *requests.SLO `json:"slo,omitempty"`
+}
+
+// Validate checks the parts of the payload which the struct-validator cannot.
+func (p RequestPayload) Validate() error {
+	if err := requests.ValidateAssertions(p.Assertions); err != nil {
+		return err
+	}
+	if p.SLO != nil {
+		if err := p.SLO.Validate(); err != nil {
+			return err
+		}
+	}
+	if err := requests.ValidateNormalizationRules(p.Normalization); err != nil {
+		return err
+	}
+	if err := p.Config.Validate(); err != nil {
+		return err
+	}
+	if p.ResponseSchema != nil {
+		// Reading files from the server is not something the api should allow.
+		if p.ResponseSchema.File != "" {
+			return fmt.Errorf("response-schema: file is not supported through the api, use an inline schema")
+		}
+		return p.ResponseSchema.Validate()
+	}
+	return nil
+}
+
+// GoldenPayload picks a response of a stored run as the golden response of a request
+type GoldenPayload struct {
+	// ID of the stored run
+	// required: true
+	StatID string `json:"statId" validate:"required"`
+	// Hash of the response, as in the responseHashMap of the run. Defaults to the most common response
+	Hash string `json:"hash,omitempty"`
 }
 
 type EndpointEntity struct {
@@ -51,7 +119,9 @@ type Endpoint struct {
 type RequestEntity struct {
 	requests.Request
 	Entity
-	Config *Config `json:"config,omitempty"`
+	Config *Config  `json:"config,omitempty"`
+	Label  string   `json:"label,omitempty"`
+	Tags   []string `json:"tags,omitempty"`
 }
 
 type ScheduleEntity struct {
@@ -0,0 +1,52 @@
+package types
+
+import (
+	"sort"
+
+	"github.com/runar-rkmedia/gabyoall/report"
+	"github.com/runar-rkmedia/gabyoall/requests"
+)
+
+// HTMLReport collects the summary, charts and sampled error-responses of a stored run.
+// The config should have its secrets redacted already.
+func (s StatEntity) HTMLReport(title string, config interface{}) report.HTMLReport {
+	r := report.HTMLReport{
+		Title:      title,
+		StartTime:  s.StartTime,
+		Throughput: s.Throughput,
+		Elapsed:    s.Throughput.Elapsed(),
+		SLO:        s.SLO,
+		Config:     config,
+	}
+	if s.TimeSeries != nil && len(s.TimeSeries.Map) > 0 {
+		r.Latency = s.TimeSeries.Latencies()
+	}
+
+	errorTypes := make([]requests.ErrorType, 0, len(s.Histograms))
+	for errorType := range s.Histograms {
+		errorTypes = append(errorTypes, errorType)
+	}
+	sort.Slice(errorTypes, func(i, j int) bool { return s.Histograms[errorTypes[i]].Count() > s.Histograms[errorTypes[j]].Count() })
+	for _, errorType := range errorTypes {
+		stats := s.Histograms[errorType].Stats()
+		r.Summary = append(r.Summary, report.NewSummaryRow(string(errorType), &stats))
+	}
+	overall := s.Stats
+	r.Summary = append(r.Summary, report.NewSummaryRow("(all)", &overall))
+
+	for _, errorType := range errorTypes {
+		if errorType == "" {
+			continue
+		}
+		bucket := report.ErrorBucket{ErrorType: errorType, Count: int(s.Histograms[errorType].Count())}
+		if s.Samples != nil {
+			for _, sample := range s.Samples.Failures[errorType] {
+				bucket.AddResponse(report.NewFailureSample(sample))
+				bucket.RequestIDs = append(bucket.RequestIDs, sample.RequestID)
+			}
+		}
+		r.Errors = append(r.Errors, bucket)
+	}
+	report.SortErrorBuckets(r.Errors)
+	return r
+}
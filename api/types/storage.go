@@ -2,6 +2,8 @@ package types
 
 import (
 	"time"
+
+	"github.com/runar-rkmedia/gabyoall/requests"
 )
 
 type Storage interface {
@@ -16,6 +18,8 @@ type Storage interface {
 	CreateRequest(e RequestPayload) (RequestEntity, error)
 	UpdateRequest(id string, p RequestPayload) (RequestEntity, error)
 	SoftDeleteRequest(id string) (RequestEntity, error)
+	// Sets the golden response of the request. Nil removes it.
+	SetGoldenResponse(id string, golden *requests.GoldenResponse) (RequestEntity, error)
 
 	Schedules() (es map[string]ScheduleEntity, err error)
 	Schedule(id string) (ScheduleEntity, error)
@@ -24,6 +28,7 @@ type Storage interface {
 	SoftDeleteSchedule(id string) (ScheduleEntity, error)
 
 	CompactStats() (es map[string]StatEntity, err error)
+	CompactStat(id string) (StatEntity, error)
 	CleanCompactStats() (err error)
 
 	Size() (int64, error)
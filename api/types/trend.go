@@ -0,0 +1,62 @@
+package types
+
+import (
+	"sort"
+	"time"
+
+	"github.com/runar-rkmedia/gabyoall/requests"
+)
+
+// ScheduleTrend is the score of each run of a schedule over time
+type ScheduleTrend struct {
+	ScheduleID string `json:"scheduleId"`
+	// Runs, oldest first
+	Runs []TrendPoint `json:"runs"`
+	// Number of runs which were scored against an SLO
+	ScoredRuns int `json:"scoredRuns"`
+	// Number of runs which were within the availability-target of their SLO
+	CompliantRuns int `json:"compliantRuns"`
+}
+
+// TrendPoint is the summary of a single run
+type TrendPoint struct {
+	RunID     string    `json:"runId"`
+	StartTime time.Time `json:"startTime"`
+	Requests  int       `json:"requests"`
+	// Percentage of the requests which failed
+	ErrorRate   float64              `json:"errorRate"`
+	Percentiles requests.Percentiles `json:"percentiles"`
+	// Set if the run was scored against an SLO
+	SLO *requests.SLOResult `json:"slo,omitempty"`
+}
+
+// NewScheduleTrend picks the runs of the schedule from the stats
+func NewScheduleTrend(scheduleID string, stats map[string]StatEntity) ScheduleTrend {
+	trend := ScheduleTrend{ScheduleID: scheduleID, Runs: []TrendPoint{}}
+	for id, s := range stats {
+		if s.ScheduleID != scheduleID {
+			continue
+		}
+		p := TrendPoint{
+			RunID:       id,
+			StartTime:   s.StartTime,
+			Requests:    s.CompletedRequests,
+			Percentiles: s.Stats.Percentiles,
+			SLO:         s.SLO,
+		}
+		if total := s.Histogram.Count(); total > 0 {
+			p.ErrorRate = float64(total-s.Histograms[""].Count()) / float64(total) * 100
+		}
+		if s.SLO != nil {
+			trend.ScoredRuns++
+			if s.SLO.Compliant {
+				trend.CompliantRuns++
+			}
+		}
+		trend.Runs = append(trend.Runs, p)
+	}
+	sort.Slice(trend.Runs, func(i, j int) bool {
+		return trend.Runs[i].StartTime.Before(trend.Runs[j].StartTime)
+	})
+	return trend
+}
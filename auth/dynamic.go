@@ -41,6 +41,8 @@ type DynamicAuthResult struct {
 	Token     string
 	HeaderKey string
 	Responses []*DynamicHttpResponse
+	// Cookies set by any of the responses. These are used to seed the cookie-jars of the virtual users.
+	Cookies []*http.Cookie
 }
 
 func (da *DynamicAuth) Retrieve() (DynamicAuthResult, error) {
@@ -60,9 +62,15 @@ func (da *DynamicAuth) Retrieve() (DynamicAuthResult, error) {
 			return dyn, err
 		}
 		results[i] = *dynRes
+		dyn.Cookies = append(dyn.Cookies, (&http.Response{Header: dynRes.response.Headers}).Cookies()...)
 	}
 	last := results[len(results)-1]
 	if last.result == nil {
+		// A login-step may only set cookies, without producing a token.
+		if len(dyn.Cookies) > 0 {
+			dyn.HeaderKey = da.HeaderKey
+			return dyn, nil
+		}
 		return dyn, fmt.Errorf("result was nil")
 	}
 	switch last.result.(type) {
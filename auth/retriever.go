@@ -1,7 +1,9 @@
 package auth
 
 import (
+	"encoding/base64"
 	"fmt"
+	"net/http"
 	"strings"
 
 	"github.com/runar-rkmedia/gabyoall/cmd"
@@ -12,7 +14,7 @@ type ValidityStringer interface {
 	PrintValidity() (string, error)
 }
 
-func Retrieve(l logger.AppLogger, a cmd.AuthConfig) (err error, token string, payload *TokenPayload, validityStringer ValidityStringer) {
+func Retrieve(l logger.AppLogger, a cmd.AuthConfig) (err error, token string, payload *TokenPayload, validityStringer ValidityStringer, cookies []*http.Cookie) {
 	switch strings.ToLower(a.Kind) {
 	case "dynamic":
 		if len(a.Dynamic.Requests) == 0 {
@@ -31,10 +33,22 @@ func Retrieve(l logger.AppLogger, a cmd.AuthConfig) (err error, token string, pa
 		res, err := da.Retrieve()
 		if err != nil {
 			l.Error().Err(err).Msg("Failed during dynamic-auth-request")
-			return err, token, payload, validityStringer
+			return err, token, payload, validityStringer, cookies
 		}
 		token = res.Token
+		cookies = res.Cookies
+	case "basic":
+		if a.Username == "" {
+			err = fmt.Errorf("With auth.kind set to basic, a username is required")
+			l.Error().Err(err).Msg("failed during Retrieve.basic")
+			return
+		}
+		token = base64.StdEncoding.EncodeToString([]byte(a.Username + ":" + a.Password))
 	case "bearer":
+		if a.Token != "" {
+			token = a.Token
+			return
+		}
 		if a.ClientID != "" {
 			bearerC := NewBearerTokenCreator(
 				logger.GetLogger("token-handler"),
@@ -60,7 +74,7 @@ func Retrieve(l logger.AppLogger, a cmd.AuthConfig) (err error, token string, pa
 				})
 				if err != nil {
 					l.Error().Err(err).Msg("failed to retrieve token")
-					return err, token, payload, validityStringer
+					return err, token, payload, validityStringer, cookies
 				}
 				token = tokenPayload.Token
 				payload = &tokenPayload
@@ -73,3 +87,14 @@ func Retrieve(l logger.AppLogger, a cmd.AuthConfig) (err error, token string, pa
 	}
 	return
 }
+
+// Returns the prefix to use for the token in the auth-header, for the auth-kind
+func HeaderPrefix(kind string) string {
+	switch strings.ToLower(kind) {
+	case "bearer":
+		return "Bearer "
+	case "basic":
+		return "Basic "
+	}
+	return ""
+}
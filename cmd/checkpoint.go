@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/runar-rkmedia/gabyoall/requests"
+)
+
+// Checkpoint is the state of a run in progress. It is written periodically next to the output-file,
+// so that a run which crashed or was killed can be resumed, with the statistics merged.
+type Checkpoint struct {
+	Time time.Time `json:"time"`
+	// Number of requests of the run, and how many of them had completed
+	RequestCount int `json:"requestCount"`
+	Completed    int `json:"completed"`
+	// Time spent running so far, over all the sessions of the run
+	Elapsed time.Duration `json:"elapsed"`
+	// Stats of the completed requests by ErrorType, since the stats of the output are rounded to milliseconds.
+	// The latencies are merged from the histograms of the output.
+	Stats map[requests.ErrorType]CheckpointStats `json:"stats"`
+	// Number of responses by ErrorType and the hash of the response, which are not in the output with a request-log
+	ResponseCounts map[requests.ErrorType]map[string]int `json:"responseCounts"`
+	// Size in bytes of the request-log, if any. The lines after it are of requests which are run again when resuming.
+	RequestLogSize *int64  `json:"requestLogSize,omitempty"`
+	Output         *Output `json:"output"`
+}
+
+// CheckpointStats are the exact stats of an ErrorType
+type CheckpointStats struct {
+	Count int           `json:"count"`
+	Total time.Duration `json:"total"`
+	Min   time.Duration `json:"min"`
+	Max   time.Duration `json:"max"`
+}
+
+// CheckpointPath returns the path which checkpoints of the run are written to, next to the output-file
+func (o *Output) CheckpointPath() string {
+	if o.path == "" {
+		return ""
+	}
+	return o.path + ".checkpoint.json"
+}
+
+// WriteCheckpoint writes the state of the run, replacing the previous checkpoint atomically,
+// so that a crash while writing leaves the previous checkpoint intact.
+func (o *Output) WriteCheckpoint(requestCount int, elapsed time.Duration) error {
+	if o.path == "" {
+		return nil
+	}
+	o.CalculateStats()
+	stats := make(map[requests.ErrorType]CheckpointStats, len(o.Stats))
+	for errorType, s := range o.Stats {
+		stats[errorType] = CheckpointStats{Count: s.Count, Total: s.Total, Min: s.Min, Max: s.Max}
+	}
+	responseCounts := make(map[requests.ErrorType]map[string]int, len(o.responseCounts))
+	for errorType, counts := range o.responseCounts {
+		m := make(map[string]int, len(counts))
+		for hash, n := range counts {
+			m[base64.URLEncoding.EncodeToString(hash[:])] = n
+		}
+		responseCounts[errorType] = m
+	}
+	var requestLogSize *int64
+	if o.RequestLog != nil {
+		size, err := o.RequestLog.Size()
+		if err != nil {
+			return fmt.Errorf("failed to read the size of the request-log: %w", err)
+		}
+		requestLogSize = &size
+	}
+	b, err := json.Marshal(Checkpoint{
+		Time:           time.Now(),
+		RequestCount:   requestCount,
+		Completed:      o.Overall.Count,
+		Elapsed:        elapsed,
+		Stats:          stats,
+		ResponseCounts: responseCounts,
+		RequestLogSize: requestLogSize,
+		Output:         o,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint: %w", err)
+	}
+	if err := writeFileAtomic(o.CheckpointPath(), b); err != nil {
+		return fmt.Errorf("failed to write checkpoint to path %s: %w", o.CheckpointPath(), err)
+	}
+	return nil
+}
+
+// RemoveCheckpoint removes the checkpoint, once the run has completed and its output is written
+func (o *Output) RemoveCheckpoint() error {
+	if o.path == "" {
+		return nil
+	}
+	if err := os.Remove(o.CheckpointPath()); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// ReadCheckpoint reads a checkpoint written by WriteCheckpoint
+func ReadCheckpoint(path string) (Checkpoint, error) {
+	var c Checkpoint
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return c, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return c, fmt.Errorf("failed to unmarshal checkpoint %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// Resume merges the statistics of the checkpoint into the output, before the rest of the run is added.
+// The throughput of the previous sessions is put before this one, without the time in between.
+// The latency-series of the charts, and the metrics and sinks, only cover the current session.
+func (o *Output) Resume(c Checkpoint) error {
+	previous := c.Output
+	if previous == nil {
+		return fmt.Errorf("the checkpoint has no output")
+	}
+	for errorType, s := range c.Stats {
+		o.Count[errorType] += s.Count
+		stats := requests.Stats{Count: s.Count, Total: s.Total, Min: s.Min, Max: s.Max}
+		o.Overall.Merge(stats)
+		current, ok := o.Stats[errorType]
+		if !ok {
+			current = &requests.Stats{}
+			o.Stats[errorType] = current
+		}
+		current.Merge(stats)
+		h, ok := o.Histograms[errorType]
+		if !ok {
+			h = requests.NewHistogram()
+			o.Histograms[errorType] = h
+		}
+		h.Merge(previous.Histograms[errorType])
+		o.Histogram.Merge(previous.Histograms[errorType])
+	}
+	for errorType, counts := range c.ResponseCounts {
+		for key, n := range counts {
+			hash, err := requests.ParseHash(key)
+			if err != nil {
+				return fmt.Errorf("the checkpoint has an invalid response-hash: %w", err)
+			}
+			if o.responseCounts[errorType] == nil {
+				o.responseCounts[errorType] = map[requests.Hash]int{}
+			}
+			o.responseCounts[errorType][hash] += n
+		}
+	}
+	for errorType, details := range previous.Details {
+		o.Details[errorType] = append(details, o.Details[errorType]...)
+	}
+	for hash, content := range previous.ResponseHashMap {
+		content.Count += o.ResponseHashMap[hash].Count
+		o.ResponseHashMap[hash] = content
+	}
+	for hash, violations := range previous.SchemaViolations {
+		o.SchemaViolations[hash] = violations
+	}
+	for hash, changes := range previous.GoldenDiffs {
+		o.GoldenDiffs[hash] = changes
+	}
+	o.GoldenMismatches += previous.GoldenMismatches
+	o.SchemaViolationBreakdown.Merge(previous.SchemaViolationBreakdown)
+	o.GqlErrors.Merge(previous.GqlErrors)
+	// The samples are continued, so that each request of the whole run has the same chance of being kept
+	if previous.Samples != nil && o.Samples != nil {
+		previous.Samples.Size = o.Samples.Size
+		o.Samples = previous.Samples
+	}
+	o.Throughput.Prepend(previous.Throughput)
+	return nil
+}
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/runar-rkmedia/gabyoall/logger"
+	"github.com/runar-rkmedia/gabyoall/requests"
+)
+
+func TestOutput_Resume(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out.json")
+	newOutput := func() *Output {
+		out, err := NewOutput(logger.GetLogger("test"), path, "", requests.Request{}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out.Throughput = requests.NewThroughputSeries(time.Now())
+		return &out
+	}
+	stat := func(errorType requests.ErrorType, ms int, body string) requests.RequestStat {
+		s := requests.RequestStat{ErrorType: errorType, Duration: time.Duration(ms) * time.Microsecond * 1001}
+		s.ContentType = "application/json"
+		s.RawResponse = []byte(body)
+		if errorType != "" {
+			s.GqlErrors = []requests.Error{{Message: string(errorType)}}
+		}
+		return s
+	}
+
+	first := newOutput()
+	first.AddStat(stat("", 10, `{"data":{"a":1}}`))
+	first.AddStat(stat("Timeout", 1000, `{"errors":[{"message":"Timeout"}]}`))
+	if err := first.WriteCheckpoint(4, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	c, err := ReadCheckpoint(first.CheckpointPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Completed != 2 || c.RequestCount != 4 || c.Elapsed != time.Second {
+		t.Errorf("unexpected checkpoint %d/%d after %s", c.Completed, c.RequestCount, c.Elapsed)
+	}
+
+	resumed := newOutput()
+	if err := resumed.Resume(c); err != nil {
+		t.Fatal(err)
+	}
+	resumed.AddStat(stat("", 30, `{"data":{"a":1}}`))
+	resumed.AddStat(stat("", 20, `{"data":{"a":2}}`))
+	resumed.CalculateStats()
+	tests := []struct {
+		name      string
+		got, want interface{}
+	}{
+		{"count", resumed.Overall.Count, 4},
+		{"successes", resumed.Count[""], 3},
+		{"exact min", resumed.Overall.Min, 10 * 1001 * time.Microsecond},
+		{"exact total", resumed.Overall.Total, 1060 * 1001 * time.Microsecond},
+		{"histogram", resumed.Histograms["Timeout"].Count(), int64(1)},
+		{"overall histogram", resumed.Histogram.Count(), int64(4)},
+		{"threshold count", resumed.ThresholdStats(0).Count[""], 3},
+		{"details", len(resumed.Details[""]), 3},
+		{"distinct responses", len(resumed.ResponseHashMap), 3},
+		{"responses of error-type", len(resumed.responseCounts["Timeout"]), 1},
+		{"responses of the previous session", resumed.responseCounts[""][*first.Details[""][0].ResponseHash], 2},
+		{"gql errors", resumed.GqlErrors.Total, 1},
+		{"samples seen", resumed.Samples.Seen["Timeout"], 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("got %v, want %v", tt.got, tt.want)
+			}
+		})
+	}
+	if err := resumed.RemoveCheckpoint(); err != nil {
+		t.Fatal(err)
+	}
+	// Only the output-directory remains, without temporary files from the atomic writes
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 0 {
+		t.Errorf("expected the checkpoint to be removed, got %v", entries)
+	}
+}
+
+func TestOutput_WriteCheckpoint_requestLog(t *testing.T) {
+	dir := t.TempDir()
+	out, err := NewOutput(logger.GetLogger("test"), filepath.Join(dir, "out.json"), "", requests.Request{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logPath := filepath.Join(dir, "log.jsonl")
+	if out.RequestLog, err = requests.NewRequestLog(logPath); err != nil {
+		t.Fatal(err)
+	}
+	defer out.RequestLog.Close()
+	out.AddStat(requests.RequestStat{Duration: time.Millisecond})
+	if err := out.WriteCheckpoint(2, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	c, err := ReadCheckpoint(out.CheckpointPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.RequestLogSize == nil || *c.RequestLogSize != info.Size() || info.Size() == 0 {
+		t.Errorf("expected the checkpoint to have the size of the request-log, %d, got %v", info.Size(), c.RequestLogSize)
+	}
+}
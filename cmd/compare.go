@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/runar-rkmedia/gabyoall/compare"
+	"github.com/runar-rkmedia/gabyoall/requests"
+	"github.com/spf13/cobra"
+)
+
+var compareJSON bool
+
+var compareCmd = &cobra.Command{
+	Use:          "compare <output-file-a> <output-file-b>",
+	Short:        "Compares two runs, like before and after a deploy",
+	SilenceUsage: true,
+	Long: `Reads the output-files of two previous runs, and reports how throughput,
+error-rate and latency-percentiles changed from the first to the second.
+
+The latencies of the successful requests are compared with the Mann-Whitney U test,
+to tell whether the difference is significant or likely just noise.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		a, err := ReadRun(args[0])
+		if err != nil {
+			return err
+		}
+		b, err := ReadRun(args[1])
+		if err != nil {
+			return err
+		}
+		result := compare.Compare(a, b)
+		if compareJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(result)
+		}
+		fmt.Print(result.Table())
+		return nil
+	},
+}
+
+// ReadRun reads the histograms and throughput from an output-file
+func ReadRun(path string) (compare.Run, error) {
+	var out struct {
+		Histograms map[requests.ErrorType]*requests.Histogram `json:"histograms"`
+		Throughput *requests.ThroughputSeries                 `json:"throughput"`
+	}
+	if err := ReadYamlFile(path, &out); err != nil {
+		return compare.Run{}, err
+	}
+	if out.Histograms == nil {
+		return compare.Run{}, fmt.Errorf("the output-file %s has no histograms, it may be from an older version", path)
+	}
+	return compare.NewRun(path, out.Histograms, out.Throughput.Elapsed()), nil
+}
+
+func init() {
+	compareCmd.Flags().BoolVar(&compareJSON, "json", false, "Print the result as json instead of a table")
+	rootCmd.AddCommand(compareCmd)
+}
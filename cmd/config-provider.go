@@ -5,6 +5,7 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -12,13 +13,15 @@ import (
 
 var (
 	cfgFile string
-	rootCmd = &cobra.Command{
+	// Set when the root-command itself was invoked, and not a subcommand like diff
+	rootInvoked bool
+	rootCmd     = &cobra.Command{
 		Use:   "gobyoall",
 		Short: "Gobyoall is a flexible stress-tester for servers",
 		/// TODO: provide more info, documentations
 		Long: `See https://github.com/runar-rkmedia/gabyoall`,
 		Run: func(cmd *cobra.Command, args []string) {
-			// Do Stuff Here
+			rootInvoked = true
 		},
 	}
 )
@@ -66,6 +69,16 @@ func ReadConfig(y ...func()) {
 				defaultInt = int(n)
 			}
 			rootCmd.PersistentFlags().IntP(cfgName, short, defaultInt, desc)
+		case "Duration":
+			var defaultDuration time.Duration
+			if defaultStr != "" {
+				d, err := time.ParseDuration(defaultStr)
+				if err != nil {
+					panic(fmt.Sprintf("failed to convert default-tag (%s) on config-field %s", defaultStr, field.Name))
+				}
+				defaultDuration = d
+			}
+			rootCmd.PersistentFlags().DurationP(cfgName, short, defaultDuration, desc)
 		case "[]int":
 			var defaultInts []int
 			if defaultStr != "" {
@@ -79,6 +92,12 @@ func ReadConfig(y ...func()) {
 				}
 			}
 			rootCmd.PersistentFlags().IntSliceP(cfgName, short, defaultInts, desc)
+		case "[]string":
+			var defaultStrings []string
+			if defaultStr != "" {
+				defaultStrings = strings.Split(defaultStr, ",")
+			}
+			rootCmd.PersistentFlags().StringArrayP(cfgName, short, defaultStrings, desc)
 		case "interface {}":
 			rootCmd.PersistentFlags().StringP(cfgName, short, defaultStr, desc)
 		case "map[string]string":
@@ -94,6 +113,10 @@ func ReadConfig(y ...func()) {
 		case "map[string]interface {}":
 			continue
 		default:
+			// Nested sections, like Api, are only available in the config-file
+			if field.Type.Kind() == reflect.Struct {
+				continue
+			}
 			panic(fmt.Sprintf("no handler for %s, %s", field.Name, kind))
 		}
 		viper.BindPFlag(mapstructure, rootCmd.PersistentFlags().Lookup(cfgName))
@@ -105,3 +128,8 @@ func Execute(y ...func()) error {
 	ReadConfig(y...)
 	return rootCmd.Execute()
 }
+
+// RootInvoked reports whether the stress-test should run, e.g. no subcommand was invoked.
+func RootInvoked() bool {
+	return rootInvoked
+}
@@ -1,15 +1,23 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"path"
+	"reflect"
+	"strings"
 	"time"
 
+	"github.com/mitchellh/mapstructure"
 	"github.com/runar-rkmedia/gabyoall/logger"
+	"github.com/runar-rkmedia/gabyoall/requests"
+	"github.com/runar-rkmedia/gabyoall/utils"
 	"github.com/spf13/viper"
 )
 
+const redacted = "**REDACTED**"
+
 type AuthConfig struct {
 	Dynamic DynamicAuth
 	Kind    string
@@ -34,26 +42,46 @@ type ImpersionationCredentials struct {
 	UserNameToImpersonate string
 }
 type Config struct {
-	Auth              AuthConfig             `cfg:"-"`
-	Url               string                 `cfg:"url" description:"The url to make requests to"`
-	NoTokenValidation bool                   `cfg:"no-token-validation" description:"If set, will skip validation of token"`
-	PrintTable        bool                   `cfg:"print-table" description:"If set, will print table while running"`
-	AuthToken         string                 `cfg:"auth-token" description:"Set to use a token"`
-	OperationName     string                 `cfg:"operation-name" description:"For Graphql, you may set an operation-name"`
-	Body              interface{}            `cfg:"data" short:"d" description:"Data to include in requests."`
-	Header            map[string]string      `cfg:"header" short:"H" description:"Additional headers to include"`
-	Method            string                 `cfg:"method" short:"X" description:"Http-method"`
-	Query             string                 `cfg:"query" description:"For Graphql, you may set a query"`
-	Variables         map[string]interface{} `cfg:"variables" description:"For Graphql, you may add variables"`
-	LogLevel          string                 `cfg:"log-level" default:"info" description:"Log-level to use. Can be trace,debug,info,warn(ing),error or panic"`
-	LogFormat         string                 `cfg:"log-format" default:"human" description:"Format of the logs. Can be human or json"`
-	Output            string                 `cfg:"output" description:"File to output results to"`
-	OkStatusCodes     []int                  `cfg:"ok-status-codes" description:"list of status-codes to consider ok. If none is provided, any status-code within 200-299 is considered ok."`
-	ResponseData      bool                   `cfg:"response-data" description:"Set to include response-data in output"`
-	Mock              bool                   `cfg:"mock" description:"Enable to mock the requests."`
-	Concurrency       int                    `cfg:"concurrency" description:"Amount of concurrent requests." default:"100" short:"c"`
-	RequestCount      int                    `cfg:"request-count" default:"200" description:"Number of request to make total" short:"n"`
-	Api               ApiConfig              `cfg:"api" description:"Used with the api-server"`
+	Auth                AuthConfig                    `cfg:"-"`
+	Url                 string                        `cfg:"url" description:"The url to make requests to"`
+	NoTokenValidation   bool                          `cfg:"no-token-validation" description:"If set, will skip validation of token"`
+	PrintTable          bool                          `cfg:"print-table" description:"If set, will print table while running"`
+	AuthToken           string                        `cfg:"auth-token" description:"Set to use a token"`
+	OperationName       string                        `cfg:"operation-name" description:"For Graphql, you may set an operation-name"`
+	Body                interface{}                   `cfg:"data" short:"d" description:"Data to include in requests."`
+	Header              map[string]string             `cfg:"header" short:"H" description:"Additional headers to include"`
+	Method              string                        `cfg:"method" short:"X" description:"Http-method"`
+	Query               string                        `cfg:"query" description:"For Graphql, you may set a query"`
+	Variables           map[string]interface{}        `cfg:"variables" description:"For Graphql, you may add variables"`
+	Vars                map[string]string             `cfg:"vars" description:"Variables available in templating, as {{ .Vars.key }}"`
+	LogLevel            string                        `cfg:"log-level" default:"info" description:"Log-level to use. Can be trace,debug,info,warn(ing),error or panic"`
+	LogFormat           string                        `cfg:"log-format" default:"human" description:"Format of the logs. Can be human or json"`
+	Output              string                        `cfg:"output" description:"File to output results to"`
+	CheckpointInterval  time.Duration                 `cfg:"checkpoint-interval" description:"Interval to write a checkpoint of the run next to the output-file at, like 30s, so that a run which crashed or was killed can be continued with --resume. Zero disables"`
+	Resume              bool                          `cfg:"resume" description:"Continue a partially completed run from the checkpoint next to its output-file, with the statistics merged"`
+	RequestLog          string                        `cfg:"request-log" description:"File to stream a line to for each completed request, as jsonl, or csv if it ends in .csv. The details of each request are then not kept in memory. Rebuild the summary with the report-command"`
+	OkStatusCodes       []int                         `cfg:"ok-status-codes" description:"list of status-codes to consider ok. If none is provided, any status-code within 200-299 is considered ok."`
+	ResponseData        bool                          `cfg:"response-data" description:"Set to include response-data in output"`
+	Mock                bool                          `cfg:"mock" description:"Enable to mock the requests."`
+	Concurrency         int                           `cfg:"concurrency" description:"Amount of concurrent requests." default:"100" short:"c"`
+	RequestCount        int                           `cfg:"request-count" default:"200" description:"Number of request to make total" short:"n"`
+	ResetSessionEvery   int                           `cfg:"reset-session-every" description:"Resets the cookies and variables of each virtual user (worker) every n requests, to simulate new users arriving. Zero disables"`
+	Capture             map[string]string             `cfg:"capture" description:"Values to capture from json-responses into the variables of each virtual user, as name=jmes-path"`
+	Assertions          []requests.Assertion          `cfg:"-" description:"Checks to perform on each response, in addition to the status-code. Can only be set in the config-file"`
+	ResponseSchema      *requests.ResponseSchema      `cfg:"-" description:"JSON Schema which json-responses are validated against. Can only be set in the config-file"`
+	ClassificationRules []requests.ClassificationRule `cfg:"-" description:"Rules for putting failed requests into named buckets. The first matching rule wins. Can only be set in the config-file"`
+	Normalization       []requests.NormalizationRule  `cfg:"-" description:"Rules for normalizing responses before they are hashed, like dropping timestamps. Can only be set in the config-file"`
+	Thresholds          []string                      `cfg:"threshold" description:"Pass/fail-criteria evaluated at the end of the run, like 'p95 < 300ms', 'error_rate < 1%', 'rps > 200' or 'count{errorType=\"Timeout\"} == 0'. Exits non-zero if any fail"`
+	AbortOnThreshold    bool                          `cfg:"abort-on-threshold" description:"Abort the run early if a threshold is already irrecoverably breached"`
+	PartialDataOk       bool                          `cfg:"partial-data-ok" description:"If set, GraphQL-responses with errors are successful as long as they have partial data. The errors are recorded either way"`
+	Samples             int                           `cfg:"samples" default:"10" description:"Number of the slowest requests, and of samples of each error-type, to keep with full details. Negative disables"`
+	SLO                 *requests.SLO                 `cfg:"-" description:"Objective which the run is scored against, with an Apdex-score and availability. Can only be set in the config-file"`
+	Baseline            string                        `cfg:"baseline" description:"Output-file of a previous run, which the markdown-summary (--output summary.md) is compared against"`
+	Golden              string                        `cfg:"golden" description:"Output-file of a previous run to use as the golden response, like run.json or run.json#<hash>. Defaults to its most common response. Responses which differ fail as GoldenMismatch"`
+	OtlpEndpoint        string                        `cfg:"otlp-endpoint" description:"OTLP/HTTP-endpoint of an OpenTelemetry-collector to export a client-span for each request to, like http://localhost:4318. The spans share trace-ids with the traceparent-header of the requests"`
+	Sinks               []string                      `cfg:"sink" description:"Sinks to push per-second aggregates of the run to, like influx+http://localhost:8086/write?db=x, influx+udp://localhost:8089, graphite://localhost:2003 or statsd://localhost:8125"`
+	MetricsAddr         string                        `cfg:"metrics-addr" description:"Address to expose live metrics of the run on, in the Prometheus format, like localhost:9100. They are served at /metrics"`
+	Api                 ApiConfig                     `cfg:"api" description:"Used with the api-server"`
 }
 
 type ApiConfig struct {
@@ -81,7 +109,7 @@ type DynamicRequest struct {
 
 func GetConfig(l logger.AppLogger) *Config {
 	var cfg Config
-	viper.Unmarshal(&cfg)
+	viper.Unmarshal(&cfg, decodeHook)
 	if cfg.Concurrency > cfg.RequestCount {
 		cfg.Concurrency = cfg.RequestCount
 	}
@@ -89,6 +117,46 @@ func GetConfig(l logger.AppLogger) *Config {
 	return &cfg
 }
 
+// The default hooks of viper, and one for values which are kept as json, like the equals of assertions.
+// That one goes first, since a json.RawMessage would otherwise be split as a comma-separated string.
+var decodeHook = viper.DecodeHook(mapstructure.ComposeDecodeHookFunc(
+	rawJSONHook,
+	mapstructure.StringToTimeDurationHookFunc(),
+	mapstructure.StringToSliceHookFunc(","),
+))
+
+func rawJSONHook(from reflect.Type, to reflect.Type, data interface{}) (interface{}, error) {
+	if to != reflect.TypeOf(json.RawMessage{}) {
+		return data, nil
+	}
+	return json.Marshal(jsonCompatible(data))
+}
+
+// Maps within lists are read from yaml with keys of any type, which cannot be marshalled to json
+func jsonCompatible(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(value))
+		for k, vv := range value {
+			m[fmt.Sprint(k)] = jsonCompatible(vv)
+		}
+		return m
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(value))
+		for k, vv := range value {
+			m[k] = jsonCompatible(vv)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(value))
+		for i, vv := range value {
+			s[i] = jsonCompatible(vv)
+		}
+		return s
+	}
+	return v
+}
+
 func InitConfig() error {
 	if cfgFile != "" {
 		viper.SetConfigFile(cfgFile)
@@ -113,3 +181,55 @@ func InitConfig() error {
 	}
 	return nil
 }
+
+// Redacted returns a copy of the config with secrets, like tokens, passwords and authorization-headers, replaced.
+func (c Config) Redacted() Config {
+	redact := func(s *string) {
+		if *s != "" {
+			*s = redacted
+		}
+	}
+	redact(&c.AuthToken)
+	redact(&c.Auth.Token)
+	redact(&c.Auth.ClientSecret)
+	redact(&c.Auth.ImpersionationCredentials.Password)
+	c.Auth.Payload = nil
+	c.Header = redactHeaders(c.Header, c.Auth.HeaderKey)
+	// Urls may hold credentials in their query or userinfo, like the token of an influx-sink
+	c.OtlpEndpoint = utils.RedactURL(c.OtlpEndpoint)
+	if len(c.Sinks) > 0 {
+		sinks := make([]string, len(c.Sinks))
+		for i, s := range c.Sinks {
+			sinks[i] = utils.RedactURL(s)
+		}
+		c.Sinks = sinks
+	}
+	if len(c.Auth.Dynamic.Requests) > 0 {
+		// These are typically used to log in, so their bodies hold credentials
+		dynamic := make([]DynamicRequest, len(c.Auth.Dynamic.Requests))
+		for i, r := range c.Auth.Dynamic.Requests {
+			if r.Body != nil {
+				r.Body = redacted
+			}
+			r.Headers = redactHeaders(r.Headers, c.Auth.HeaderKey)
+			dynamic[i] = r
+		}
+		c.Auth.Dynamic.Requests = dynamic
+	}
+	return c
+}
+
+// Redacts the sensitive headers, and the header which the auth-token is sent in
+func redactHeaders(h map[string]string, authHeaderKey string) map[string]string {
+	if h == nil {
+		return nil
+	}
+	redactedHeaders := make(map[string]string, len(h))
+	for k, v := range h {
+		if requests.IsSensitiveHeader(k) || (authHeaderKey != "" && strings.EqualFold(k, authHeaderKey)) {
+			v = redacted
+		}
+		redactedHeaders[k] = v
+	}
+	return redactedHeaders
+}
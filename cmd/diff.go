@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/runar-rkmedia/gabyoall/requests"
+	"github.com/spf13/cobra"
+)
+
+var diffCmd = &cobra.Command{
+	Use:          "diff <output-file> [hash-a hash-b]",
+	Short:        "Shows how the response-variants of a run differ",
+	SilenceUsage: true,
+	Long: `Reads the responseHashMap from an output-file of a previous run.
+
+With two hashes, the structured json-diff between those two responses is printed.
+Without hashes, all variants are clustered by which json-paths differ from the most common variant.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 && len(args) != 3 {
+			return fmt.Errorf("expected an output-file, and optionally two hashes")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		bm, err := readResponseHashMap(args[0])
+		if err != nil {
+			return err
+		}
+		var result interface{}
+		if len(args) == 1 {
+			summary, err := bm.Summarize()
+			if err != nil {
+				return err
+			}
+			result = summary
+		} else {
+			a, err := requests.ParseHash(args[1])
+			if err != nil {
+				return err
+			}
+			b, err := requests.ParseHash(args[2])
+			if err != nil {
+				return err
+			}
+			d, err := bm.Diff(a, b)
+			if err != nil {
+				return err
+			}
+			result = d
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(result)
+	},
+}
+
+// Reads the responseHashMap from an output-file
+func readResponseHashMap(path string) (requests.ByteHashMap, error) {
+	var out struct {
+		ResponseHashMap requests.ByteHashMap `json:"responseHashMap"`
+	}
+	if err := ReadYamlFile(path, &out); err != nil {
+		return nil, err
+	}
+	return out.ResponseHashMap, nil
+}
+
+// ReadGoldenResponse reads the golden response from an output-file, like run.json or run.json#<hash>.
+// Without a hash, the most common response is used.
+func ReadGoldenResponse(spec string) (*requests.GoldenResponse, error) {
+	path, hashStr := spec, ""
+	if i := strings.LastIndex(spec, "#"); i >= 0 {
+		path, hashStr = spec[:i], spec[i+1:]
+	}
+	var hash *requests.Hash
+	if hashStr != "" {
+		h, err := requests.ParseHash(hashStr)
+		if err != nil {
+			return nil, err
+		}
+		hash = &h
+	}
+	bm, err := readResponseHashMap(path)
+	if err != nil {
+		return nil, err
+	}
+	return requests.NewGoldenResponse(bm, hash, path)
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/runar-rkmedia/gabyoall/report"
+	"github.com/runar-rkmedia/gabyoall/requests"
+)
+
+// Implemented by content which can be written as a self-contained html-report
+type htmlMarshaler interface {
+	MarshalHTML() ([]byte, error)
+}
+
+func marshalHTML(j interface{}) ([]byte, error) {
+	m, ok := j.(htmlMarshaler)
+	if !ok {
+		return nil, fmt.Errorf("cannot write %T as html", j)
+	}
+	return m.MarshalHTML()
+}
+
+// HTMLReport collects the summary, charts, error-responses, token and config of the run
+func (o *Output) HTMLReport() report.HTMLReport {
+	o.CalculateStats()
+	r := report.HTMLReport{
+		Title:      o.Url,
+		Throughput: o.Throughput,
+		SLO:        o.SLO,
+		JWT:        o.JwtPayload,
+		Elapsed:    o.Throughput.Elapsed(),
+	}
+	if o.Query.OperationName != "" {
+		r.Title += " " + o.Query.OperationName
+	}
+	if o.Throughput != nil {
+		r.StartTime = o.Throughput.StartTime
+	}
+	if o.TimeSeries != nil {
+		r.Latency = o.TimeSeries.Latencies()
+		if r.StartTime.IsZero() {
+			r.StartTime = o.TimeSeries.StartTime
+		}
+	}
+	if o.Config != nil {
+		r.Config = o.Config
+	}
+
+	errorTypes := make([]requests.ErrorType, 0, len(o.Stats))
+	for errorType := range o.Stats {
+		errorTypes = append(errorTypes, errorType)
+	}
+	sort.Slice(errorTypes, func(i, j int) bool { return o.Count[errorTypes[i]] > o.Count[errorTypes[j]] })
+	for _, errorType := range errorTypes {
+		r.Summary = append(r.Summary, report.NewSummaryRow(string(errorType), o.Stats[errorType]))
+	}
+	r.Summary = append(r.Summary, report.NewSummaryRow("(all)", o.Overall))
+
+	for _, errorType := range errorTypes {
+		if errorType == "" {
+			continue
+		}
+		bucket := report.ErrorBucket{ErrorType: errorType, Count: o.Count[errorType]}
+		for hash, n := range o.responseCounts[errorType] {
+			bucket.AddResponse(report.NewResponseSample(hash, o.ResponseHashMap[hash], n))
+		}
+		if o.Samples != nil {
+			for _, s := range o.Samples.Failures[errorType] {
+				bucket.RequestIDs = append(bucket.RequestIDs, s.RequestID)
+			}
+		}
+		r.Errors = append(r.Errors, bucket)
+	}
+	report.SortErrorBuckets(r.Errors)
+	return r
+}
+
+// MarshalHTML writes the output as a self-contained html-report
+func (o *Output) MarshalHTML() ([]byte, error) {
+	return o.HTMLReport().MarshalHTML()
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/runar-rkmedia/gabyoall/logger"
+	"github.com/runar-rkmedia/gabyoall/requests"
+)
+
+func TestOutput_MarshalHTML(t *testing.T) {
+	out, err := NewOutput(logger.GetLogger("test"), "", "https://example.com", requests.Request{OperationName: "getThings"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	ts := requests.NewTimeSeriesWithLabel(start)
+	out.TimeSeries = &ts
+	out.Throughput = ts.Throughput
+	for i, s := range []struct {
+		errorType requests.ErrorType
+		body      string
+	}{{"", `{"data":{}}`}, {"ServerError", `{"errors":[{"message":"boom"}]}`}, {"ServerError", `{"errors":[{"message":"boom"}]}`}} {
+		d := time.Duration(i+1) * 10 * time.Millisecond
+		ts.Push(string(s.errorType), start.Add(d), float64(d))
+		stat := requests.RequestStat{ErrorType: s.errorType, Duration: d, RequestID: "req-" + string(rune('a'+i))}
+		stat.ContentType = "application/json"
+		stat.RawResponse = []byte(s.body)
+		out.AddStat(stat)
+	}
+	config := Config{AuthToken: "secret-token", Header: map[string]string{"Authorization": "Bearer abc", "X-Trace": "1"}}.Redacted()
+	out.Config = &config
+
+	r := out.HTMLReport()
+	if len(r.Errors) != 1 || r.Errors[0].Count != 2 || len(r.Errors[0].Responses) != 1 || r.Errors[0].Responses[0].Count != 2 {
+		t.Fatalf("expected a single error-bucket with one distinct response, got %#v", r.Errors)
+	}
+	b, err := out.MarshalHTML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	html := string(b)
+	tests := []struct {
+		name string
+		want string
+		not  bool
+	}{
+		{"title", "https://example.com getThings", false},
+		{"latency-chart", "Latency (ms)", false},
+		{"error-response", "boom", false},
+		{"request-id", "req-b", false},
+		{"unredacted header", "X-Trace", false},
+		{"token", "secret-token", true},
+		{"authorization", "Bearer abc", true},
+		{"external resources", "<script src", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := strings.Contains(html, tt.want); got == tt.not {
+				t.Errorf("expected contains(%q) to be %v", tt.want, !tt.not)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/runar-rkmedia/gabyoall/report"
+	"github.com/runar-rkmedia/gabyoall/requests"
+)
+
+// Number of sample-messages included for each failing error-type
+const junitSampleMessages = 5
+
+// Implemented by content which can be written as a JUnit XML-report
+type junitMarshaler interface {
+	MarshalJUnit() ([]byte, error)
+}
+
+func marshalJUnit(j interface{}) ([]byte, error) {
+	m, ok := j.(junitMarshaler)
+	if !ok {
+		return nil, fmt.Errorf("cannot write %T as junit", j)
+	}
+	return m.MarshalJUnit()
+}
+
+// MarshalJUnit writes the output as a JUnit XML-report, with each threshold, assertion and error-type as a testcase,
+// and the timing-summary as properties.
+func (o *Output) MarshalJUnit() ([]byte, error) {
+	o.CalculateStats()
+	var start time.Time
+	if o.Throughput != nil {
+		start = o.Throughput.StartTime
+	}
+	name := o.Url
+	if o.Query.OperationName != "" {
+		name += " " + o.Query.OperationName
+	}
+	suite := report.NewJUnitTestSuite(name, start, o.Throughput.Elapsed())
+
+	total := o.Overall.Count
+	failed := total - o.Count[""]
+	suite.AddProperty("requests", total)
+	suite.AddProperty("failed", failed)
+	if elapsed := o.Throughput.Elapsed(); elapsed > 0 {
+		suite.AddProperty("rps", fmt.Sprintf("%.2f", float64(total)/elapsed.Seconds()))
+	}
+	suite.AddProperty("min", o.Overall.Min)
+	suite.AddProperty("avg", o.Overall.Average)
+	suite.AddProperty("max", o.Overall.Max)
+	p := o.Overall.Percentiles
+	suite.AddProperty("p50", p.P50)
+	suite.AddProperty("p90", p.P90)
+	suite.AddProperty("p95", p.P95)
+	suite.AddProperty("p99", p.P99)
+	suite.AddProperty("p99.9", p.P999)
+	if o.SLO != nil {
+		suite.AddProperty("apdex", fmt.Sprintf("%.3f", o.SLO.Apdex))
+	}
+
+	for _, t := range o.Thresholds {
+		var failure *report.JUnitFailure
+		if !t.Ok {
+			failure = &report.JUnitFailure{Type: "threshold", Message: fmt.Sprintf("%s, actual: %s", t.Threshold, t.ActualString)}
+		}
+		suite.AddCase("thresholds", t.Threshold, 0, failure)
+	}
+	for _, a := range o.Query.Assertions {
+		errorType := a.ErrorType()
+		var failure *report.JUnitFailure
+		if n := o.Count[errorType]; n > 0 {
+			failure = &report.JUnitFailure{
+				Type:    "assertion",
+				Message: fmt.Sprintf("%d of %d requests failed", n, total),
+				Details: o.sampleMessages(errorType),
+			}
+		}
+		suite.AddCase("assertions", string(errorType), 0, failure)
+	}
+	if o.SLO != nil {
+		var failure *report.JUnitFailure
+		var messages []string
+		if !o.SLO.AvailabilityCompliant {
+			messages = append(messages, fmt.Sprintf("availability %.2f%% is below the target of %.2f%%", o.SLO.ActualAvailability, o.SLO.Availability))
+		}
+		if !o.SLO.LatencyCompliant {
+			messages = append(messages, fmt.Sprintf("%.2f%% of the successful requests are within %s, below the objective of %.2f%%", o.SLO.WithinLatencyTarget, o.SLO.LatencyTarget, o.SLO.LatencyObjective))
+		}
+		if len(messages) > 0 {
+			failure = &report.JUnitFailure{
+				Type:    "slo",
+				Message: strings.Join(messages, ", "),
+			}
+		}
+		suite.AddCase("slo", fmt.Sprintf("availability >= %g%%, %g%% within %s", o.SLO.Availability, o.SLO.LatencyObjective, o.SLO.LatencyTarget), 0, failure)
+	}
+
+	errorTypes := make([]requests.ErrorType, 0, len(o.Count))
+	for errorType := range o.Count {
+		errorTypes = append(errorTypes, errorType)
+	}
+	sort.Slice(errorTypes, func(i, j int) bool { return errorTypes[i] < errorTypes[j] })
+	for _, errorType := range errorTypes {
+		n := o.Count[errorType]
+		if errorType == "" {
+			suite.AddCase("requests", fmt.Sprintf("%d successful requests", n), 0, nil)
+			continue
+		}
+		suite.AddCase("errors", string(errorType), 0, &report.JUnitFailure{
+			Type:    "error",
+			Message: fmt.Sprintf("%d of %d requests failed", n, total),
+			Details: o.sampleMessages(errorType),
+		})
+	}
+	return report.JUnitTestSuites{Name: "gobyoall", Time: suite.Time, Suites: []report.JUnitTestSuite{suite}}.Marshal()
+}
+
+// Returns a few of the error-messages of the error-type, with their request-ids if they were sampled
+func (o *Output) sampleMessages(errorType requests.ErrorType) string {
+	var lines []string
+	if o.Samples != nil {
+		for _, s := range o.Samples.Failures[errorType] {
+			if len(lines) == junitSampleMessages {
+				break
+			}
+			msg := s.Error
+			if msg == "" {
+				msg = string(errorType)
+			}
+			lines = append(lines, fmt.Sprintf("%s (status %d, request-id %s)", msg, s.StatusCode, s.RequestID))
+		}
+	}
+	if len(lines) > 0 {
+		return strings.Join(lines, "\n")
+	}
+	seen := map[string]bool{}
+	for _, d := range o.Details[errorType] {
+		if len(lines) == junitSampleMessages {
+			break
+		}
+		if d.Error == "" || seen[d.Error] {
+			continue
+		}
+		seen[d.Error] = true
+		lines = append(lines, d.Error)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/runar-rkmedia/gabyoall/logger"
+	"github.com/runar-rkmedia/gabyoall/report"
+	"github.com/runar-rkmedia/gabyoall/requests"
+	"github.com/runar-rkmedia/gabyoall/thresholds"
+)
+
+func TestOutput_MarshalJUnit(t *testing.T) {
+	assertion := requests.Assertion{Kind: requests.AssertStatus, Status: []int{200}}
+	out, err := NewOutput(logger.GetLogger("test"), "", "https://example.com", requests.Request{Assertions: []requests.Assertion{assertion}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []struct {
+		errorType requests.ErrorType
+		err       string
+	}{{"", ""}, {"", ""}, {"Timeout", "context deadline exceeded"}, {assertion.ErrorType(), "status 500"}} {
+		stat := requests.RequestStat{ErrorType: s.errorType, Duration: 10 * time.Millisecond, RequestID: "id-" + string(s.errorType)}
+		stat.Error = s.err
+		out.AddStat(stat)
+	}
+	out.Thresholds = []thresholds.Result{
+		{Threshold: "p95 < 300ms", ActualString: "10ms", Ok: true},
+		{Threshold: "error_rate < 1%", ActualString: "50.00%", Ok: false},
+	}
+	b, err := out.MarshalJUnit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got report.JUnitTestSuites
+	if err := xml.Unmarshal(b, &got); err != nil {
+		t.Fatalf("invalid xml: %v\n%s", err, b)
+	}
+	if len(got.Suites) != 1 {
+		t.Fatalf("expected a single suite, got %d", len(got.Suites))
+	}
+	suite := got.Suites[0]
+	cases := map[string]report.JUnitTestCase{}
+	for _, c := range suite.TestCases {
+		cases[c.Classname+"/"+c.Name] = c
+	}
+	properties := map[string]string{}
+	for _, p := range suite.Properties {
+		properties[p.Name] = p.Value
+	}
+	tests := []struct {
+		name        string
+		wantFailure bool
+		wantDetails string
+	}{
+		{"thresholds/p95 < 300ms", false, ""},
+		{"thresholds/error_rate < 1%", true, ""},
+		{"assertions/" + string(assertion.ErrorType()), true, "status 500"},
+		{"errors/Timeout", true, "id-Timeout"},
+		{"requests/2 successful requests", false, ""},
+	}
+	for _, tt := range tests {
+		c, ok := cases[tt.name]
+		if !ok {
+			t.Errorf("missing testcase %s, got %v", tt.name, cases)
+			continue
+		}
+		if (c.Failure != nil) != tt.wantFailure {
+			t.Errorf("%s: failure: got %v, want %v", tt.name, c.Failure, tt.wantFailure)
+		}
+		if tt.wantDetails != "" && !strings.Contains(c.Failure.Details, tt.wantDetails) {
+			t.Errorf("%s: expected the details to contain %q, got %q", tt.name, tt.wantDetails, c.Failure.Details)
+		}
+	}
+	if got.Tests != 6 || got.Failures != 4 {
+		t.Errorf("got %d tests and %d failures, want 6 and 4", got.Tests, got.Failures)
+	}
+	if properties["requests"] != "4" || properties["failed"] != "2" || properties["p50"] != "10ms" {
+		t.Errorf("unexpected properties: %v", properties)
+	}
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/runar-rkmedia/gabyoall/compare"
+	"github.com/runar-rkmedia/gabyoall/report"
+)
+
+// Implemented by content which can be written as a markdown-summary
+type markdownMarshaler interface {
+	MarshalMarkdown() ([]byte, error)
+}
+
+func marshalMarkdown(j interface{}) ([]byte, error) {
+	m, ok := j.(markdownMarshaler)
+	if !ok {
+		return nil, fmt.Errorf("cannot write %T as markdown", j)
+	}
+	return m.MarshalMarkdown()
+}
+
+// MarkdownSummary collects the verdict, key numbers and most common errors of the run,
+// compared against the baseline, if any
+func (o *Output) MarkdownSummary() report.MarkdownSummary {
+	o.CalculateStats()
+	s := report.MarkdownSummary{
+		Title:      o.Url,
+		Requests:   o.Overall.Count,
+		Failed:     o.Overall.Count - o.Count[""],
+		Elapsed:    o.Throughput.Elapsed(),
+		Overall:    *o.Overall,
+		Thresholds: o.Thresholds,
+		SLO:        o.SLO,
+	}
+	if o.Query.OperationName != "" {
+		s.Title += " " + o.Query.OperationName
+	}
+	for errorType, count := range o.Count {
+		if errorType != "" {
+			s.Errors = append(s.Errors, report.ErrorBucket{ErrorType: errorType, Count: count})
+		}
+	}
+	report.SortErrorBuckets(s.Errors)
+	if o.Baseline != nil {
+		c := compare.Compare(*o.Baseline, compare.NewRun("this run", o.Histograms, s.Elapsed))
+		s.Comparison = &c
+	}
+	return s
+}
+
+// MarshalMarkdown writes the output as a short markdown-summary, like for a comment on a pull-request
+func (o *Output) MarshalMarkdown() ([]byte, error) {
+	return o.MarkdownSummary().MarshalMarkdown()
+}
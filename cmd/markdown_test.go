@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/runar-rkmedia/gabyoall/compare"
+	"github.com/runar-rkmedia/gabyoall/logger"
+	"github.com/runar-rkmedia/gabyoall/requests"
+	"github.com/runar-rkmedia/gabyoall/thresholds"
+)
+
+func TestOutput_MarshalMarkdown(t *testing.T) {
+	newOutput := func(t *testing.T, errorTypes ...requests.ErrorType) *Output {
+		out, err := NewOutput(logger.GetLogger("test"), "", "https://example.com", requests.Request{OperationName: "getThings"}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		start := time.Now()
+		ts := requests.NewTimeSeriesWithLabel(start)
+		out.TimeSeries = &ts
+		out.Throughput = ts.Throughput
+		for i, errorType := range errorTypes {
+			d := time.Duration(i+1) * 10 * time.Millisecond
+			ts.Push(string(errorType), start.Add(d), float64(d))
+			out.AddStat(requests.RequestStat{ErrorType: errorType, Duration: d})
+		}
+		return &out
+	}
+	tests := []struct {
+		name  string
+		setup func(o *Output)
+		want  []string
+		not   []string
+	}{
+		{
+			name: "passed",
+			want: []string{"### ✅ Passed: https://example.com getThings", "| Requests | Failed |", "`ServerError` | 1"},
+			not:  []string{"Baseline"},
+		},
+		{
+			name: "failing threshold",
+			setup: func(o *Output) {
+				o.Thresholds = []thresholds.Result{{Threshold: "p95 < 10", ActualString: "30ms"}, {Threshold: "rate < 0.5", Ok: true}}
+			},
+			want: []string{"### ❌ Failed", "Failed thresholds: `p95 < 10` (actual 30ms)"},
+			not:  []string{"rate < 0.5"},
+		},
+		{
+			name: "many error-types",
+			setup: func(o *Output) {
+				for i := 0; i < 7; i++ {
+					o.AddStat(requests.RequestStat{ErrorType: requests.ErrorType(fmt.Sprintf("Err|%d", i)), Duration: time.Millisecond})
+				}
+			},
+			want: []string{`Err\|0`, "_3 other error-types_"},
+		},
+		{
+			name: "baseline",
+			setup: func(o *Output) {
+				h := requests.NewHistogram()
+				h.Record(5 * time.Millisecond)
+				baseline := compare.NewRun("baseline", map[requests.ErrorType]*requests.Histogram{"": h}, time.Second)
+				o.Baseline = &baseline
+			},
+			want: []string{"| Metric | Baseline | This run | Diff | Relative |"},
+			not:  []string{"| Requests | Failed |"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := newOutput(t, "", "", "ServerError")
+			if tt.setup != nil {
+				tt.setup(out)
+			}
+			b, err := out.MarshalMarkdown()
+			if err != nil {
+				t.Fatal(err)
+			}
+			md := string(b)
+			for _, w := range tt.want {
+				if !strings.Contains(md, w) {
+					t.Errorf("expected the summary to contain %q, got:\n%s", w, md)
+				}
+			}
+			for _, w := range tt.not {
+				if strings.Contains(md, w) {
+					t.Errorf("expected the summary not to contain %q, got:\n%s", w, md)
+				}
+			}
+		})
+	}
+}
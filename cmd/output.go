@@ -2,39 +2,125 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 	"path/filepath"
 	"sort"
+	"time"
 
 	tm "github.com/buger/goterm"
+	"github.com/runar-rkmedia/gabyoall/compare"
 	"github.com/runar-rkmedia/gabyoall/logger"
 	"github.com/runar-rkmedia/gabyoall/requests"
 	queries "github.com/runar-rkmedia/gabyoall/requests"
+	"github.com/runar-rkmedia/gabyoall/thresholds"
 )
 
 type Output struct {
-	l               logger.AppLogger
-	Url             string                                        `json:"url,omitempty"`
-	Query           queries.Request                               `json:"query,omitempty"`
-	Details         map[requests.ErrorType][]requests.CompactStat `json:"details,omitempty"`
-	JwtPayload      map[string]interface{}                        `json:"jwt_payload,omitempty"`
-	Count           map[requests.ErrorType]int                    `json:"count,omitempty"`
-	Stats           map[requests.ErrorType]requests.Stats         `json:"stats,omitempty"`
-	AllRequests     map[requests.ErrorType][]queries.RequestStat  `json:"-"`
-	ResponseHashMap requests.ByteHashMap                          `json:"responseHashMap,omitempty"`
-	path            string
+	l          logger.AppLogger
+	Url        string                                        `json:"url,omitempty"`
+	Query      queries.Request                               `json:"query,omitempty"`
+	Details    map[requests.ErrorType][]requests.CompactStat `json:"details,omitempty"`
+	JwtPayload map[string]interface{}                        `json:"jwt_payload,omitempty"`
+	Count      map[requests.ErrorType]int                    `json:"count,omitempty"`
+	Stats      map[requests.ErrorType]*requests.Stats        `json:"stats,omitempty"`
+	// Stats of all requests combined
+	Overall     *requests.Stats                              `json:"overall,omitempty"`
+	AllRequests map[requests.ErrorType][]queries.RequestStat `json:"-"`
+	// Results of the thresholds, if any
+	Thresholds      []thresholds.Result  `json:"thresholds,omitempty"`
+	ResponseHashMap requests.ByteHashMap `json:"responseHashMap,omitempty"`
+	// Violations of the response-schema, by the same hash as in the ResponseHashMap
+	SchemaViolations requests.SchemaViolationMap `json:"schemaViolations,omitempty"`
+	// Every violation of the ResponseSchema, by pointer
+	SchemaViolationBreakdown requests.SchemaViolationBreakdown `json:"schemaViolationBreakdown"`
+	// Number of responses which did not match the golden response
+	GoldenMismatches int `json:"goldenMismatches,omitempty"`
+	// Differences from the golden response, by the same hash as in the ResponseHashMap
+	GoldenDiffs requests.GoldenDiffMap `json:"goldenDiffs,omitempty"`
+	// GraphQL-errors by path and code, including those of responses with accepted partial data
+	GqlErrors requests.GqlErrorBreakdown `json:"gqlErrors"`
+	// Latencies of all requests, which can be merged with the histograms of other runs
+	Histogram *requests.Histogram `json:"histogram"`
+	// Latencies by ErrorType
+	Histograms map[requests.ErrorType]*requests.Histogram `json:"histograms"`
+	// Completed requests, bytes received and requests in flight, per second. Set from the TimeSeriesMap of the run.
+	Throughput *requests.ThroughputSeries `json:"throughput,omitempty"`
+	// The slowest requests, and samples of the failed requests, with full details
+	Samples *requests.Samples `json:"samples,omitempty"`
+	// The score of the run against the SLO, if any
+	SLO *requests.SLOResult `json:"slo,omitempty"`
+	// Latencies over time, used in the html-report
+	TimeSeries *requests.TimeSeriesMap `json:"-"`
+	// The effective config, with secrets redacted, used in the html-report
+	Config *Config `json:"-"`
+	// Run which the markdown-summary is compared against, if any
+	Baseline *compare.Run `json:"-"`
+	// If set, a line is streamed to it for each request, and the details of each request are not kept in memory
+	RequestLog *requests.RequestLog `json:"-"`
+	path       string
+	// Number of responses by ErrorType and hash
+	responseCounts map[requests.ErrorType]map[requests.Hash]int
 }
 
 type Marshal func(j interface{}) ([]byte, error)
 
 func (o *Output) AddStat(stat requests.RequestStat) *Output {
-	o.Count[stat.ErrorType]++
-	hash := o.ResponseHashMap.Add(stat.ContentType, stat.RawResponse)
+	o.addDuration(stat.ErrorType, stat.Duration)
+	hash := o.ResponseHashMap.Add(stat.ContentType, stat.RawResponse, stat.Normalization)
 	if hash != nil {
 		stat.CompactStat.ResponseHash = hash
+		o.SchemaViolations.Add(*hash, stat.SchemaViolations)
+		o.GoldenDiffs.Add(*hash, stat.GoldenDiff)
 	}
-	o.Details[stat.ErrorType] = append(o.Details[stat.ErrorType], stat.CompactStat)
+	o.countResponse(stat.ErrorType, stat.CompactStat.ResponseHash)
+	if stat.ErrorType == requests.GoldenMismatch {
+		o.GoldenMismatches++
+	}
+	o.SchemaViolationBreakdown.Add(stat.SchemaViolations)
+	o.GqlErrors.Add(stat.GqlErrors, stat.PartialData)
+	if o.RequestLog != nil {
+		if err := o.RequestLog.Write(stat); err != nil {
+			o.l.Error().Err(err).Msg("Failed to write to the request-log")
+		}
+	} else {
+		o.Details[stat.ErrorType] = append(o.Details[stat.ErrorType], stat.CompactStat)
+	}
+	o.Samples.Add(stat)
 	return o
 }
+
+// Adds the duration of a request to the counts, stats and histograms
+func (o *Output) addDuration(errorType requests.ErrorType, d time.Duration) {
+	o.Count[errorType]++
+	o.Overall.Add(d)
+	s, ok := o.Stats[errorType]
+	if !ok {
+		s = &requests.Stats{}
+		o.Stats[errorType] = s
+	}
+	s.Add(d)
+	o.Histogram.Record(d)
+	h, ok := o.Histograms[errorType]
+	if !ok {
+		h = requests.NewHistogram()
+		o.Histograms[errorType] = h
+	}
+	h.Record(d)
+}
+
+// Counts the response by its hash, which is kept also when the details are not
+func (o *Output) countResponse(errorType requests.ErrorType, hash *requests.Hash) {
+	if hash == nil {
+		return
+	}
+	counts, ok := o.responseCounts[errorType]
+	if !ok {
+		counts = map[requests.Hash]int{}
+		o.responseCounts[errorType] = counts
+	}
+	counts[*hash]++
+}
+
 func (o *Output) Write() error {
 	if o.path == "" {
 		return nil
@@ -47,20 +133,60 @@ func (o *Output) Write() error {
 	return nil
 }
 
+// CalculateStats fills in the percentiles from the histograms.
+// The rest of the stats are kept up to date in AddStat.
 func (o *Output) CalculateStats() {
-	// TODO: ???
-	// for errorType, r := range o.Details {
-	// 	// o.Stats[errorType] = r
-	// }
+	for errorType, s := range o.Stats {
+		if h, ok := o.Histograms[errorType]; ok {
+			s.SetPercentiles(h.Percentiles())
+		}
+	}
+	o.Overall.SetPercentiles(o.Histogram.Percentiles())
 }
+
+// Percentiles returns the percentiles of all requests so far
+func (o *Output) Percentiles() requests.Percentiles {
+	return o.Histogram.Percentiles()
+}
+
+// ThresholdStats returns the stats which thresholds are evaluated against
+func (o *Output) ThresholdStats(elapsed time.Duration) thresholds.Stats {
+	return thresholds.Stats{
+		Count:      o.Count,
+		Stats:      o.Stats,
+		Histograms: o.Histograms,
+		Overall:    o.Overall,
+		Histogram:  o.Histogram,
+		Elapsed:    elapsed,
+	}
+}
+
+func (out *Output) PrintThresholds() {
+	table := tm.NewTable(0, 10, 5, ' ', 0)
+	fmt.Fprintf(table, "\nResult\tThreshold\tActual\n")
+	for _, r := range out.Thresholds {
+		result := tm.Color("PASS", tm.GREEN)
+		if !r.Ok {
+			result = tm.Color("FAIL", tm.RED)
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s\n", result, r.Threshold, r.ActualString)
+	}
+	// Printed directly, since the screen-buffer of goterm is not flushed when stdout is not a terminal, like in CI.
+	fmt.Println(table.String())
+}
+
 func (o *Output) GetPath() string {
 	return o.path
 }
 
 func (out *Output) PrintTable() {
+	tm.Println(out.Table())
+}
 
+// Table returns the summary-table, with a row for each ErrorType, most common first
+func (out *Output) Table() string {
 	totals := tm.NewTable(0, 10, 5, ' ', 0)
-	fmt.Fprintf(totals, "\nCount\tErrorType\tMin\tAverage\tMax\tTotal\n")
+	fmt.Fprintf(totals, "\nCount\tErrorType\tMin\tAverage\tp50\tp75\tp90\tp95\tp99\tp99.9\tMax\tTotal\n")
 	countSort := []struct {
 		Count     int
 		ErrorType requests.ErrorType
@@ -77,10 +203,15 @@ func (out *Output) PrintTable() {
 	})
 	out.CalculateStats()
 	for _, c := range countSort {
-		s := out.Stats[c.ErrorType]
-		fmt.Fprintf(totals, "%d\t%s\t%s\t%s\t%s\t%s\n", c.Count, c.ErrorType, s.Min.String(), s.Average.String(), s.Max.String(), s.Total.String())
+		printStatsRow(totals, string(c.ErrorType), out.Stats[c.ErrorType])
 	}
-	tm.Println(totals)
+	printStatsRow(totals, "(all)", out.Overall)
+	return totals.String()
+}
+
+func printStatsRow(w io.Writer, label string, s *requests.Stats) {
+	p := s.Percentiles
+	fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", s.Count, label, s.Min.String(), s.Average.String(), p.P50.String(), p.P75.String(), p.P90.String(), p.P95.String(), p.P99.String(), p.P999.String(), s.Max.String(), s.Total.String())
 }
 
 func NewOutput(l logger.AppLogger, path, url string, query queries.Request, JwtPayload map[string]interface{}) (Output, error) {
@@ -94,14 +225,21 @@ func NewOutput(l logger.AppLogger, path, url string, query queries.Request, JwtP
 		abs = _abs
 	}
 	return Output{
-		l:               l,
-		path:            abs,
-		Url:             url,
-		Query:           query,
-		JwtPayload:      JwtPayload,
-		Details:         map[requests.ErrorType][]requests.CompactStat{},
-		Count:           map[requests.ErrorType]int{},
-		Stats:           map[requests.ErrorType]requests.Stats{},
-		ResponseHashMap: queries.ByteHashMap{},
+		l:                l,
+		path:             abs,
+		Url:              url,
+		Query:            query,
+		JwtPayload:       JwtPayload,
+		Details:          map[requests.ErrorType][]requests.CompactStat{},
+		Count:            map[requests.ErrorType]int{},
+		Stats:            map[requests.ErrorType]*requests.Stats{},
+		Overall:          &requests.Stats{},
+		ResponseHashMap:  queries.ByteHashMap{},
+		SchemaViolations: queries.SchemaViolationMap{},
+		GoldenDiffs:      queries.GoldenDiffMap{},
+		Histogram:        queries.NewHistogram(),
+		Histograms:       map[requests.ErrorType]*requests.Histogram{},
+		Samples:          requests.NewSamples(requests.DefaultSampleSize),
+		responseCounts:   map[requests.ErrorType]map[requests.Hash]int{},
 	}, nil
 }
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/runar-rkmedia/gabyoall/logger"
+	"github.com/runar-rkmedia/gabyoall/requests"
+)
+
+func TestOutput_CalculateStats(t *testing.T) {
+	out, err := NewOutput(logger.GetLogger("test"), "", "", requests.Request{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []struct {
+		errorType requests.ErrorType
+		ms        int
+	}{{"", 10}, {"", 30}, {"", 20}, {"Timeout", 1000}} {
+		out.AddStat(requests.RequestStat{ErrorType: s.errorType, Duration: time.Duration(s.ms) * time.Millisecond})
+	}
+	out.CalculateStats()
+	tests := []struct {
+		name                      string
+		got                       *requests.Stats
+		count                     int
+		min, avg, max, total, p50 time.Duration
+	}{
+		{"successes", out.Stats[""], 3, 10 * time.Millisecond, 20 * time.Millisecond, 30 * time.Millisecond, 60 * time.Millisecond, 20 * time.Millisecond},
+		{"timeouts", out.Stats["Timeout"], 1, time.Second, time.Second, time.Second, time.Second, time.Second},
+		{"overall", out.Overall, 4, 10 * time.Millisecond, 265 * time.Millisecond, time.Second, 1060 * time.Millisecond, 20 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := tt.got
+			if s.Count != tt.count || s.Min != tt.min || s.Average != tt.avg || s.Max != tt.max || s.Total != tt.total {
+				t.Errorf("got %+v", s)
+			}
+			// The histogram has about 1% precision
+			if d := s.Percentiles.P50 - tt.p50; d < -tt.p50/100 || d > tt.p50/100 {
+				t.Errorf("p50 = %v, want %v", s.Percentiles.P50, tt.p50)
+			}
+			if s.Percentiles.P999 > s.Max {
+				t.Errorf("p99.9 %v is larger than max %v", s.Percentiles.P999, s.Max)
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/runar-rkmedia/gabyoall/logger"
+	"github.com/runar-rkmedia/gabyoall/requests"
+	"github.com/runar-rkmedia/gabyoall/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	replayErrorTypes []string
+	replayRequestIDs []string
+	replayFreshToken bool
+)
+
+// AuthHeader retrieves a fresh token for the auth-config, as the header to set on each request.
+// It is set by main, since the auth-package depends on this package.
+var AuthHeader func(l logger.AppLogger, config Config) (key, value string, err error)
+
+var replayCmd = &cobra.Command{
+	Use:          "replay <output-file | stat-url>",
+	Short:        "Resends the failed requests of a previous run, to check whether they still fail",
+	SilenceUsage: true,
+	Long: `Reads the samples of the failed requests of a previous run, and sends the exact same requests again,
+with the same method, url, headers and rendered body. This is useful to confirm a fix, without running the whole stress-test again.
+
+The run is read from an output-file, or from a stored run of the api, like http://localhost/api/stat/<id>.
+Only the requests which were kept as samples can be replayed, see --samples.
+Samples with a request-body larger than 64KiB were truncated, and are not replayed.
+
+Credentials are redacted from the samples, so use --fresh-token to authenticate the requests with the auth-config.`,
+	Example: `  gobyoall replay output.json
+  gobyoall replay output.json --error-type 'Not found' --fresh-token
+  gobyoall replay http://localhost/api/stat/abc --request-id xyz`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		l := logger.GetLogger("replay")
+		config := GetConfig(l)
+		samples, err := readSamples(args[0])
+		if err != nil {
+			return err
+		}
+		selected := selectSamples(samples, replayErrorTypes, replayRequestIDs)
+		if len(selected) == 0 {
+			return fmt.Errorf("found no samples to replay in %s", args[0])
+		}
+		query := requests.Request{Assertions: config.Assertions, ResponseSchema: config.ResponseSchema}
+		if config.Golden != "" {
+			if query.Golden, err = ReadGoldenResponse(config.Golden); err != nil {
+				return err
+			}
+		}
+		var authKey, authValue string
+		if replayFreshToken {
+			if AuthHeader == nil {
+				return fmt.Errorf("fresh tokens are not supported")
+			}
+			if authKey, authValue, err = AuthHeader(l, *config); err != nil {
+				return fmt.Errorf("failed to retrieve a fresh token: %w", err)
+			}
+		}
+		ts := requests.NewTimeSeriesWithLabel(time.Now())
+		results := make([]replayResult, 0, len(selected))
+		for _, sample := range selected {
+			result := replayResult{Sample: sample}
+			if !replayFreshToken {
+				if redacted := sample.Request.RedactedHeaders(); len(redacted) > 0 {
+					l.Warn().Str("requestId", sample.RequestID).Strs("headers", redacted).Msg("The headers were redacted, and are not sent. Use --fresh-token to authenticate")
+				}
+			}
+			if sample.Request.HasRedactedBody() {
+				l.Warn().Str("requestId", sample.RequestID).Msg("Sensitive values in the body were redacted, so the replayed body differs from the original")
+			}
+			r, err := sample.Request.NewRequest()
+			if err != nil {
+				result.Stat.ErrorType = requests.ServerTestError
+				result.Stat.Error = err.Error()
+				results = append(results, result)
+				continue
+			}
+			endpoint := requests.NewEndpoint(logger.GetLogger("gql"), sample.Request.Url, &ts)
+			if authKey != "" {
+				endpoint.Headers.Set(authKey, authValue)
+			}
+			endpoint.ClassificationRules = config.ClassificationRules
+			endpoint.Normalization = config.Normalization
+			endpoint.PartialDataOk = config.PartialDataOk
+			stat := requests.NewStat(0, &ts)
+			_, result.Stat, _ = endpoint.DoRequest(l, r, stat, config.OkStatusCodes, query, nil)
+			results = append(results, result)
+		}
+		fmt.Print(replayTable(results))
+		failing := 0
+		for _, r := range results {
+			if r.Stat.ErrorType != "" {
+				failing++
+			}
+		}
+		if failing > 0 {
+			return fmt.Errorf("%d of %d replayed requests still fail", failing, len(results))
+		}
+		fmt.Printf("All %d replayed requests succeeded\n", len(results))
+		return nil
+	},
+}
+
+type replayResult struct {
+	Sample requests.Sample
+	Stat   requests.RequestStat
+}
+
+// Reads the samples from an output-file, or from a stored run of the api if the source is an url
+func readSamples(source string) (*requests.Samples, error) {
+	var out struct {
+		Samples *requests.Samples `json:"samples"`
+	}
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		res, err := http.Get(source)
+		if err != nil {
+			return nil, err
+		}
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("failed to get the run from %s: status %d", source, res.StatusCode)
+		}
+		if err := json.NewDecoder(res.Body).Decode(&out); err != nil {
+			return nil, fmt.Errorf("failed to decode the run from %s: %w", source, err)
+		}
+	} else if err := ReadYamlFile(source, &out); err != nil {
+		return nil, err
+	}
+	if out.Samples == nil {
+		return nil, fmt.Errorf("the run %s has no samples, it may be from an older version, or sampling was disabled", source)
+	}
+	return out.Samples, nil
+}
+
+// Selects the failed samples of the error-types, and the samples of the request-ids.
+// If neither are set, all the failed samples are selected.
+func selectSamples(samples *requests.Samples, errorTypes, requestIDs []string) []requests.Sample {
+	wantErrorType := map[string]bool{}
+	for _, e := range errorTypes {
+		wantErrorType[e] = true
+	}
+	wantRequestID := map[string]bool{}
+	for _, id := range requestIDs {
+		wantRequestID[id] = true
+	}
+	all := len(errorTypes) == 0 && len(requestIDs) == 0
+	var selected []requests.Sample
+	for errorType, list := range samples.Failures {
+		if all || wantErrorType[string(errorType)] {
+			selected = append(selected, list...)
+			continue
+		}
+		for _, s := range list {
+			if wantRequestID[s.RequestID] {
+				selected = append(selected, s)
+			}
+		}
+	}
+	for _, s := range samples.Slowest {
+		if wantRequestID[s.RequestID] {
+			selected = append(selected, s)
+		}
+	}
+	sort.SliceStable(selected, func(i, j int) bool {
+		if selected[i].ErrorType != selected[j].ErrorType {
+			return selected[i].ErrorType < selected[j].ErrorType
+		}
+		return selected[i].Start.Before(selected[j].Start)
+	})
+	return selected
+}
+
+func replayTable(results []replayResult) string {
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 8, 3, ' ', 0)
+	fmt.Fprintf(w, "Request-id\tBefore\tNow\tStatus\tDuration\n")
+	for _, r := range results {
+		before := string(r.Sample.ErrorType)
+		if before == "" {
+			before = "ok"
+		}
+		now := string(r.Stat.ErrorType)
+		if now == "" {
+			now = "ok"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n", r.Sample.RequestID, utils.Truncate(before, 60), utils.Truncate(now, 60), r.Stat.StatusCode, utils.PrettyDuration(r.Stat.Duration))
+	}
+	w.Flush()
+	return sb.String()
+}
+
+func init() {
+	replayCmd.Flags().StringArrayVar(&replayErrorTypes, "error-type", nil, "Replay the failed requests of this error-type. Can be repeated")
+	replayCmd.Flags().StringArrayVar(&replayRequestIDs, "request-id", nil, "Replay the request with this request-id. Can be repeated")
+	replayCmd.Flags().BoolVar(&replayFreshToken, "fresh-token", false, "Authenticate the requests with a fresh token from the auth-config, or --auth-token, since credentials are redacted from the samples")
+	rootCmd.AddCommand(replayCmd)
+}
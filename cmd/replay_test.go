@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/runar-rkmedia/gabyoall/requests"
+)
+
+func Test_selectSamples(t *testing.T) {
+	start := time.Now()
+	sample := func(id string, errorType requests.ErrorType, offset int) requests.Sample {
+		return requests.Sample{RequestID: id, ErrorType: errorType, Start: start.Add(time.Duration(offset) * time.Second)}
+	}
+	samples := &requests.Samples{
+		Slowest: []requests.Sample{sample("slow", "", 0)},
+		Failures: map[requests.ErrorType][]requests.Sample{
+			"Timeout":  {sample("t2", "Timeout", 2), sample("t1", "Timeout", 1)},
+			"NotFound": {sample("n1", "NotFound", 3)},
+		},
+	}
+	tests := []struct {
+		name       string
+		errorTypes []string
+		requestIDs []string
+		want       []string
+	}{
+		{"all failures", nil, nil, []string{"n1", "t1", "t2"}},
+		{"by error-type", []string{"Timeout"}, nil, []string{"t1", "t2"}},
+		{"by request-id", nil, []string{"t2", "slow"}, []string{"slow", "t2"}},
+		{"both", []string{"NotFound"}, []string{"t1"}, []string{"n1", "t1"}},
+		{"unknown", []string{"Nope"}, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, s := range selectSamples(samples, tt.errorTypes, tt.requestIDs) {
+				got = append(got, s.RequestID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("selectSamples() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
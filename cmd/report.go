@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/runar-rkmedia/gabyoall/logger"
+	"github.com/runar-rkmedia/gabyoall/requests"
+	"github.com/runar-rkmedia/gabyoall/thresholds"
+	"github.com/spf13/cobra"
+)
+
+var (
+	reportOutput     string
+	reportThresholds []string
+)
+
+var reportCmd = &cobra.Command{
+	Use:          "report <request-log>",
+	Short:        "Rebuilds the summary of a run from its request-log",
+	SilenceUsage: true,
+	Long: `Reads a request-log written with --request-log, as jsonl or csv, and prints the summary-table.
+This also works for runs which crashed or were stopped before they wrote their output.
+
+With --output, the rebuilt output is written like after a run, for instance as html.
+Response-bodies are not in the log, so the output only has the hashes of the responses.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		thresholdList, err := thresholds.ParseAll(reportThresholds)
+		if err != nil {
+			return err
+		}
+		out, err := NewOutput(logger.GetLogger("report"), reportOutput, "", requests.Request{}, nil)
+		if err != nil {
+			return err
+		}
+		if err := rebuildOutput(&out, args[0]); err != nil {
+			return err
+		}
+		// Printed directly, since the screen-buffer of goterm is not flushed when stdout is not a terminal.
+		fmt.Println(out.Table())
+		thresholdsOk := true
+		if len(thresholdList) > 0 {
+			out.Thresholds, thresholdsOk = thresholds.EvaluateAll(thresholdList, out.ThresholdStats(out.Throughput.Elapsed()))
+			out.PrintThresholds()
+		}
+		if err := out.Write(); err != nil {
+			return err
+		}
+		if !thresholdsOk {
+			return fmt.Errorf("one or more thresholds failed")
+		}
+		return nil
+	},
+}
+
+// Adds each request in the log to the output, with series for the charts.
+// The log is read twice, since the start of the run is needed before the series are built.
+func rebuildOutput(out *Output, path string) error {
+	var start time.Time
+	count := 0
+	err := readRequestLog(path, func(e requests.LogEntry) error {
+		if start.IsZero() || e.Time.Before(start) {
+			start = e.Time
+		}
+		count++
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf("the request-log %s has no requests", path)
+	}
+	ts := requests.NewTimeSeriesWithLabel(start)
+	err = readRequestLog(path, func(e requests.LogEntry) error {
+		end := e.Time.Add(e.Duration)
+		// The requests in flight are approximated, since the log is in the order the requests completed
+		ts.RequestStarted(e.Time)
+		ts.Push(string(e.ErrorType), end, float64(e.Duration))
+		ts.RequestDone(string(e.ErrorType), end, 0)
+		out.AddStat(e.Stat())
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	out.TimeSeries = &ts
+	out.Throughput = ts.Throughput
+	return nil
+}
+
+func readRequestLog(path string, fn func(e requests.LogEntry) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := requests.ReadRequestLog(f, requests.IsCSVLog(path), fn); err != nil {
+		return fmt.Errorf("failed to read the request-log %s: %w", path, err)
+	}
+	return nil
+}
+
+func init() {
+	reportCmd.Flags().StringVarP(&reportOutput, "output", "o", "", "File to write the rebuilt output to, like after a run")
+	reportCmd.Flags().StringArrayVar(&reportThresholds, "threshold", nil, "Pass/fail-criteria to evaluate against the log, like 'p95 < 300ms'. Exits non-zero if any fail")
+	rootCmd.AddCommand(reportCmd)
+}
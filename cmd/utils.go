@@ -15,6 +15,12 @@ func WriteAuto(outpath string, content interface{}) error {
 	switch ext {
 	case ".yaml", ".yml":
 		marshal = yaml.Marshal
+	case ".xml":
+		marshal = marshalJUnit
+	case ".html":
+		marshal = marshalHTML
+	case ".md":
+		marshal = marshalMarkdown
 	default:
 		marshal = func(j interface{}) ([]byte, error) {
 			return json.MarshalIndent(j, "", "  ")
@@ -26,11 +32,44 @@ func WriteAuto(outpath string, content interface{}) error {
 func Write(marshal Marshal, outpath string, content interface{}) error {
 	b, err := marshal(content)
 	if err != nil {
-		return fmt.Errorf("failed to marshal yaml for output: %w", err)
+		return fmt.Errorf("failed to marshal output: %w", err)
 	}
-	err = os.WriteFile(outpath, b, 0666)
+	err = writeFileAtomic(outpath, b)
 	if err != nil {
 		return fmt.Errorf("failed to write file-contents to path %s: %w", outpath, err)
 	}
 	return nil
 }
+
+// Writes to a temporary file in the same directory, which is then renamed,
+// so that a crash while writing never leaves a partially written file.
+func writeFileAtomic(path string, b []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Chmod(tmp, 0644); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,237 @@
+// Package compare compares two runs, like before and after a deploy.
+// It reports the deltas of throughput, error-rate and latency-percentiles,
+// and whether the latencies differ significantly, using the Mann-Whitney U test.
+package compare
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/runar-rkmedia/gabyoall/requests"
+)
+
+// Runs are significantly different if the p-value is below this
+const SignificanceLevel = 0.05
+
+// Run is what is compared of each run
+type Run struct {
+	// Like the id of a stored run, or the path of an output-file
+	ID       string
+	Requests int64
+	Failed   int64
+	// Time from the start of the run until the last request completed
+	Elapsed time.Duration
+	// Latencies of the successful requests
+	Latencies *requests.Histogram
+}
+
+// NewRun creates a Run from the histograms by ErrorType, as in both the output-file and the stored stats
+func NewRun(id string, histograms map[requests.ErrorType]*requests.Histogram, elapsed time.Duration) Run {
+	r := Run{ID: id, Elapsed: elapsed, Latencies: histograms[""]}
+	for errorType, h := range histograms {
+		r.Requests += h.Count()
+		if errorType != "" {
+			r.Failed += h.Count()
+		}
+	}
+	if r.Latencies == nil {
+		r.Latencies = requests.NewHistogram()
+	}
+	return r
+}
+
+type Delta struct {
+	Metric string  `json:"metric"`
+	A      float64 `json:"a"`
+	B      float64 `json:"b"`
+	// B - A
+	Diff float64 `json:"diff"`
+	// The difference relative to A, in percent. Not set if A is zero.
+	Relative *float64 `json:"relative,omitempty"`
+	// ms, %, or rps
+	Unit string `json:"unit"`
+}
+
+// MannWhitney is the result of the Mann-Whitney U test over the latencies of the successful requests.
+type MannWhitney struct {
+	U float64 `json:"u"`
+	Z float64 `json:"z"`
+	// Two-sided p-value, from the normal approximation
+	P           float64 `json:"p"`
+	Significant bool    `json:"significant"`
+	// Probability that a request in B is slower than a request in A. 0.5 means no difference.
+	ProbabilityBSlower float64 `json:"probabilityBSlower"`
+}
+
+type Result struct {
+	A           string       `json:"a"`
+	B           string       `json:"b"`
+	Deltas      []Delta      `json:"deltas"`
+	MannWhitney *MannWhitney `json:"mannWhitney,omitempty"`
+}
+
+func (r Run) rps() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Requests) / r.Elapsed.Seconds()
+}
+
+func (r Run) errorRate() float64 {
+	if r.Requests == 0 {
+		return 0
+	}
+	return float64(r.Failed) / float64(r.Requests) * 100
+}
+
+func delta(metric, unit string, a, b float64) Delta {
+	d := Delta{Metric: metric, Unit: unit, A: a, B: b, Diff: b - a}
+	if a != 0 {
+		rel := (b - a) / a * 100
+		d.Relative = &rel
+	}
+	return d
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// Compare compares run b to run a
+func Compare(a, b Run) Result {
+	pa, pb := a.Latencies.Percentiles(), b.Latencies.Percentiles()
+	r := Result{
+		A: a.ID,
+		B: b.ID,
+		Deltas: []Delta{
+			delta("requests", "", float64(a.Requests), float64(b.Requests)),
+			delta("rps", "rps", a.rps(), b.rps()),
+			delta("error_rate", "%", a.errorRate(), b.errorRate()),
+			delta("p50", "ms", ms(pa.P50), ms(pb.P50)),
+			delta("p90", "ms", ms(pa.P90), ms(pb.P90)),
+			delta("p95", "ms", ms(pa.P95), ms(pb.P95)),
+			delta("p99", "ms", ms(pa.P99), ms(pb.P99)),
+			delta("p99.9", "ms", ms(pa.P999), ms(pb.P999)),
+		},
+	}
+	if mw, ok := MannWhitneyU(a.Latencies, b.Latencies); ok {
+		r.MannWhitney = &mw
+	}
+	return r
+}
+
+// MannWhitneyU tests whether the latencies of b tend to be larger or smaller than those of a.
+// Values within the same bucket of the histograms are treated as ties.
+// Ok is false if either histogram is empty.
+func MannWhitneyU(a, b *requests.Histogram) (result MannWhitney, ok bool) {
+	n1, n2 := float64(a.Count()), float64(b.Count())
+	if n1 == 0 || n2 == 0 {
+		return result, false
+	}
+	ba, bb := a.Buckets(), b.Buckets()
+	// Walk the buckets of both in ascending order, ranking ties by their average rank
+	var rankSumA, tieSum, rank float64
+	i, j := 0, 0
+	for i < len(ba) || j < len(bb) {
+		var ca, cb int64
+		switch {
+		case j >= len(bb) || (i < len(ba) && ba[i].Value < bb[j].Value):
+			ca = ba[i].Count
+			i++
+		case i >= len(ba) || bb[j].Value < ba[i].Value:
+			cb = bb[j].Count
+			j++
+		default:
+			ca, cb = ba[i].Count, bb[j].Count
+			i++
+			j++
+		}
+		t := float64(ca + cb)
+		rankSumA += float64(ca) * (rank + (t+1)/2)
+		rank += t
+		tieSum += t*t*t - t
+	}
+	u := rankSumA - n1*(n1+1)/2
+	n := n1 + n2
+	mu := n1 * n2 / 2
+	sigma := math.Sqrt(n1 * n2 / 12 * ((n + 1) - tieSum/(n*(n-1))))
+	result.U = u
+	// U counts the pairs where a is slower than b
+	result.ProbabilityBSlower = 1 - u/(n1*n2)
+	if sigma == 0 {
+		// All values are tied
+		result.P = 1
+		return result, true
+	}
+	// Continuity-correction
+	diff := u - mu
+	switch {
+	case diff > 0.5:
+		diff -= 0.5
+	case diff < -0.5:
+		diff += 0.5
+	default:
+		diff = 0
+	}
+	result.Z = diff / sigma
+	result.P = math.Erfc(math.Abs(result.Z) / math.Sqrt2)
+	result.Significant = result.P < SignificanceLevel
+	return result, true
+}
+
+func formatValue(v float64, unit string) string {
+	switch unit {
+	case "ms", "rps":
+		return strconv.FormatFloat(v, 'f', 1, 64) + unit
+	case "%":
+		return strconv.FormatFloat(v, 'f', 2, 64) + unit
+	}
+	return strconv.FormatFloat(v, 'f', 0, 64)
+}
+
+// Formatted returns the values of the delta with their unit, and the diff and relative diff with their sign
+func (d Delta) Formatted() (a, b, diff, relative string) {
+	relative = "-"
+	if d.Relative != nil {
+		relative = fmt.Sprintf("%+.1f%%", *d.Relative)
+	}
+	diff = formatValue(d.Diff, d.Unit)
+	if d.Diff > 0 {
+		diff = "+" + diff
+	}
+	return formatValue(d.A, d.Unit), formatValue(d.B, d.Unit), diff, relative
+}
+
+// Verdict describes whether B is significantly slower or faster than A
+func (mw MannWhitney) Verdict() string {
+	if !mw.Significant {
+		return "not significant"
+	}
+	if mw.ProbabilityBSlower > 0.5 {
+		return "significant, B is slower"
+	}
+	return "significant, B is faster"
+}
+
+// Table renders the result for the terminal
+func (r Result) Table() string {
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 8, 3, ' ', 0)
+	fmt.Fprintf(w, "Metric\tA\tB\tDiff\tRelative\n")
+	for _, d := range r.Deltas {
+		a, b, diff, rel := d.Formatted()
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", d.Metric, a, b, diff, rel)
+	}
+	w.Flush()
+	fmt.Fprintf(&sb, "\nA: %s\nB: %s\n", r.A, r.B)
+	if mw := r.MannWhitney; mw != nil {
+		fmt.Fprintf(&sb, "Mann-Whitney U over successful latencies: p=%.4f (%s). P(B slower than A)=%.2f\n", mw.P, mw.Verdict(), mw.ProbabilityBSlower)
+	} else {
+		fmt.Fprintf(&sb, "Mann-Whitney U: not enough successful requests to compare\n")
+	}
+	return sb.String()
+}
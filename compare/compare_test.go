@@ -0,0 +1,105 @@
+package compare
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/runar-rkmedia/gabyoall/requests"
+)
+
+func histogram(from, to, step int) *requests.Histogram {
+	h := requests.NewHistogram()
+	for v := from; v <= to; v += step {
+		h.Record(time.Duration(v) * time.Millisecond)
+	}
+	return h
+}
+
+func TestMannWhitneyU(t *testing.T) {
+	tests := []struct {
+		name            string
+		a, b            *requests.Histogram
+		wantOk          bool
+		wantU           float64
+		wantP           float64
+		wantSignificant bool
+		wantBSlower     float64
+	}{
+		{"empty", histogram(1, 0, 1), histogram(1, 3, 1), false, 0, 0, false, 0},
+		{"few, all slower", histogram(1, 3, 1), histogram(4, 6, 1), true, 0, 0.0809, false, 1},
+		{"many, all slower", histogram(1, 20, 1), histogram(21, 40, 1), true, 0, 0, true, 1},
+		{"many, all faster", histogram(21, 40, 1), histogram(1, 20, 1), true, 400, 0, true, 0},
+		{"same", histogram(1, 20, 1), histogram(1, 20, 1), true, 200, 1, false, 0.5},
+		{"all tied", histogram(5, 5, 1), histogram(5, 5, 1), true, 0.5, 1, false, 0.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := MannWhitneyU(tt.a, tt.b)
+			if ok != tt.wantOk {
+				t.Fatalf("ok: got %v, want %v", ok, tt.wantOk)
+			}
+			if !ok {
+				return
+			}
+			if got.U != tt.wantU {
+				t.Errorf("U: got %v, want %v", got.U, tt.wantU)
+			}
+			if math.Abs(got.P-tt.wantP) > 0.001 {
+				t.Errorf("P: got %v, want %v", got.P, tt.wantP)
+			}
+			if got.Significant != tt.wantSignificant {
+				t.Errorf("Significant: got %v, want %v", got.Significant, tt.wantSignificant)
+			}
+			if got.ProbabilityBSlower != tt.wantBSlower {
+				t.Errorf("ProbabilityBSlower: got %v, want %v", got.ProbabilityBSlower, tt.wantBSlower)
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	a := NewRun("before", map[requests.ErrorType]*requests.Histogram{
+		"":        histogram(10, 100, 10),
+		"Timeout": histogram(1000, 1000, 1),
+	}, 2*time.Second)
+	b := NewRun("after", map[requests.ErrorType]*requests.Histogram{
+		"": histogram(20, 200, 20),
+	}, time.Second)
+	r := Compare(a, b)
+	deltas := map[string]Delta{}
+	for _, d := range r.Deltas {
+		deltas[d.Metric] = d
+	}
+	tests := []struct {
+		metric       string
+		wantA, wantB float64
+	}{
+		{"requests", 11, 10},
+		{"rps", 5.5, 10},
+		{"error_rate", 100.0 / 11, 0},
+		{"p50", 50, 100},
+	}
+	for _, tt := range tests {
+		d, ok := deltas[tt.metric]
+		if !ok {
+			t.Errorf("missing metric %s", tt.metric)
+			continue
+		}
+		if math.Abs(d.A-tt.wantA) > 0.5 || math.Abs(d.B-tt.wantB) > 0.5 {
+			t.Errorf("%s: got %v -> %v, want %v -> %v", tt.metric, d.A, d.B, tt.wantA, tt.wantB)
+		}
+		if d.Diff != d.B-d.A {
+			t.Errorf("%s: diff %v is not B-A", tt.metric, d.Diff)
+		}
+	}
+	if deltas["error_rate"].Relative == nil || *deltas["error_rate"].Relative != -100 {
+		t.Errorf("expected the error-rate to drop by 100%%, got %v", deltas["error_rate"].Relative)
+	}
+	if r.MannWhitney == nil || r.MannWhitney.ProbabilityBSlower <= 0.5 {
+		t.Errorf("expected B to be slower, got %#v", r.MannWhitney)
+	}
+	if r.Table() == "" {
+		t.Error("expected a table")
+	}
+}
@@ -3,6 +3,7 @@ module github.com/runar-rkmedia/gabyoall
 go 1.17
 
 require (
+	github.com/HdrHistogram/hdrhistogram-go v1.1.2
 	github.com/Masterminds/goutils v1.1.1 // indirect
 	github.com/Masterminds/semver v1.5.0 // indirect
 	github.com/Masterminds/sprig v2.22.0+incompatible
@@ -12,6 +13,7 @@ require (
 	github.com/huandu/xstrings v1.3.2 // indirect
 	github.com/mitchellh/copystructure v1.2.0 // indirect
 	github.com/rs/zerolog v1.23.0
+	github.com/santhosh-tekuri/jsonschema/v5 v5.1.1
 	github.com/spf13/cobra v1.2.1
 	github.com/spf13/viper v1.9.0
 	github.com/tj/go-spin v1.1.0
@@ -26,7 +28,7 @@ require (
 	github.com/inconshreveable/mousetrap v1.0.0 // indirect
 	github.com/jmespath/go-jmespath v0.4.0
 	github.com/magiconair/properties v1.8.5 // indirect
-	github.com/mitchellh/mapstructure v1.4.2 // indirect
+	github.com/mitchellh/mapstructure v1.4.2
 	github.com/mitchellh/reflectwalk v1.0.2 // indirect
 	github.com/pelletier/go-toml v1.9.4
 	github.com/spf13/afero v1.6.0 // indirect
@@ -47,6 +49,8 @@ require (
 	github.com/NYTimes/gziphandler v1.1.1
 	github.com/arl/statsviz v0.4.0
 	github.com/dustin/go-humanize v1.0.0
+	github.com/go-test/deep v1.0.8
+	github.com/gookit/color v1.5.0
 	github.com/gorilla/websocket v1.4.2
 	github.com/r3labs/diff/v2 v2.14.0
 	github.com/tsenart/go-tsz v0.0.0-20180814235614-0bd30b3df1c3
@@ -55,9 +59,7 @@ require (
 require (
 	github.com/go-playground/locales v0.14.0 // indirect
 	github.com/go-playground/universal-translator v0.18.0 // indirect
-	github.com/go-test/deep v1.0.8 // indirect
 	github.com/golang/protobuf v1.5.2 // indirect
-	github.com/gookit/color v1.5.0 // indirect
 	github.com/leodido/go-urn v1.2.1 // indirect
 	github.com/stretchr/testify v1.7.1-0.20210427113832-6241f9ab9942 // indirect
 	github.com/vmihailenco/msgpack v4.0.4+incompatible // indirect
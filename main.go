@@ -2,10 +2,10 @@ package main
 
 import (
 	"errors"
+	"net/http"
 	"os"
 	"os/signal"
 	"path"
-	"strings"
 	"syscall"
 	"time"
 
@@ -14,8 +14,12 @@ import (
 	"github.com/runar-rkmedia/gabyoall/auth"
 	"github.com/runar-rkmedia/gabyoall/cmd"
 	"github.com/runar-rkmedia/gabyoall/logger"
+	"github.com/runar-rkmedia/gabyoall/metrics"
 	"github.com/runar-rkmedia/gabyoall/printer"
 	"github.com/runar-rkmedia/gabyoall/requests"
+	"github.com/runar-rkmedia/gabyoall/sinks"
+	"github.com/runar-rkmedia/gabyoall/thresholds"
+	"github.com/runar-rkmedia/gabyoall/tracing"
 	"github.com/runar-rkmedia/gabyoall/utils"
 	"github.com/runar-rkmedia/gabyoall/worker"
 )
@@ -28,6 +32,7 @@ type TemplateVars struct {
 }
 
 func main() {
+	cmd.AuthHeader = authHeader
 
 	err := cmd.Execute(func() {
 		err := cmd.InitConfig()
@@ -38,15 +43,22 @@ func main() {
 	if err != nil {
 		os.Exit(1)
 	}
+	if !cmd.RootInvoked() {
+		return
+	}
 	config := cmd.GetConfig(logger.GetLogger("initial"))
 	// TODO: Refactor so this is a bit more general. (but still support graphql)
 	var query = requests.Request{
-		Body:          config.Body,
-		Query:         config.Query,
-		Variables:     config.Variables,
-		OperationName: config.OperationName,
-		Headers:       config.Header,
-		Method:        config.Method,
+		Body:           config.Body,
+		Query:          config.Query,
+		Variables:      config.Variables,
+		OperationName:  config.OperationName,
+		Headers:        config.Header,
+		Method:         config.Method,
+		Capture:        config.Capture,
+		Vars:           config.Vars,
+		Assertions:     config.Assertions,
+		ResponseSchema: config.ResponseSchema,
 	}
 	logger.InitLogger(logger.LogConfig{
 		Level:      config.LogLevel,
@@ -62,6 +74,36 @@ func main() {
 	if query.Query == "" && query.Body == "" {
 		l.Fatal().Interface("query", query).Msg("Missing query/body")
 	}
+	if err := requests.ValidateAssertions(query.Assertions); err != nil {
+		l.Fatal().Err(err).Msg("Invalid assertions")
+	}
+	if err := requests.ValidateClassificationRules(config.ClassificationRules); err != nil {
+		l.Fatal().Err(err).Msg("Invalid classification-rules")
+	}
+	if err := requests.ValidateNormalizationRules(config.Normalization); err != nil {
+		l.Fatal().Err(err).Msg("Invalid normalization-rules")
+	}
+	if query.ResponseSchema != nil {
+		if err := query.ResponseSchema.Validate(); err != nil {
+			l.Fatal().Err(err).Msg("Invalid response-schema")
+		}
+	}
+	if config.SLO != nil {
+		if err := config.SLO.Validate(); err != nil {
+			l.Fatal().Err(err).Msg("Invalid slo")
+		}
+	}
+	if config.Golden != "" {
+		golden, err := cmd.ReadGoldenResponse(config.Golden)
+		if err != nil {
+			l.Fatal().Err(err).Msg("Failed to read the golden response")
+		}
+		query.Golden = golden
+	}
+	thresholdList, err := thresholds.ParseAll(config.Thresholds)
+	if err != nil {
+		l.Fatal().Err(err).Msg("Invalid thresholds")
+	}
 	if config.Auth.HeaderKey == "" {
 		config.Auth.HeaderKey = "Authorization"
 	}
@@ -79,36 +121,141 @@ func main() {
 	token := utils.RunTemplating(l, config.AuthToken, "token", vars)
 	var tokenPayload *auth.TokenPayload
 	var validityStringer printer.ValidityStringer
+	var cookies []*http.Cookie
 	if token == "" {
-		err, token, tokenPayload, validityStringer = auth.Retrieve(l, config.Auth)
+		err, token, tokenPayload, validityStringer, cookies = auth.Retrieve(l, config.Auth)
 		if err != nil {
 			l.Fatal().Err(err).Msg("Failed to perform authentication")
 		}
 	}
 
-	out, err := cmd.NewOutput(l, outputPath, config.Url, query, tokenPayload.Raw)
+	var jwtPayload map[string]interface{}
+	if tokenPayload != nil {
+		jwtPayload = tokenPayload.Raw
+	}
+	out, err := cmd.NewOutput(l, outputPath, config.Url, query, jwtPayload)
 	if err != nil {
 		l.Fatal().Err(err).Msg("Failed to set up output")
 	}
 	l.Info().Str("path", out.GetPath()).Msg("Will write output to path:")
+	if config.Baseline != "" {
+		baseline, err := cmd.ReadRun(config.Baseline)
+		if err != nil {
+			l.Fatal().Err(err).Msg("Failed to read the baseline")
+		}
+		out.Baseline = &baseline
+	}
+	if (config.CheckpointInterval > 0 || config.Resume) && out.GetPath() == "" {
+		l.Fatal().Msg("Checkpoints are written next to the output-file, so --output is required with --checkpoint-interval and --resume")
+	}
+	var checkpoint *cmd.Checkpoint
+	if config.Resume {
+		c, err := cmd.ReadCheckpoint(out.CheckpointPath())
+		if err != nil {
+			l.Fatal().Err(err).Msg("Failed to read the checkpoint of the run to resume")
+		}
+		if c.Completed >= config.RequestCount {
+			l.Fatal().Int("completed", c.Completed).Int("count", config.RequestCount).Msg("The run to resume has already completed all of its requests")
+		}
+		checkpoint = &c
+	}
+	if config.RequestLog != "" {
+		logPath := utils.RunTemplating(l, config.RequestLog, "request-log", vars)
+		if err := os.MkdirAll(path.Dir(logPath), 0755); err != nil {
+			l.Fatal().Err(err).Str("dir", path.Dir(logPath)).Msg("Failed to create directories for the request-log")
+		}
+		if checkpoint != nil {
+			size := int64(-1)
+			if checkpoint.RequestLogSize != nil {
+				size = *checkpoint.RequestLogSize
+			}
+			out.RequestLog, err = requests.AppendRequestLog(logPath, size)
+		} else {
+			out.RequestLog, err = requests.NewRequestLog(logPath)
+		}
+		if err != nil {
+			l.Fatal().Err(err).Msg("Failed to set up the request-log")
+		}
+		defer out.RequestLog.Close()
+		l.Info().Str("path", logPath).Msg("Will stream each request to the request-log")
+	}
 	ts := requests.NewTimeSeriesWithLabel(time.Now())
-	endpoint := requests.NewEndpoint(logger.GetLogger("gql"), config.Url, &ts)
-	authPrefix := ""
-	if strings.ToLower(config.Auth.Kind) == "bearer" {
-		authPrefix = "Bearer "
+	out.Throughput = ts.Throughput
+	out.Samples = requests.NewSamples(config.Samples, config.Auth.HeaderKey)
+	out.TimeSeries = &ts
+	var resumedElapsed time.Duration
+	if checkpoint != nil {
+		if err := out.Resume(*checkpoint); err != nil {
+			l.Fatal().Err(err).Msg("Failed to resume the run from its checkpoint")
+		}
+		resumedElapsed = checkpoint.Elapsed
+		l.Info().Int("completed", checkpoint.Completed).Int("count", config.RequestCount).Time("checkpoint", checkpoint.Time).Msg("Resuming the run from its checkpoint")
+	}
+	redactedConfig := config.Redacted()
+	out.Config = &redactedConfig
+	var runMetrics *metrics.Metrics
+	if config.MetricsAddr != "" {
+		runMetrics = metrics.NewMetrics()
+		runMetrics.TrackRun(ts.Throughput)
+		if tokenPayload != nil {
+			runMetrics.SetTokenExpiry(tokenPayload.Expires)
+		}
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", runMetrics)
+		go func() {
+			if err := http.ListenAndServe(config.MetricsAddr, mux); err != nil {
+				l.Error().Err(err).Str("address", config.MetricsAddr).Msg("Failed to serve metrics")
+			}
+		}()
+		l.Info().Str("address", config.MetricsAddr).Msg("Serving metrics at /metrics")
+	}
+	runID, warn := utils.ForceCreateUniqueId()
+	if warn != nil {
+		l.Warn().Err(warn).Str("ID", runID).Msg("Failed to create id, used fallback-method instead")
 	}
-	endpoint.Headers.Add(config.Auth.HeaderKey, authPrefix+token)
+	var exporter *tracing.Exporter
+	if config.OtlpEndpoint != "" {
+		exporter, err = tracing.NewExporter(logger.GetLogger("otlp"), config.OtlpEndpoint, runID)
+		if err != nil {
+			l.Fatal().Err(err).Msg("Failed to set up the otlp-exporter")
+		}
+		l.Info().Str("endpoint", utils.URLHost(exporter.Endpoint)).Str("runID", runID).Msg("Will export a span for each request")
+	}
+	var pusher *sinks.Pusher
+	if len(config.Sinks) > 0 {
+		sinkList, err := sinks.NewSinks(config.Sinks)
+		if err != nil {
+			l.Fatal().Err(err).Msg("Failed to set up the sinks")
+		}
+		pusher = sinks.NewPusher(logger.GetLogger("sinks"), sinkList, time.Second, map[string]string{"run_id": runID})
+		hosts := make([]string, len(config.Sinks))
+		for i, s := range config.Sinks {
+			hosts[i] = utils.URLHost(s)
+		}
+		l.Info().Strs("sinks", hosts).Str("runID", runID).Msg("Will push aggregates every second to the sinks")
+	}
+	endpoint := requests.NewEndpoint(logger.GetLogger("gql"), config.Url, &ts)
+	endpoint.Headers.Add(config.Auth.HeaderKey, auth.HeaderPrefix(config.Auth.Kind)+token)
+	endpoint.ClassificationRules = config.ClassificationRules
+	endpoint.Normalization = config.Normalization
+	endpoint.PartialDataOk = config.PartialDataOk
 
 	l.Info().Str("url", config.Url).Str("operationName", query.OperationName).Int("count", config.RequestCount).Int("paralism", config.Concurrency).Msg("Running requests with paralism")
-	SetupCloseHandler(func(signal os.Signal) {
-		out.Write()
-	})
+	// The start is moved back by the time of the previous sessions of a resumed run
+	startTime := time.Now().Add(-resumedElapsed)
+	interrupted := SetupCloseHandler()
 
-	wt := worker.WorkThing{}
-	ch, quit := wt.Run(endpoint, *config, query)
+	completed := out.Overall.Count
+	successes := out.Count[""]
+	// Only the remaining requests are made, if the run was resumed
+	workConfig := *config
+	workConfig.RequestCount -= completed
+	if workConfig.Concurrency > workConfig.RequestCount {
+		workConfig.Concurrency = workConfig.RequestCount
+	}
+	wt := worker.WorkThing{Cookies: cookies}
+	ch, quit := wt.Run(endpoint, workConfig, query)
 
-	successes := 0
-	startTime := time.Now()
 	print := printer.NewPrinter(
 		*config,
 		validityStringer,
@@ -118,33 +265,116 @@ func main() {
 	)
 	print.Animate()
 
-	for i := 0; i < config.RequestCount; i++ {
-		stat := <-ch
+	aborted := false
+	var interruptedBy os.Signal
+	lastCheckpoint := time.Now()
+run:
+	for ; completed < config.RequestCount; completed++ {
+		var stat requests.RequestStat
+		select {
+		case stat = <-ch:
+		case interruptedBy = <-interrupted:
+			// The run is ended here, and not by the signal-handler, so that the output is not read while a stat is added
+			log.Warn().Str("signal", interruptedBy.String()).Msg("Interrupted, ending the run with the completed requests")
+			break run
+		}
 		if stat.ErrorType == "" {
 			successes++
 		}
 		out.AddStat(stat)
-		print.Update(i, successes)
+		runMetrics.Observe(stat)
+		exporter.Export(stat)
+		pusher.Observe(stat)
+		print.Update(completed, successes)
+		if config.CheckpointInterval > 0 && time.Now().Sub(lastCheckpoint) >= config.CheckpointInterval {
+			if err := out.WriteCheckpoint(config.RequestCount, time.Now().Sub(startTime)); err != nil {
+				l.Warn().Err(err).Msg("Failed to write checkpoint")
+			}
+			lastCheckpoint = time.Now()
+		}
+		if config.AbortOnThreshold && len(thresholdList) > 0 {
+			if t, breached := thresholds.AnyBreached(thresholdList, out.ThresholdStats(time.Now().Sub(startTime)), config.RequestCount); breached {
+				l.Error().Str("threshold", t.Expression).Int("completed", completed+1).Msg("Threshold is irrecoverably breached, aborting")
+				completed++
+				aborted = true
+				break
+			}
+		}
 
 	}
 	out.CalculateStats()
+	out.SLO = config.SLO.Evaluate(out.Histograms)
 
-	print.Complete(config.RequestCount, successes)
+	print.Complete(completed, successes)
+	if err := exporter.Close(); err != nil {
+		l.Warn().Err(err).Msg("Failed to export all spans")
+	}
+	if err := pusher.Close(); err != nil {
+		l.Warn().Err(err).Msg("Failed to close the sinks")
+	}
+	if out.SLO != nil {
+		l.Info().Float64("apdex", out.SLO.Apdex).Float64("availability", out.SLO.ActualAvailability).Float64("withinLatencyTarget", out.SLO.WithinLatencyTarget).Bool("compliant", out.SLO.Compliant).Msg("SLO")
+	}
+	thresholdsOk := true
+	if len(thresholdList) > 0 {
+		out.Thresholds, thresholdsOk = thresholds.EvaluateAll(thresholdList, out.ThresholdStats(time.Now().Sub(startTime)))
+		out.PrintThresholds()
+	}
 	err = out.Write()
 	if err != nil {
 		l.Fatal().Err(errors.Unwrap(err)).Msg("Failed to write output")
 	}
-	close(quit)
+	if interruptedBy != nil {
+		// The checkpoint is kept, so that the run can be resumed
+		if config.CheckpointInterval > 0 {
+			if err := out.WriteCheckpoint(config.RequestCount, time.Now().Sub(startTime)); err != nil {
+				l.Error().Err(err).Msg("Failed to write checkpoint")
+			}
+		}
+		out.RequestLog.Close()
+		os.Exit(signalExitCode(interruptedBy))
+	}
+	if err := out.RemoveCheckpoint(); err != nil {
+		l.Warn().Err(err).Msg("Failed to remove the checkpoint")
+	}
+	if !thresholdsOk {
+		l.Error().Msg("One or more thresholds failed")
+		os.Exit(1)
+	}
+	// Jobs may still be queued when aborting, so the channel cannot be closed.
+	if !aborted {
+		close(quit)
+	}
 	l.Info().Msg("All done")
 }
 
-func SetupCloseHandler(f func(signal os.Signal)) {
-	c := make(chan os.Signal)
+// SetupCloseHandler returns a channel which receives Ctrl+C and SIGTERM
+func SetupCloseHandler() <-chan os.Signal {
+	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
-	go func() {
-		signal := <-c
-		log.Warn().Msg("- Ctrl+C pressed in Terminal")
-		f(signal)
-		os.Exit(0)
-	}()
+	return c
+}
+
+// The exit-code of a process ended by the signal, like 130 for Ctrl+C and 143 for SIGTERM
+func signalExitCode(sig os.Signal) int {
+	if s, ok := sig.(syscall.Signal); ok {
+		return 128 + int(s)
+	}
+	return 1
+}
+
+// Retrieves a token like for a run, as the header to authenticate each request with
+func authHeader(l logger.AppLogger, config cmd.Config) (string, string, error) {
+	if config.Auth.HeaderKey == "" {
+		config.Auth.HeaderKey = "Authorization"
+	}
+	token := utils.RunTemplating(l, config.AuthToken, "token", TemplateVars{config})
+	if token == "" {
+		err, t, _, _, _ := auth.Retrieve(l, config.Auth)
+		if err != nil {
+			return "", "", err
+		}
+		token = t
+	}
+	return config.Auth.HeaderKey, auth.HeaderPrefix(config.Auth.Kind) + token, nil
 }
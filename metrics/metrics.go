@@ -0,0 +1,188 @@
+// Package metrics exposes live metrics of runs in the Prometheus text exposition format,
+// so that runs can be charted next to the metrics of the server under test.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/runar-rkmedia/gabyoall/requests"
+)
+
+// The default buckets of the Prometheus client-libraries, in seconds
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+const namespace = "gobyoall"
+
+type counterKey struct {
+	errorType  requests.ErrorType
+	statusCode int
+}
+
+type histogram struct {
+	// Cumulative counts are calculated when written, these are per bucket
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// Metrics collects counters and latency-histograms from completed requests.
+// The requests in flight are read from the throughput-series of the runs which are tracked.
+type Metrics struct {
+	Buckets     []float64
+	requests    map[counterKey]uint64
+	durations   map[requests.ErrorType]*histogram
+	runs        map[*requests.ThroughputSeries]struct{}
+	runsTotal   uint64
+	tokenExpiry time.Time
+	lock        sync.Mutex
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{
+		Buckets:   DefaultBuckets,
+		requests:  map[counterKey]uint64{},
+		durations: map[requests.ErrorType]*histogram{},
+		runs:      map[*requests.ThroughputSeries]struct{}{},
+	}
+}
+
+// Observe records a completed request. A nil Metrics is a no-op.
+func (m *Metrics) Observe(stat requests.RequestStat) {
+	if m == nil {
+		return
+	}
+	seconds := stat.Duration.Seconds()
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.requests[counterKey{stat.ErrorType, int(stat.StatusCode)}]++
+	h, ok := m.durations[stat.ErrorType]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(m.Buckets))}
+		m.durations[stat.ErrorType] = h
+	}
+	for i, le := range m.Buckets {
+		if seconds <= le {
+			h.counts[i]++
+			break
+		}
+	}
+	h.sum += seconds
+	h.count++
+}
+
+// TrackRun includes the requests in flight of the run, until it is untracked
+func (m *Metrics) TrackRun(t *requests.ThroughputSeries) {
+	if m == nil || t == nil {
+		return
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.runs[t] = struct{}{}
+	m.runsTotal++
+}
+
+func (m *Metrics) UntrackRun(t *requests.ThroughputSeries) {
+	if m == nil {
+		return
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	delete(m.runs, t)
+}
+
+// SetTokenExpiry sets when the token used for the requests expires. The zero time removes it.
+func (m *Metrics) SetTokenExpiry(t time.Time) {
+	if m == nil {
+		return
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.tokenExpiry = t
+}
+
+// ServeHTTP writes the metrics in the Prometheus text exposition format
+func (m *Metrics) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	rw.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w := bufio.NewWriter(rw)
+	m.Write(w)
+	w.Flush()
+}
+
+// Write writes the metrics in the Prometheus text exposition format, sorted by their labels
+func (m *Metrics) Write(w io.Writer) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	writeHeader(w, "requests_total", "counter", "Completed requests, by error-type and status-code. Successful requests have an empty error-type.")
+	keys := make([]counterKey, 0, len(m.requests))
+	for k := range m.requests {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].errorType == keys[j].errorType {
+			return keys[i].statusCode < keys[j].statusCode
+		}
+		return keys[i].errorType < keys[j].errorType
+	})
+	for _, k := range keys {
+		fmt.Fprintf(w, "%s_requests_total{error_type=%s,status_code=\"%d\"} %d\n", namespace, quote(string(k.errorType)), k.statusCode, m.requests[k])
+	}
+
+	writeHeader(w, "request_duration_seconds", "histogram", "Latency of completed requests, by error-type.")
+	errorTypes := make([]string, 0, len(m.durations))
+	for errorType := range m.durations {
+		errorTypes = append(errorTypes, string(errorType))
+	}
+	sort.Strings(errorTypes)
+	for _, errorType := range errorTypes {
+		h := m.durations[requests.ErrorType(errorType)]
+		label := "error_type=" + quote(errorType)
+		var cumulative uint64
+		for i, le := range m.Buckets {
+			cumulative += h.counts[i]
+			fmt.Fprintf(w, "%s_request_duration_seconds_bucket{%s,le=\"%s\"} %d\n", namespace, label, formatFloat(le), cumulative)
+		}
+		fmt.Fprintf(w, "%s_request_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", namespace, label, h.count)
+		fmt.Fprintf(w, "%s_request_duration_seconds_sum{%s} %s\n", namespace, label, formatFloat(h.sum))
+		fmt.Fprintf(w, "%s_request_duration_seconds_count{%s} %d\n", namespace, label, h.count)
+	}
+
+	writeHeader(w, "requests_in_flight", "gauge", "Requests which have been sent, but not yet completed.")
+	var inFlight int64
+	for t := range m.runs {
+		inFlight += t.InFlightNow()
+	}
+	fmt.Fprintf(w, "%s_requests_in_flight %d\n", namespace, inFlight)
+
+	writeHeader(w, "runs_active", "gauge", "Runs which are in progress.")
+	fmt.Fprintf(w, "%s_runs_active %d\n", namespace, len(m.runs))
+	writeHeader(w, "runs_total", "counter", "Runs which have been started.")
+	fmt.Fprintf(w, "%s_runs_total %d\n", namespace, m.runsTotal)
+
+	if !m.tokenExpiry.IsZero() {
+		writeHeader(w, "token_expiry_timestamp_seconds", "gauge", "When the token used for the requests expires, in seconds since the epoch.")
+		fmt.Fprintf(w, "%s_token_expiry_timestamp_seconds %d\n", namespace, m.tokenExpiry.Unix())
+	}
+}
+
+func writeHeader(w io.Writer, name, kind, help string) {
+	fmt.Fprintf(w, "# HELP %s_%s %s\n# TYPE %s_%s %s\n", namespace, name, help, namespace, name, kind)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func quote(labelValue string) string {
+	return `"` + labelEscaper.Replace(labelValue) + `"`
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/runar-rkmedia/gabyoall/requests"
+)
+
+func TestMetrics_ServeHTTP(t *testing.T) {
+	m := NewMetrics()
+	m.Buckets = []float64{.01, .1}
+	for _, s := range []struct {
+		errorType  requests.ErrorType
+		statusCode int16
+		duration   time.Duration
+	}{
+		{"", 200, 5 * time.Millisecond},
+		{"", 200, 50 * time.Millisecond},
+		{"", 200, time.Second},
+		{`Say "hi"`, 500, 20 * time.Millisecond},
+	} {
+		stat := requests.RequestStat{ErrorType: s.errorType, Duration: s.duration}
+		stat.StatusCode = s.statusCode
+		m.Observe(stat)
+	}
+	start := time.Now()
+	throughput := requests.NewThroughputSeries(start)
+	throughput.RequestStarted(start)
+	throughput.RequestStarted(start)
+	m.TrackRun(throughput)
+	m.SetTokenExpiry(time.Unix(1700000000, 0))
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	b, _ := io.ReadAll(rec.Body)
+	body := string(b)
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("unexpected content-type %s", ct)
+	}
+	tests := []string{
+		`# TYPE gobyoall_requests_total counter`,
+		`gobyoall_requests_total{error_type="",status_code="200"} 3`,
+		`gobyoall_requests_total{error_type="Say \"hi\"",status_code="500"} 1`,
+		`# TYPE gobyoall_request_duration_seconds histogram`,
+		`gobyoall_request_duration_seconds_bucket{error_type="",le="0.01"} 1`,
+		`gobyoall_request_duration_seconds_bucket{error_type="",le="0.1"} 2`,
+		`gobyoall_request_duration_seconds_bucket{error_type="",le="+Inf"} 3`,
+		`gobyoall_request_duration_seconds_sum{error_type=""} 1.055`,
+		`gobyoall_request_duration_seconds_count{error_type=""} 3`,
+		`gobyoall_requests_in_flight 2`,
+		`gobyoall_runs_active 1`,
+		`gobyoall_token_expiry_timestamp_seconds 1700000000`,
+	}
+	for _, want := range tests {
+		t.Run(want, func(t *testing.T) {
+			if !strings.Contains(body, want+"\n") {
+				t.Errorf("missing line %s in\n%s", want, body)
+			}
+		})
+	}
+
+	m.UntrackRun(throughput)
+	rec = httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if !strings.Contains(rec.Body.String(), "gobyoall_requests_in_flight 0\n") {
+		t.Error("expected no requests in flight after the run is untracked")
+	}
+}
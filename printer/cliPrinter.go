@@ -86,7 +86,9 @@ func (p *Printer) update(i int, successes int) {
 	if failures > 0 {
 		fails = fmt.Sprintf("\033[31m[%d (%.2f%%)\033[0m", failures, float64(failures)/float64(i)*100)
 	}
-	fmt.Printf("\r\033[36m[%d/%d (%.2f%%) %s -c=%d] %s Waiting for result from: %s (%s) \033[m %s (%s) %s", i, p.config.RequestCount, fraction*100, fails, p.config.Concurrency, p.spinner.Current(), p.config.Url, p.operationName, utils.PrettyDuration(dur), utils.PrettyDuration(estimatedCompletion), validStr)
+	pct := p.out.Percentiles()
+	latency := fmt.Sprintf("p50=%s p95=%s p99=%s", utils.PrettyDuration(pct.P50), utils.PrettyDuration(pct.P95), utils.PrettyDuration(pct.P99))
+	fmt.Printf("\r\033[36m[%d/%d (%.2f%%) %s -c=%d] %s Waiting for result from: %s (%s) \033[m %s (%s) %s %s", i, p.config.RequestCount, fraction*100, fails, p.config.Concurrency, p.spinner.Current(), p.config.Url, p.operationName, utils.PrettyDuration(dur), utils.PrettyDuration(estimatedCompletion), latency, validStr)
 
 }
 
@@ -0,0 +1,221 @@
+package report
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/runar-rkmedia/gabyoall/requests"
+	"github.com/runar-rkmedia/gabyoall/utils"
+)
+
+var (
+	//go:embed html.tmpl
+	htmlTemplate string
+	htmlTmpl     = template.Must(template.New("report").Funcs(template.FuncMap{
+		"duration": utils.PrettyDuration,
+		"json":     prettyJSON,
+		"percent":  func(f float64) string { return fmt.Sprintf("%.2f%%", f) },
+	}).Parse(htmlTemplate))
+)
+
+// Latency-series are reduced to this many points before they are drawn
+const maxChartPoints = 400
+
+// HTMLReport is everything in the self-contained html-report of a run
+type HTMLReport struct {
+	Title     string
+	Generated time.Time
+	StartTime time.Time
+	Elapsed   time.Duration
+	Summary   []SummaryRow
+	// Latencies over time, by ErrorType, from the TimeSeriesMap
+	Latency map[string]*requests.TimeSeriesExpanded
+	// Per-second series of the run, if any
+	Throughput *requests.ThroughputSeries
+	Errors     []ErrorBucket
+	SLO        *requests.SLOResult
+	// Payload of the token used, if any
+	JWT map[string]interface{}
+	// The effective config of the run, which must have its secrets redacted already
+	Config interface{}
+}
+
+// SummaryRow is a row in the summary-table
+type SummaryRow struct {
+	Label       string
+	Stats       requests.Stats
+	Percentiles requests.Percentiles
+}
+
+// ErrorBucket is the failed requests of an ErrorType
+type ErrorBucket struct {
+	ErrorType requests.ErrorType
+	Count     int
+	// Distinct responses, most common first
+	Responses []ResponseSample
+	// Request-ids of some of the requests, if they were sampled
+	RequestIDs []string
+}
+
+// ResponseSample is a distinct response of an ErrorType
+type ResponseSample struct {
+	Hash        string
+	Count       int
+	ContentType string
+	Body        string
+}
+
+// NewSummaryRow creates a row from the stats of an ErrorType
+func NewSummaryRow(label string, s *requests.Stats) SummaryRow {
+	if s == nil {
+		return SummaryRow{Label: label}
+	}
+	return SummaryRow{Label: label, Stats: *s, Percentiles: s.Percentiles}
+}
+
+// AddResponse adds a response to the bucket, merging it with an identical one.
+// Samples without a hash are merged by their body.
+func (b *ErrorBucket) AddResponse(r ResponseSample) {
+	for i := range b.Responses {
+		if b.Responses[i].Hash == r.Hash && b.Responses[i].Body == r.Body {
+			b.Responses[i].Count += r.Count
+			return
+		}
+	}
+	b.Responses = append(b.Responses, r)
+}
+
+// SortErrorBuckets sorts the buckets and their responses by count, most common first
+func SortErrorBuckets(buckets []ErrorBucket) {
+	for _, b := range buckets {
+		sort.SliceStable(b.Responses, func(i, j int) bool { return b.Responses[i].Count > b.Responses[j].Count })
+	}
+	sort.SliceStable(buckets, func(i, j int) bool {
+		if buckets[i].Count == buckets[j].Count {
+			return buckets[i].ErrorType < buckets[j].ErrorType
+		}
+		return buckets[i].Count > buckets[j].Count
+	})
+}
+
+// MarshalHTML renders the report as a single html-file, with the charts as inline svg
+func (r HTMLReport) MarshalHTML() ([]byte, error) {
+	if r.Generated.IsZero() {
+		r.Generated = time.Now()
+	}
+	data := struct {
+		HTMLReport
+		LatencyChart    template.HTML
+		ThroughputChart template.HTML
+	}{
+		HTMLReport:      r,
+		LatencyChart:    r.latencyChart(),
+		ThroughputChart: r.throughputChart(),
+	}
+	var buf bytes.Buffer
+	if err := htmlTmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("failed to render html-report: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func (r HTMLReport) latencyChart() template.HTML {
+	if len(r.Latency) == 0 {
+		return ""
+	}
+	labels := make([]string, 0, len(r.Latency))
+	for label := range r.Latency {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	var series []chartSeries
+	for _, label := range labels {
+		exp := r.Latency[label]
+		if exp == nil || len(exp.Series) == 0 {
+			continue
+		}
+		points := append([]requests.Serie{}, exp.DropResolution(maxChartPoints).Series...)
+		sort.Slice(points, func(i, j int) bool { return points[i][0] < points[j][0] })
+		offset := exp.StartTime.Sub(r.StartTime).Seconds()
+		s := chartSeries{Name: label, Dots: true}
+		if label == "" {
+			s.Name = "Success"
+		}
+		for _, p := range points {
+			s.Points = append(s.Points, chartPoint{offset + float64(p[0])/1000, float64(p[1])})
+		}
+		series = append(series, s)
+	}
+	return svgChart("Latency (ms)", series)
+}
+
+func (r HTMLReport) throughputChart() template.HTML {
+	t := r.Throughput
+	if t == nil || len(t.Completed) == 0 {
+		return ""
+	}
+	perSecond := func(name string, values []int64) chartSeries {
+		s := chartSeries{Name: name}
+		for i, v := range values {
+			s.Points = append(s.Points, chartPoint{float64(i), float64(v)})
+		}
+		return s
+	}
+	series := []chartSeries{perSecond("Completed/s", t.Completed), perSecond("In flight", t.InFlight)}
+	succeeded := t.CompletedByLabel[""]
+	failed := make([]int64, len(t.Completed))
+	for i := range failed {
+		failed[i] = t.Completed[i]
+		if i < len(succeeded) {
+			failed[i] -= succeeded[i]
+		}
+	}
+	series = append(series, perSecond("Failed/s", failed))
+	return svgChart("Throughput", series)
+}
+
+func prettyJSON(v interface{}) string {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err.Error()
+	}
+	return string(b)
+}
+
+// Formats json-bodies for readability, and leaves others as they are
+func formatBody(contentType string, body []byte) string {
+	if strings.Contains(contentType, "json") {
+		var j interface{}
+		if err := json.Unmarshal(body, &j); err == nil {
+			return prettyJSON(j)
+		}
+	}
+	return string(body)
+}
+
+// NewFailureSample creates a sample from the response of a sampled failure.
+// These have no hash, so identical bodies are merged in AddResponse.
+func NewFailureSample(s requests.Sample) ResponseSample {
+	contentType := s.Response.Headers.Get("Content-Type")
+	return ResponseSample{
+		Count:       1,
+		ContentType: contentType,
+		Body:        formatBody(contentType, []byte(s.Response.Body)),
+	}
+}
+
+// NewResponseSample creates a sample from a response in the ByteHashMap
+func NewResponseSample(hash [32]byte, content requests.ByteContent, count int) ResponseSample {
+	return ResponseSample{
+		Hash:        requests.Hash(hash).String(),
+		Count:       count,
+		ContentType: content.ContentType,
+		Body:        formatBody(content.ContentType, content.Content),
+	}
+}
@@ -0,0 +1,91 @@
+// Package report renders the results of a run in formats which other tools understand.
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"time"
+)
+
+// JUnitTestSuites is the root of a JUnit XML-report, as understood by most CI-systems
+type JUnitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr,omitempty"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Time     string           `xml:"time,attr,omitempty"`
+	Suites   []JUnitTestSuite `xml:"testsuite"`
+}
+
+type JUnitTestSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Time       string          `xml:"time,attr,omitempty"`
+	Timestamp  string          `xml:"timestamp,attr,omitempty"`
+	Properties []JUnitProperty `xml:"properties>property,omitempty"`
+	TestCases  []JUnitTestCase `xml:"testcase"`
+}
+
+type JUnitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type JUnitTestCase struct {
+	Classname string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Time      string        `xml:"time,attr,omitempty"`
+	Failure   *JUnitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type JUnitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr,omitempty"`
+	Details string `xml:",chardata"`
+}
+
+// NewJUnitTestSuite creates a suite. A zero start-time is left out.
+func NewJUnitTestSuite(name string, start time.Time, elapsed time.Duration) JUnitTestSuite {
+	s := JUnitTestSuite{Name: name, Time: junitSeconds(elapsed)}
+	if !start.IsZero() {
+		s.Timestamp = start.UTC().Format("2006-01-02T15:04:05")
+	}
+	return s
+}
+
+func junitSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+func (s *JUnitTestSuite) AddProperty(name string, value interface{}) {
+	s.Properties = append(s.Properties, JUnitProperty{Name: name, Value: fmt.Sprint(value)})
+}
+
+// AddCase adds a testcase, which failed if failure is set
+func (s *JUnitTestSuite) AddCase(classname, name string, duration time.Duration, failure *JUnitFailure) {
+	c := JUnitTestCase{Classname: classname, Name: name, Failure: failure}
+	if duration > 0 {
+		c.Time = junitSeconds(duration)
+	}
+	s.TestCases = append(s.TestCases, c)
+	s.Tests++
+	if failure != nil {
+		s.Failures++
+	}
+}
+
+// Marshal writes the report, with the xml-header
+func (r JUnitTestSuites) Marshal() ([]byte, error) {
+	r.Tests, r.Failures = 0, 0
+	for _, s := range r.Suites {
+		r.Tests += s.Tests
+		r.Failures += s.Failures
+	}
+	b, err := xml.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(b, '\n')...), nil
+}
@@ -0,0 +1,123 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/runar-rkmedia/gabyoall/compare"
+	"github.com/runar-rkmedia/gabyoall/requests"
+	"github.com/runar-rkmedia/gabyoall/thresholds"
+	"github.com/runar-rkmedia/gabyoall/utils"
+)
+
+const (
+	// Number of error-buckets in the markdown-summary, to keep it short enough for a comment
+	markdownErrorBuckets = 5
+	// Error-types are truncated to this many characters
+	markdownErrorTypeLength = 80
+)
+
+// MarkdownSummary is a compact summary of a run, to paste as a comment on a pull-request
+type MarkdownSummary struct {
+	Title    string
+	Requests int
+	Failed   int
+	Elapsed  time.Duration
+	Overall  requests.Stats
+	// Sorted by count, most common first
+	Errors     []ErrorBucket
+	Thresholds []thresholds.Result
+	SLO        *requests.SLOResult
+	// Comparison against a baseline-run, if any. The baseline is A.
+	Comparison *compare.Result
+}
+
+// Passed is true unless a threshold failed or the SLO was not met
+func (s MarkdownSummary) Passed() bool {
+	for _, t := range s.Thresholds {
+		if !t.Ok {
+			return false
+		}
+	}
+	return s.SLO == nil || s.SLO.Compliant
+}
+
+// MarshalMarkdown renders the summary as GitHub-flavoured markdown
+func (s MarkdownSummary) MarshalMarkdown() ([]byte, error) {
+	var sb strings.Builder
+	verdict := "✅ Passed"
+	if !s.Passed() {
+		verdict = "❌ Failed"
+	}
+	fmt.Fprintf(&sb, "### %s: %s\n\n", verdict, escapeMarkdown(s.Title))
+
+	var failing []string
+	for _, t := range s.Thresholds {
+		if !t.Ok {
+			failing = append(failing, fmt.Sprintf("`%s` (actual %s)", t.Threshold, t.ActualString))
+		}
+	}
+	if len(failing) > 0 {
+		fmt.Fprintf(&sb, "Failed thresholds: %s\n\n", strings.Join(failing, ", "))
+	} else if len(s.Thresholds) > 0 {
+		fmt.Fprintf(&sb, "All %d thresholds passed.\n\n", len(s.Thresholds))
+	}
+	if slo := s.SLO; slo != nil {
+		compliant := "met"
+		if !slo.Compliant {
+			compliant = "not met"
+		}
+		fmt.Fprintf(&sb, "SLO %s: Apdex %.3f, %.2f%% within %s (objective %.2f%%), availability %.2f%% (target %.2f%%), %.0f%% of the error-budget used.\n\n",
+			compliant, slo.Apdex, slo.WithinLatencyTarget, slo.LatencyTarget, slo.LatencyObjective, slo.ActualAvailability, slo.Availability, slo.ErrorBudgetUsed)
+	}
+
+	if c := s.Comparison; c != nil {
+		sb.WriteString("| Metric | Baseline | This run | Diff | Relative |\n|---|---:|---:|---:|---:|\n")
+		for _, d := range c.Deltas {
+			a, b, diff, rel := d.Formatted()
+			fmt.Fprintf(&sb, "| %s | %s | %s | %s | %s |\n", d.Metric, a, b, diff, rel)
+		}
+		sb.WriteString("\n")
+		if mw := c.MannWhitney; mw != nil {
+			fmt.Fprintf(&sb, "Latency against the baseline: %s (Mann-Whitney U, p=%.4f).\n\n", mw.Verdict(), mw.P)
+		}
+	} else {
+		errorRate := 0.0
+		if s.Requests > 0 {
+			errorRate = float64(s.Failed) / float64(s.Requests) * 100
+		}
+		rps := "-"
+		if s.Elapsed > 0 {
+			rps = fmt.Sprintf("%.1f", float64(s.Requests)/s.Elapsed.Seconds())
+		}
+		p := s.Overall.Percentiles
+		sb.WriteString("| Requests | Failed | Error-rate | RPS | p50 | p95 | p99 | Max |\n|---:|---:|---:|---:|---:|---:|---:|---:|\n")
+		fmt.Fprintf(&sb, "| %d | %d | %.2f%% | %s | %s | %s | %s | %s |\n\n", s.Requests, s.Failed, errorRate, rps,
+			utils.PrettyDuration(p.P50), utils.PrettyDuration(p.P95), utils.PrettyDuration(p.P99), utils.PrettyDuration(s.Overall.Max))
+	}
+
+	if len(s.Errors) > 0 {
+		sb.WriteString("| Error | Count |\n|---|---:|\n")
+		for i, b := range s.Errors {
+			if i == markdownErrorBuckets {
+				rest := 0
+				for _, b := range s.Errors[i:] {
+					rest += b.Count
+				}
+				fmt.Fprintf(&sb, "| _%d other error-types_ | %d |\n", len(s.Errors)-i, rest)
+				break
+			}
+			fmt.Fprintf(&sb, "| `%s` | %d |\n", escapeMarkdown(utils.Truncate(string(b.ErrorType), markdownErrorTypeLength)), b.Count)
+		}
+		sb.WriteString("\n")
+	}
+	return []byte(sb.String()), nil
+}
+
+// Escapes the characters which would break a table-cell or inline code
+var markdownEscaper = strings.NewReplacer("|", `\|`, "`", "'", "\n", " ", "\r", "")
+
+func escapeMarkdown(s string) string {
+	return markdownEscaper.Replace(s)
+}
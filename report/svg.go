@@ -0,0 +1,91 @@
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	chartWidth  = 900
+	chartHeight = 260
+	chartMargin = 45
+)
+
+var chartColors = []string{"#2b8a3e", "#1971c2", "#e03131", "#f08c00", "#9c36b5", "#0c8599", "#5c940d", "#d6336c"}
+
+type chartPoint struct {
+	// Seconds since the start of the run
+	X float64
+	Y float64
+}
+
+type chartSeries struct {
+	Name   string
+	Points []chartPoint
+	// Draw each point as a dot instead of a line, like for latencies of individual requests
+	Dots bool
+}
+
+func formatAxis(v float64) string {
+	if v >= 10 || v == 0 {
+		return strconv.FormatFloat(math.Round(v), 'f', 0, 64)
+	}
+	return strconv.FormatFloat(v, 'f', 1, 64)
+}
+
+// svgChart draws the series as an inline svg, with the x-axis in seconds
+func svgChart(title string, series []chartSeries) template.HTML {
+	var maxX, maxY float64
+	for _, s := range series {
+		for _, p := range s.Points {
+			maxX = math.Max(maxX, p.X)
+			maxY = math.Max(maxY, p.Y)
+		}
+	}
+	if maxX == 0 {
+		maxX = 1
+	}
+	if maxY == 0 {
+		maxY = 1
+	}
+	w, h := float64(chartWidth-2*chartMargin), float64(chartHeight-2*chartMargin)
+	x := func(v float64) float64 { return chartMargin + v/maxX*w }
+	y := func(v float64) float64 { return chartMargin + h - v/maxY*h }
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" class="chart">`, chartWidth, chartHeight)
+	fmt.Fprintf(&sb, `<text x="%d" y="20" class="title">%s</text>`, chartMargin, template.HTMLEscapeString(title))
+	// Grid and axis-labels
+	for i := 0; i <= 4; i++ {
+		v := maxY * float64(i) / 4
+		fmt.Fprintf(&sb, `<line x1="%d" x2="%d" y1="%.1f" y2="%.1f" class="grid"/>`, chartMargin, chartWidth-chartMargin, y(v), y(v))
+		fmt.Fprintf(&sb, `<text x="%d" y="%.1f" class="axis" text-anchor="end">%s</text>`, chartMargin-5, y(v)+4, formatAxis(v))
+		t := maxX * float64(i) / 4
+		fmt.Fprintf(&sb, `<text x="%.1f" y="%d" class="axis" text-anchor="middle">%ss</text>`, x(t), chartHeight-chartMargin+15, formatAxis(t))
+	}
+	for i, s := range series {
+		color := chartColors[i%len(chartColors)]
+		fmt.Fprintf(&sb, `<g><title>%s</title>`, template.HTMLEscapeString(s.Name))
+		if s.Dots {
+			for _, p := range s.Points {
+				fmt.Fprintf(&sb, `<circle cx="%.1f" cy="%.1f" r="2" fill="%s"/>`, x(p.X), y(p.Y), color)
+			}
+		} else {
+			points := make([]string, len(s.Points))
+			for j, p := range s.Points {
+				points[j] = fmt.Sprintf("%.1f,%.1f", x(p.X), y(p.Y))
+			}
+			fmt.Fprintf(&sb, `<polyline fill="none" stroke="%s" stroke-width="1.5" points="%s"/>`, color, strings.Join(points, " "))
+		}
+		sb.WriteString(`</g>`)
+		// Legend
+		lx := chartMargin + 150*i
+		fmt.Fprintf(&sb, `<rect x="%d" y="%d" width="10" height="10" fill="%s"/>`, lx, chartHeight-15, color)
+		fmt.Fprintf(&sb, `<text x="%d" y="%d" class="axis">%s</text>`, lx+14, chartHeight-6, template.HTMLEscapeString(s.Name))
+	}
+	sb.WriteString(`</svg>`)
+	return template.HTML(sb.String())
+}
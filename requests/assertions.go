@@ -0,0 +1,330 @@
+package requests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/jmespath/go-jmespath"
+)
+
+type AssertionKind string
+
+const (
+	// The status-code must be one of Status
+	AssertStatus AssertionKind = "status"
+	// The header must equal Equals, and/or match Matches
+	AssertHeader AssertionKind = "header"
+	// The body must match the regex in Matches
+	AssertBody AssertionKind = "body"
+	// The result of the jmes-path-expression on the json-body must equal Equals, exist, and/or have the length Length
+	AssertJmesPath AssertionKind = "jmes-path"
+	// The size of the body (in bytes) must be within MinSize and MaxSize
+	AssertSize AssertionKind = "size"
+	// The request must complete within MaxLatency
+	AssertLatency AssertionKind = "latency"
+)
+
+// Assertion is a check performed on every successful response.
+// If the check fails, the request is marked with the ErrorType `AssertionFailed-<Name>`
+type Assertion struct {
+	// Used in the ErrorType. Defaults to the kind
+	Name string        `json:"name,omitempty"`
+	Kind AssertionKind `json:"kind"`
+	// With kind=status, the accepted status-codes
+	Status []int `json:"status,omitempty"`
+	// With kind=header, the name of the header
+	Header string `json:"header,omitempty"`
+	// With kind=jmes-path, the expression to evaluate on the json-body
+	JmesPath string `json:"jmes_path,omitempty" mapstructure:"jmes_path"`
+	// With kind=header or jmes-path, the value must equal this.
+	// It is kept as json, since any value can be compared, and stored requests cannot hold interface-values.
+	Equals json.RawMessage `json:"equals,omitempty"`
+	// With kind=header or body, a regular expression the value must match
+	Matches string `json:"matches,omitempty"`
+	// With kind=jmes-path, whether the result must be non-null or null
+	Exists *bool `json:"exists,omitempty"`
+	// With kind=jmes-path, the length of the resulting array, object or string
+	Length *int `json:"length,omitempty"`
+	// With kind=size, the minimum size of the body in bytes
+	MinSize int `json:"min_size,omitempty" mapstructure:"min_size"`
+	// With kind=size, the maximum size of the body in bytes
+	MaxSize int `json:"max_size,omitempty" mapstructure:"max_size"`
+	// With kind=latency, the maximum duration of the request, like 300ms
+	MaxLatency string `json:"max_latency,omitempty" mapstructure:"max_latency"`
+}
+
+// The parts of the response which assertions are run against
+type assertionResponse struct {
+	statusCode  int
+	headers     http.Header
+	contentType string
+	body        []byte
+	latency     time.Duration
+	// lazily unmarshalled
+	json    interface{}
+	jsonErr error
+	parsed  bool
+}
+
+func (r *assertionResponse) JSON() (interface{}, error) {
+	if !r.parsed {
+		r.parsed = true
+		r.jsonErr = json.Unmarshal(r.body, &r.json)
+	}
+	return r.json, r.jsonErr
+}
+
+func hasStatusAssertion(assertions []Assertion) bool {
+	for _, a := range assertions {
+		if a.Kind == AssertStatus {
+			return true
+		}
+	}
+	return false
+}
+
+func (a Assertion) ErrorType() ErrorType {
+	name := a.Name
+	if name == "" {
+		name = string(a.Kind)
+	}
+	return ErrorType(fmt.Sprintf("%s-%s", AssertionFailed, name))
+}
+
+// Validate checks that the assertion is usable, for instance that the regexes compile.
+func (a Assertion) Validate() error {
+	switch a.Kind {
+	case AssertStatus:
+		if len(a.Status) == 0 {
+			return fmt.Errorf("assertion %s: status is required", a.ErrorType())
+		}
+	case AssertHeader:
+		if a.Header == "" {
+			return fmt.Errorf("assertion %s: header is required", a.ErrorType())
+		}
+		// Otherwise it would always pass
+		if len(a.Equals) == 0 && a.Matches == "" {
+			return fmt.Errorf("assertion %s: equals or matches is required", a.ErrorType())
+		}
+	case AssertBody:
+		if a.Matches == "" {
+			return fmt.Errorf("assertion %s: matches is required", a.ErrorType())
+		}
+	case AssertJmesPath:
+		if _, err := compileJmesPath(a.JmesPath); err != nil {
+			return fmt.Errorf("assertion %s: %w", a.ErrorType(), err)
+		}
+		if a.Exists == nil && len(a.Equals) == 0 && a.Length == nil {
+			return fmt.Errorf("assertion %s: exists, equals or length is required", a.ErrorType())
+		}
+	case AssertSize:
+		if a.MinSize == 0 && a.MaxSize == 0 {
+			return fmt.Errorf("assertion %s: min_size or max_size is required", a.ErrorType())
+		}
+	case AssertLatency:
+		if _, err := time.ParseDuration(a.MaxLatency); err != nil {
+			return fmt.Errorf("assertion %s: max_latency: %w", a.ErrorType(), err)
+		}
+	default:
+		return fmt.Errorf("assertion %s: unknown kind '%s'", a.ErrorType(), a.Kind)
+	}
+	if a.Matches != "" {
+		if _, err := compileRegex(a.Matches); err != nil {
+			return fmt.Errorf("assertion %s: %w", a.ErrorType(), err)
+		}
+	}
+	if _, err := a.equals(); err != nil {
+		return fmt.Errorf("assertion %s: equals: %w", a.ErrorType(), err)
+	}
+	return nil
+}
+
+// The decoded value of Equals, or nil if it is not set
+func (a Assertion) equals() (interface{}, error) {
+	if len(a.Equals) == 0 {
+		return nil, nil
+	}
+	var v interface{}
+	err := json.Unmarshal(a.Equals, &v)
+	return v, err
+}
+
+func ValidateAssertions(assertions []Assertion) error {
+	for _, a := range assertions {
+		if err := a.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// check returns an error describing the failure, or nil if the assertion passed.
+func (a Assertion) check(r *assertionResponse) error {
+	switch a.Kind {
+	case AssertStatus:
+		for _, s := range a.Status {
+			if s == r.statusCode {
+				return nil
+			}
+		}
+		return fmt.Errorf("expected status to be one of %v, got %d", a.Status, r.statusCode)
+	case AssertHeader:
+		value := r.headers.Get(a.Header)
+		equals, err := a.equals()
+		if err != nil {
+			return err
+		}
+		if equals != nil && fmt.Sprintf("%v", equals) != value {
+			return fmt.Errorf("expected header %s to equal '%v', got '%s'", a.Header, equals, value)
+		}
+		return a.checkMatches("header "+a.Header, []byte(value))
+	case AssertBody:
+		return a.checkMatches("body", r.body)
+	case AssertJmesPath:
+		JSON, err := r.JSON()
+		if err != nil {
+			return fmt.Errorf("expected body to be json: %w", err)
+		}
+		jp, err := compileJmesPath(a.JmesPath)
+		if err != nil {
+			return err
+		}
+		result, err := jp.Search(JSON)
+		if err != nil {
+			return fmt.Errorf("jmes-path %s failed: %w", a.JmesPath, err)
+		}
+		if a.Exists != nil && *a.Exists != (result != nil) {
+			if *a.Exists {
+				return fmt.Errorf("expected %s to exist", a.JmesPath)
+			}
+			return fmt.Errorf("expected %s to not exist, got %v", a.JmesPath, result)
+		}
+		equals, err := a.equals()
+		if err != nil {
+			return err
+		}
+		if equals != nil && !jsonEqual(equals, result) {
+			return fmt.Errorf("expected %s to equal %v, got %v", a.JmesPath, equals, result)
+		}
+		if a.Length != nil {
+			length := -1
+			if result != nil {
+				switch reflect.TypeOf(result).Kind() {
+				case reflect.Slice, reflect.Map, reflect.String:
+					length = reflect.ValueOf(result).Len()
+				}
+			}
+			if length != *a.Length {
+				return fmt.Errorf("expected %s to have length %d, got %d", a.JmesPath, *a.Length, length)
+			}
+		}
+	case AssertSize:
+		size := len(r.body)
+		if size < a.MinSize {
+			return fmt.Errorf("expected body to be at least %d bytes, got %d", a.MinSize, size)
+		}
+		if a.MaxSize > 0 && size > a.MaxSize {
+			return fmt.Errorf("expected body to be at most %d bytes, got %d", a.MaxSize, size)
+		}
+	case AssertLatency:
+		max, err := time.ParseDuration(a.MaxLatency)
+		if err != nil {
+			return err
+		}
+		if r.latency > max {
+			return fmt.Errorf("expected latency to be under %s, got %s", max, r.latency)
+		}
+	}
+	return nil
+}
+
+func (a Assertion) checkMatches(what string, value []byte) error {
+	if a.Matches == "" {
+		return nil
+	}
+	re, err := compileRegex(a.Matches)
+	if err != nil {
+		return err
+	}
+	if !re.Match(value) {
+		return fmt.Errorf("expected %s to match %s", what, a.Matches)
+	}
+	return nil
+}
+
+// Runs the assertions in order, returning the first failure
+func checkAssertions(assertions []Assertion, r *assertionResponse) (ErrorType, error) {
+	for _, a := range assertions {
+		if err := a.check(r); err != nil {
+			return a.ErrorType(), fmt.Errorf("assertion failed: %w", err)
+		}
+	}
+	return "", nil
+}
+
+// Values from yaml/json may differ in numeric types, so they are compared by their json-representation.
+func jsonEqual(a, b interface{}) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	if errA != nil || errB != nil {
+		return reflect.DeepEqual(a, b)
+	}
+	if string(ja) == string(jb) {
+		return true
+	}
+	// Normalize numbers, like 1 and 1.0
+	var na, nb interface{}
+	if json.Unmarshal(ja, &na) != nil || json.Unmarshal(jb, &nb) != nil {
+		return false
+	}
+	return reflect.DeepEqual(na, nb)
+}
+
+// Regexes and jmes-paths are evaluated for every request, so they are compiled once.
+var (
+	regexCache    = map[string]*regexp.Regexp{}
+	jmesPathCache = map[string]*jmespath.JMESPath{}
+	compileLock   sync.RWMutex
+)
+
+func compileRegex(s string) (*regexp.Regexp, error) {
+	compileLock.RLock()
+	re, ok := regexCache[s]
+	compileLock.RUnlock()
+	if ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(s)
+	if err != nil {
+		return nil, err
+	}
+	compileLock.Lock()
+	regexCache[s] = re
+	compileLock.Unlock()
+	return re, nil
+}
+
+func compileJmesPath(s string) (*jmespath.JMESPath, error) {
+	if s == "" {
+		return nil, fmt.Errorf("jmes-path is required")
+	}
+	compileLock.RLock()
+	jp, ok := jmesPathCache[s]
+	compileLock.RUnlock()
+	if ok {
+		return jp, nil
+	}
+	jp, err := jmespath.Compile(s)
+	if err != nil {
+		return nil, err
+	}
+	compileLock.Lock()
+	jmesPathCache[s] = jp
+	compileLock.Unlock()
+	return jp, nil
+}
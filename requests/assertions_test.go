@@ -0,0 +1,137 @@
+package requests
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/runar-rkmedia/gabyoall/logger"
+)
+
+func TestCheckAssertions(t *testing.T) {
+	yes := true
+	no := false
+	two := 2
+	response := assertionResponse{
+		statusCode:  200,
+		headers:     http.Header{"Content-Type": []string{"application/json; charset=utf-8"}},
+		contentType: "application/json; charset=utf-8",
+		body:        []byte(`{"data": {"user": {"id": 1, "name": "john", "roles": ["admin", "user"]}}}`),
+		latency:     time.Millisecond * 120,
+	}
+	tests := []struct {
+		name       string
+		assertions []Assertion
+		want       ErrorType
+	}{
+		{"status in set", []Assertion{{Kind: AssertStatus, Status: []int{200, 201}}}, ""},
+		{"status not in set", []Assertion{{Kind: AssertStatus, Status: []int{201}}}, "AssertionFailed-status"},
+		{"header matches", []Assertion{{Kind: AssertHeader, Header: "content-type", Matches: "^application/json"}}, ""},
+		{"header equals", []Assertion{{Name: "ct", Kind: AssertHeader, Header: "Content-Type", Equals: json.RawMessage(`"application/json"`)}}, "AssertionFailed-ct"},
+		{"body matches", []Assertion{{Kind: AssertBody, Matches: `"name": "john"`}}, ""},
+		{"body does not match", []Assertion{{Kind: AssertBody, Matches: `"errors"`}}, "AssertionFailed-body"},
+		{"jmes-path equals number", []Assertion{{Kind: AssertJmesPath, JmesPath: "data.user.id", Equals: json.RawMessage(`1`)}}, ""},
+		{"jmes-path equals object", []Assertion{{Kind: AssertJmesPath, JmesPath: "data.user.roles", Equals: json.RawMessage(`["admin", "user"]`)}}, ""},
+		{"jmes-path not equal", []Assertion{{Kind: AssertJmesPath, JmesPath: "data.user.name", Equals: json.RawMessage(`"jane"`)}}, "AssertionFailed-jmes-path"},
+		{"jmes-path exists", []Assertion{{Kind: AssertJmesPath, JmesPath: "data.user", Exists: &yes}}, ""},
+		{"jmes-path does not exist", []Assertion{{Kind: AssertJmesPath, JmesPath: "errors", Exists: &no}}, ""},
+		{"jmes-path missing", []Assertion{{Kind: AssertJmesPath, JmesPath: "data.files", Exists: &yes}}, "AssertionFailed-jmes-path"},
+		{"jmes-path length", []Assertion{{Kind: AssertJmesPath, JmesPath: "data.user.roles", Length: &two}}, ""},
+		{"jmes-path wrong length", []Assertion{{Kind: AssertJmesPath, JmesPath: "data.user", Length: &two}}, "AssertionFailed-jmes-path"},
+		{"size within bounds", []Assertion{{Kind: AssertSize, MinSize: 10, MaxSize: 1000}}, ""},
+		{"size too large", []Assertion{{Kind: AssertSize, MaxSize: 10}}, "AssertionFailed-size"},
+		{"latency under", []Assertion{{Kind: AssertLatency, MaxLatency: "300ms"}}, ""},
+		{"latency over", []Assertion{{Kind: AssertLatency, MaxLatency: "100ms"}}, "AssertionFailed-latency"},
+		{
+			"first failing assertion wins",
+			[]Assertion{
+				{Kind: AssertStatus, Status: []int{200}},
+				{Name: "slow", Kind: AssertLatency, MaxLatency: "1ms"},
+				{Kind: AssertSize, MaxSize: 1},
+			},
+			"AssertionFailed-slow",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateAssertions(tt.assertions); err != nil {
+				t.Fatalf("ValidateAssertions() error = %v", err)
+			}
+			r := response
+			got, err := checkAssertions(tt.assertions, &r)
+			if got != tt.want {
+				t.Errorf("checkAssertions() = %v, want %v (err: %v)", got, tt.want, err)
+			}
+			if (err != nil) != (tt.want != "") {
+				t.Errorf("checkAssertions() error = %v, want error: %v", err, tt.want != "")
+			}
+		})
+	}
+}
+
+type statusClient int
+
+func (c statusClient) Do(req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: int(c),
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(bytes.NewBufferString(`{"data": {}}`)),
+	}, nil
+}
+
+func TestEndpoint_StatusAssertion(t *testing.T) {
+	tests := []struct {
+		name          string
+		statusCode    int
+		okStatusCodes []int
+		assertions    []Assertion
+		want          ErrorType
+	}{
+		{"non-ok without assertion", 404, nil, nil, "NonOK-404"},
+		{"assertion accepts non-ok", 404, nil, []Assertion{{Kind: AssertStatus, Status: []int{404}}}, ""},
+		{"assertion overrides okStatusCodes", 404, []int{200}, []Assertion{{Kind: AssertStatus, Status: []int{404}}}, ""},
+		{"failing assertion", 500, nil, []Assertion{{Kind: AssertStatus, Status: []int{200}}}, "AssertionFailed-status"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := NewTimeSeriesWithLabel(time.Now())
+			g := Endpoint{
+				Url:     "http://localhost",
+				Headers: http.Header{},
+				ts:      &ts,
+				l:       logger.GetLogger("test"),
+				client:  statusClient(tt.statusCode),
+			}
+			_, stat, _ := g.RunQuery(time.Now(), Request{Query: "{ user { name } }", Assertions: tt.assertions}, tt.okStatusCodes, nil)
+			if stat.ErrorType != tt.want {
+				t.Errorf("RunQuery() = %q, want %q", stat.ErrorType, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateAssertions(t *testing.T) {
+	tests := []struct {
+		name      string
+		assertion Assertion
+	}{
+		{"unknown kind", Assertion{Kind: "foo"}},
+		{"status without codes", Assertion{Kind: AssertStatus}},
+		{"invalid regex", Assertion{Kind: AssertBody, Matches: "("}},
+		{"invalid jmes-path", Assertion{Kind: AssertJmesPath, JmesPath: "data.["}},
+		{"header without a check", Assertion{Kind: AssertHeader, Header: "Content-Type"}},
+		{"jmes-path without a check", Assertion{Kind: AssertJmesPath, JmesPath: "data.user"}},
+		{"invalid equals", Assertion{Kind: AssertJmesPath, JmesPath: "data.user", Equals: json.RawMessage(`{`)}},
+		{"invalid latency", Assertion{Kind: AssertLatency, MaxLatency: "fast"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.assertion.Validate(); err == nil {
+				t.Errorf("Validate() expected error for %#v", tt.assertion)
+			}
+		})
+	}
+}
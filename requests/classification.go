@@ -0,0 +1,212 @@
+package requests
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"strings"
+	"syscall"
+)
+
+// Kinds of errors which can occur while performing the request, before any response is received.
+const (
+	ErrorKindTimeout           = "timeout"
+	ErrorKindConnectionRefused = "connection-refused"
+	ErrorKindConnectionReset   = "connection-reset"
+	ErrorKindDNS               = "dns"
+	ErrorKindTLS               = "tls"
+	ErrorKindEOF               = "eof"
+)
+
+// ClassificationRule puts failed requests into a named bucket (ErrorType).
+// All of the conditions which are set must match. Rules are evaluated in order, and the first matching rule wins.
+type ClassificationRule struct {
+	// Name of the bucket. Capture-groups from MessageRegex can be used, like `NotFound-${kind}` or `NotFound-$1`
+	// Required: true
+	Name string `json:"name"`
+	// Matches if the status-code is one of these
+	StatusCodes []int `json:"status_codes,omitempty" mapstructure:"status_codes"`
+	// Regular expression matched against the error-message, which for GraphQL is the message of the first error.
+	MessageRegex string `json:"message_regex,omitempty" mapstructure:"message_regex"`
+	// Matches if any of the GraphQL-errors has this extensions.code
+	GqlCode string `json:"gql_code,omitempty" mapstructure:"gql_code"`
+	// Matches if the jmes-path-expression on the json-body returns a truthy value
+	JmesPath string `json:"jmes_path,omitempty" mapstructure:"jmes_path"`
+	// Matches errors from performing the request, like timeout, connection-refused, connection-reset, dns, tls or eof
+	ErrorKind string `json:"error_kind,omitempty" mapstructure:"error_kind"`
+}
+
+// The details of a failed request, which the classification-rules are matched against
+type failure struct {
+	// Used if no rule matches
+	errorType  ErrorType
+	err        error
+	statusCode int
+	message    string
+	gqlErrors  []Error
+	body       []byte
+}
+
+func (r ClassificationRule) Validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("classification-rule: name is required")
+	}
+	if len(r.StatusCodes) == 0 && r.MessageRegex == "" && r.GqlCode == "" && r.JmesPath == "" && r.ErrorKind == "" {
+		return fmt.Errorf("classification-rule %s: at least one condition is required", r.Name)
+	}
+	if r.MessageRegex != "" {
+		if _, err := compileRegex(r.MessageRegex); err != nil {
+			return fmt.Errorf("classification-rule %s: %w", r.Name, err)
+		}
+	}
+	if r.JmesPath != "" {
+		if _, err := compileJmesPath(r.JmesPath); err != nil {
+			return fmt.Errorf("classification-rule %s: %w", r.Name, err)
+		}
+	}
+	switch r.ErrorKind {
+	case "", ErrorKindTimeout, ErrorKindConnectionRefused, ErrorKindConnectionReset, ErrorKindDNS, ErrorKindTLS, ErrorKindEOF:
+	default:
+		return fmt.Errorf("classification-rule %s: unknown error_kind '%s'", r.Name, r.ErrorKind)
+	}
+	return nil
+}
+
+func ValidateClassificationRules(rules []ClassificationRule) error {
+	for _, r := range rules {
+		if err := r.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns the ErrorType of the first matching rule
+func classify(rules []ClassificationRule, f failure) (ErrorType, bool) {
+	var JSON interface{}
+	parsed := false
+	for _, r := range rules {
+		if len(r.StatusCodes) > 0 {
+			ok := false
+			for _, s := range r.StatusCodes {
+				if s == f.statusCode {
+					ok = true
+					break
+				}
+			}
+			if !ok {
+				continue
+			}
+		}
+		if r.ErrorKind != "" && r.ErrorKind != errorKind(f.err) {
+			continue
+		}
+		if r.GqlCode != "" {
+			ok := false
+			for _, e := range f.gqlErrors {
+				if code, _ := e.Extensions["code"].(string); code == r.GqlCode {
+					ok = true
+					break
+				}
+			}
+			if !ok {
+				continue
+			}
+		}
+		if r.JmesPath != "" {
+			if !parsed {
+				parsed = true
+				if err := json.Unmarshal(f.body, &JSON); err != nil {
+					JSON = nil
+				}
+			}
+			if JSON == nil {
+				continue
+			}
+			jp, err := compileJmesPath(r.JmesPath)
+			if err != nil {
+				continue
+			}
+			result, err := jp.Search(JSON)
+			if err != nil || !isTruthy(result) {
+				continue
+			}
+		}
+		name := r.Name
+		if r.MessageRegex != "" {
+			re, err := compileRegex(r.MessageRegex)
+			if err != nil {
+				continue
+			}
+			match := re.FindStringSubmatchIndex(f.message)
+			if match == nil {
+				continue
+			}
+			name = string(re.ExpandString(nil, r.Name, f.message, match))
+		}
+		return ErrorType(name), true
+	}
+	return "", false
+}
+
+// Same semantics as in jmes-path, where empty values are falsy
+func isTruthy(v interface{}) bool {
+	if v == nil {
+		return false
+	}
+	if b, ok := v.(bool); ok {
+		return b
+	}
+	switch reflect.TypeOf(v).Kind() {
+	case reflect.Slice, reflect.Map, reflect.String:
+		return reflect.ValueOf(v).Len() > 0
+	}
+	return true
+}
+
+// errorKind returns the kind of error which occurred while performing the request, if any is recognized.
+func errorKind(err error) string {
+	if err == nil {
+		return ""
+	}
+	var netErr net.Error
+	var dnsErr *net.DNSError
+	var recordErr tls.RecordHeaderError
+	var unknownAuthErr x509.UnknownAuthorityError
+	var certErr x509.CertificateInvalidError
+	var hostnameErr x509.HostnameError
+	switch {
+	case errors.As(err, &dnsErr):
+		return ErrorKindDNS
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return ErrorKindTimeout
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return ErrorKindConnectionRefused
+	case errors.Is(err, syscall.ECONNRESET):
+		return ErrorKindConnectionReset
+	case errors.As(err, &recordErr), errors.As(err, &unknownAuthErr), errors.As(err, &certErr), errors.As(err, &hostnameErr),
+		strings.Contains(err.Error(), "tls: "):
+		return ErrorKindTLS
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return ErrorKindEOF
+	}
+	return ""
+}
+
+// Ends the stat with the ErrorType of the first matching classification-rule, or the default ErrorType
+func (g *Endpoint) fail(stat RequestStat, f failure) RequestStat {
+	errorType := f.errorType
+	if f.message == "" && f.err != nil {
+		f.message = f.err.Error()
+	}
+	if t, ok := classify(g.ClassificationRules, f); ok {
+		errorType = t
+	}
+	return stat.End(f.body, errorType, f.err)
+}
@@ -0,0 +1,48 @@
+package requests
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	rules := []ClassificationRule{
+		{Name: "NotFound-$1", MessageRegex: `^(\w+) with id [\w-]+ not found`},
+		{Name: "Unauthenticated", GqlCode: "UNAUTHENTICATED"},
+		{Name: "RateLimited", StatusCodes: []int{429, 503}, JmesPath: "error.retryAfter"},
+		{Name: "ServiceUnavailable", StatusCodes: []int{503}},
+		{Name: "Refused", ErrorKind: ErrorKindConnectionRefused},
+		{Name: "Timeout", ErrorKind: ErrorKindTimeout},
+	}
+	connRefused := &url.Error{Op: "Post", URL: "http://localhost", Err: &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}}
+	tests := []struct {
+		name    string
+		failure failure
+		want    ErrorType
+		wantOk  bool
+	}{
+		{"capture-groups in the name", failure{message: "File with id 4f2a-11 not found"}, "NotFound-File", true},
+		{"graphql extensions.code", failure{message: "Access denied", gqlErrors: []Error{{Message: "Access denied"}, {Message: "Who?", Extensions: map[string]interface{}{"code": "UNAUTHENTICATED"}}}}, "Unauthenticated", true},
+		{"status and jmes-path", failure{statusCode: 503, body: []byte(`{"error": {"retryAfter": 3}}`)}, "RateLimited", true},
+		{"status only, jmes-path is falsy", failure{statusCode: 503, body: []byte(`{"error": {}}`)}, "ServiceUnavailable", true},
+		{"connection refused", failure{err: connRefused}, "Refused", true},
+		{"timeout", failure{err: fmt.Errorf("request: %w", context.DeadlineExceeded)}, "Timeout", true},
+		{"no match", failure{statusCode: 500, message: "Internal error"}, "", false},
+	}
+	if err := ValidateClassificationRules(rules); err != nil {
+		t.Fatalf("ValidateClassificationRules() error = %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := classify(rules, tt.failure)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("classify() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
-	"regexp"
 	"strings"
 	"time"
 
@@ -20,9 +19,37 @@ type CompactRequestStatistics struct {
 	CompletedRequests int `json:"completed_requests`
 	RunID             string
 	TimeSeries        *TimeSeriesMap
-	ResponseHashMap   ByteHashMap `json:"response_hash_map,omitempty"`
-	Requests          map[ErrorType]CompactStat
-	// TODO: Implement streaming Average,p99 etc
+	// Completed requests, bytes received and requests in flight, per second
+	Throughput      *ThroughputSeries `json:"throughput,omitempty"`
+	ResponseHashMap ByteHashMap       `json:"response_hash_map,omitempty"`
+	// Violations of the ResponseSchema, by the hash of the response
+	SchemaViolations SchemaViolationMap `json:"schema_violations,omitempty"`
+	// Every violation of the ResponseSchema, by pointer
+	SchemaViolationBreakdown SchemaViolationBreakdown `json:"schema_violation_breakdown"`
+	// Number of responses which did not match the golden response of the request
+	GoldenMismatches int `json:"golden_mismatches,omitempty"`
+	// Differences from the golden response, by the hash of the response
+	GoldenDiffs GoldenDiffMap `json:"golden_diffs,omitempty"`
+	// GraphQL-errors by path and code, including those of responses with accepted partial data
+	GqlErrors GqlErrorBreakdown `json:"gql_errors"`
+	Requests  map[ErrorType]CompactStat
+	// Latencies of all requests
+	Histogram *Histogram `json:"histogram"`
+	// Latencies by ErrorType
+	Histograms map[ErrorType]*Histogram `json:"histograms"`
+	// Percentiles by ErrorType. The overall percentiles are in Stats.
+	Percentiles map[ErrorType]Percentiles `json:"percentiles"`
+	// The slowest requests, and samples of the failed requests, with full details
+	Samples *Samples `json:"samples,omitempty"`
+	// Set if the run was started by a schedule
+	ScheduleID string `json:"schedule_id,omitempty"`
+	// Title of the run, like the url and operation-name
+	Title string `json:"title,omitempty"`
+	// The config which the run used, with its secrets redacted
+	Config json.RawMessage `json:"config,omitempty"`
+	// The score of the run against its SLO, if any
+	SLO *SLOResult `json:"slo,omitempty"`
+	slo *SLO
 }
 
 type TimeSeriePusher interface {
@@ -36,18 +63,14 @@ type CompactStat struct {
 	// TODO: make requestIDS just a range like 'gobyoall-<RunID>-<WORKER#1>'
 }
 
-// should match uuids, and the request-id that this project creates for each request.
-var requestIdRegex = regexp.MustCompile(`([a-fA-F0-9-]{25,32}|srv-test-[-_\w]*)`)
-
-func hash256(content []byte) *[32]byte {
+// The content is normalized before hashing, so that responses which only differ in unique values,
+// like request-ids, still hash to the same value.
+func hash256(content []byte, rules []NormalizationRule) *[32]byte {
 	if len(content) == 0 {
 		return nil
 	}
 	s := sha256.New()
-	// TODO: this should probably be a setting on endpoint/request.
-	// The point is to somehow ignore any references to unique request ids so that the same response
-	// even though they contain unique request-ids still hashes to the
-	normalized := requestIdRegex.ReplaceAll(content, []byte("__UID__"))
+	normalized := normalize(content, rules)
 	s.Write([]byte(normalized))
 	sum := s.Sum(nil)
 	c := (*[32]byte)(sum)
@@ -63,25 +86,41 @@ func (rs *CompactRequestStatistics) AddStat(stat RequestStat) {
 
 	if len(stat.RawResponse) > 0 {
 		body := stat.RawResponse
-		bodyHash := hash256(body)
+		bodyHash := hash256(body, stat.Normalization)
 		if bodyHash != nil {
 			rs.ResponseHashMap[*bodyHash] = ByteContent{
 				Content:     body,
 				ContentType: stat.ContentType,
+				Count:       rs.ResponseHashMap[*bodyHash].Count + 1,
 			}
 			h := Hash(*bodyHash)
 			s.ResponseHash = &h
+			rs.SchemaViolations.Add(h, stat.SchemaViolations)
+			rs.GoldenDiffs.Add(h, stat.GoldenDiff)
 		}
 	}
-	if stat.Duration > rs.Max {
-		rs.Max = stat.Duration
+	if stat.ErrorType == GoldenMismatch {
+		rs.GoldenMismatches++
 	}
-	if stat.Duration < rs.Min {
-		rs.Min = stat.Duration
+	rs.SchemaViolationBreakdown.Add(stat.SchemaViolations)
+	rs.GqlErrors.Add(stat.GqlErrors, stat.PartialData)
+	rs.Stats.Add(stat.Duration)
+	rs.Histogram.Record(stat.Duration)
+	h, ok := rs.Histograms[stat.ErrorType]
+	if !ok {
+		h = NewHistogram()
+		rs.Histograms[stat.ErrorType] = h
 	}
-	rs.Total += stat.Duration
+	h.Record(stat.Duration)
+	rs.Samples.Add(stat)
 	// rs.Requests[stat.RequestID] = s
 }
+
+// SetSLO sets the objective which the run is scored against in Calculate
+func (rs *CompactRequestStatistics) SetSLO(slo *SLO) {
+	rs.slo = slo
+}
+
 func (rs *CompactRequestStatistics) RecalculateAll() {
 	// to offset the min-calculation
 	// rs.Min = 100 * time.Hour
@@ -97,11 +136,15 @@ func (rs *CompactRequestStatistics) RecalculateAll() {
 	rs.Calculate()
 }
 func (rs *CompactRequestStatistics) Calculate() {
-	length := len(rs.Requests)
-	if length == 0 {
+	if rs.Histogram.Count() == 0 {
 		return
 	}
-	rs.Average = rs.Total / time.Duration(length)
+	rs.Stats.SetPercentiles(rs.Histogram.Percentiles())
+	rs.SLO = rs.slo.Evaluate(rs.Histograms)
+	rs.Percentiles = make(map[ErrorType]Percentiles, len(rs.Histograms))
+	for errorType, h := range rs.Histograms {
+		rs.Percentiles[errorType] = h.Percentiles()
+	}
 }
 
 type Hash [32]byte
@@ -110,35 +153,50 @@ type ByteHashMap map[[32]byte]ByteContent
 type ByteContent struct {
 	Content     []byte `json:"content,omitempty"`
 	ContentType string `json:"contentType,omitempty"`
+	// Number of responses with this hash
+	Count int `json:"count,omitempty"`
 }
 
-func (bm ByteHashMap) Add(contentType string, body []byte) *Hash {
+// Add stores the body by its hash, after normalizing it with the rules.
+func (bm ByteHashMap) Add(contentType string, body []byte, rules []NormalizationRule) *Hash {
 	if len(body) == 0 {
 		return nil
 	}
 
-	bodyHash := hash256(body)
+	bodyHash := hash256(body, rules)
 	if bodyHash == nil {
 		return nil
 	}
 	bm[*bodyHash] = ByteContent{
 		Content:     body,
 		ContentType: contentType,
+		Count:       bm[*bodyHash].Count + 1,
 	}
 	h := Hash(*bodyHash)
 	return &h
 }
 
 func NewCompactRequestStatistics(runID string, ts *TimeSeriesMap) CompactRequestStatistics {
+	var throughput *ThroughputSeries
+	if ts != nil {
+		throughput = ts.Throughput
+	}
 	return CompactRequestStatistics{
-		StartTime: time.Now(),
-		RunID:     runID,
+		Throughput: throughput,
+		StartTime:  time.Now(),
+		RunID:      runID,
 		Stats: Stats{
 			Min: time.Hour * 100,
 		},
-		TimeSeries:      ts,
-		ResponseHashMap: ByteHashMap{},
-		Requests:        map[ErrorType]CompactStat{},
+		TimeSeries:       ts,
+		ResponseHashMap:  ByteHashMap{},
+		SchemaViolations: SchemaViolationMap{},
+		GoldenDiffs:      GoldenDiffMap{},
+		Histogram:        NewHistogram(),
+		Histograms:       map[ErrorType]*Histogram{},
+		Percentiles:      map[ErrorType]Percentiles{},
+		Samples:          NewSamples(DefaultSampleSize),
+		Requests:         map[ErrorType]CompactStat{},
 	}
 }
 
@@ -153,8 +211,10 @@ func (c ByteContent) MarshalJSON() ([]byte, error) {
 	t := struct {
 		Content     interface{} `json:"content,omitempty"`
 		ContentType string      `json:"contentType,omitempty"`
+		Count       int         `json:"count,omitempty"`
 	}{
 		ContentType: string(c.ContentType),
+		Count:       c.Count,
 	}
 	switch {
 	case c.ContentType == "application/json", strings.Contains(c.ContentType, "json"):
@@ -187,3 +247,59 @@ func (c ByteContent) MarshalJSON() ([]byte, error) {
 func (c Hash) MarshalJSON() ([]byte, error) {
 	return []byte(`"` + base64.URLEncoding.EncodeToString(c[:]) + `"`), nil
 }
+
+func (c *Hash) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	h, err := ParseHash(s)
+	if err != nil {
+		return err
+	}
+	*c = h
+	return nil
+}
+
+func (c *ByteHashMap) UnmarshalJSON(b []byte) error {
+	var u map[string]ByteContent
+	if err := json.Unmarshal(b, &u); err != nil {
+		return err
+	}
+	m := ByteHashMap{}
+	for k, v := range u {
+		h, err := ParseHash(k)
+		if err != nil {
+			return err
+		}
+		m[h] = v
+	}
+	*c = m
+	return nil
+}
+
+// UnmarshalJSON reads the content as it was written by MarshalJSON.
+// Structured content is kept as json, regardless of the original content-type.
+func (c *ByteContent) UnmarshalJSON(b []byte) error {
+	var t struct {
+		Content     json.RawMessage `json:"content,omitempty"`
+		ContentType string          `json:"contentType,omitempty"`
+		Count       int             `json:"count,omitempty"`
+	}
+	if err := json.Unmarshal(b, &t); err != nil {
+		return err
+	}
+	c.ContentType = t.ContentType
+	c.Count = t.Count
+	c.Content = nil
+	if len(t.Content) == 0 {
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(t.Content, &s); err == nil {
+		c.Content = []byte(s)
+		return nil
+	}
+	c.Content = []byte(t.Content)
+	return nil
+}
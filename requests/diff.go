@@ -0,0 +1,157 @@
+package requests
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/r3labs/diff/v2"
+)
+
+// ResponseDiff is the structured difference between two responses in a ByteHashMap
+type ResponseDiff struct {
+	From    Hash           `json:"from"`
+	To      Hash           `json:"to"`
+	Changes diff.Changelog `json:"changes"`
+}
+
+// VariantCluster is a group of response-variants which differ from the reference-variant in the same json-paths.
+type VariantCluster struct {
+	// The json-paths which differ from the reference, like data.user.email
+	Paths []string `json:"paths"`
+	// The variants within the cluster
+	Hashes []Hash `json:"hashes"`
+	// Number of responses of the variants within the cluster
+	Count int `json:"count"`
+}
+
+// VariantSummary clusters all the variants in a ByteHashMap by how they differ from the most common variant.
+type VariantSummary struct {
+	// The most common variant, which the others are compared with
+	Reference      Hash             `json:"reference"`
+	ReferenceCount int              `json:"referenceCount"`
+	Clusters       []VariantCluster `json:"clusters"`
+}
+
+// ParseHash parses a hash as written in json, like in the ResponseHashMap
+func ParseHash(s string) (Hash, error) {
+	var h Hash
+	b, err := base64.URLEncoding.DecodeString(s)
+	if err != nil {
+		return h, fmt.Errorf("hash '%s' is not valid base64: %w", s, err)
+	}
+	if len(b) != len(h) {
+		return h, fmt.Errorf("hash '%s' has the wrong length, expected %d bytes, got %d", s, len(h), len(b))
+	}
+	copy(h[:], b)
+	return h, nil
+}
+
+func (c Hash) String() string {
+	return base64.URLEncoding.EncodeToString(c[:])
+}
+
+// The value which is diffed. Json is compared structurally, anything else as a string.
+func (c ByteContent) diffValue() interface{} {
+	var j interface{}
+	if strings.Contains(c.ContentType, "json") || json.Valid(c.Content) {
+		if err := json.Unmarshal(c.Content, &j); err == nil {
+			return j
+		}
+	}
+	return string(c.Content)
+}
+
+// Diff returns the structured difference between the responses with the hashes a and b
+func (bm ByteHashMap) Diff(a, b Hash) (ResponseDiff, error) {
+	d := ResponseDiff{From: a, To: b}
+	from, ok := bm[a]
+	if !ok {
+		return d, fmt.Errorf("hash %s was not found", a)
+	}
+	to, ok := bm[b]
+	if !ok {
+		return d, fmt.Errorf("hash %s was not found", b)
+	}
+	changes, err := diffValues(from.diffValue(), to.diffValue())
+	d.Changes = changes
+	return d, err
+}
+
+func diffValues(a, b interface{}) (diff.Changelog, error) {
+	// The diff-library does not handle values of different types, like a string and a map.
+	if fmt.Sprintf("%T", a) != fmt.Sprintf("%T", b) {
+		return diff.Changelog{{Type: diff.UPDATE, Path: []string{}, From: a, To: b}}, nil
+	}
+	changes, err := diff.Diff(a, b, diff.SliceOrdering(true))
+	if changes == nil {
+		changes = diff.Changelog{}
+	}
+	return changes, err
+}
+
+// Summarize clusters all variants by which json-paths differ from the most common variant.
+func (bm ByteHashMap) Summarize() (VariantSummary, error) {
+	s := VariantSummary{Clusters: []VariantCluster{}}
+	if len(bm) == 0 {
+		return s, nil
+	}
+	hashes := make([]Hash, 0, len(bm))
+	for k := range bm {
+		hashes = append(hashes, Hash(k))
+	}
+	// Most common first, and then by hash for stable output
+	sort.Slice(hashes, func(i, j int) bool {
+		ci, cj := bm[hashes[i]].Count, bm[hashes[j]].Count
+		if ci != cj {
+			return ci > cj
+		}
+		return hashes[i].String() < hashes[j].String()
+	})
+	s.Reference = hashes[0]
+	s.ReferenceCount = bm[s.Reference].Count
+	reference := bm[s.Reference].diffValue()
+	clusters := map[string]*VariantCluster{}
+	var keys []string
+	for _, h := range hashes[1:] {
+		changes, err := diffValues(reference, bm[h].diffValue())
+		if err != nil {
+			return s, fmt.Errorf("failed to diff %s: %w", h, err)
+		}
+		paths := changedPaths(changes)
+		key := strings.Join(paths, "\n")
+		c, ok := clusters[key]
+		if !ok {
+			c = &VariantCluster{Paths: paths}
+			clusters[key] = c
+			keys = append(keys, key)
+		}
+		c.Hashes = append(c.Hashes, h)
+		c.Count += bm[h].Count
+	}
+	for _, k := range keys {
+		s.Clusters = append(s.Clusters, *clusters[k])
+	}
+	sort.SliceStable(s.Clusters, func(i, j int) bool {
+		return s.Clusters[i].Count > s.Clusters[j].Count
+	})
+	return s, nil
+}
+
+// Returns the unique, sorted json-paths of the changes
+func changedPaths(changes diff.Changelog) []string {
+	seen := map[string]bool{}
+	paths := []string{}
+	for _, c := range changes {
+		p := strings.Join(c.Path, ".")
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
@@ -0,0 +1,95 @@
+package requests
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestByteHashMap_Summarize(t *testing.T) {
+	bm := ByteHashMap{}
+	add := func(body string, n int) Hash {
+		var h *Hash
+		for i := 0; i < n; i++ {
+			h = bm.Add("application/json", []byte(body), nil)
+		}
+		return *h
+	}
+	ref := add(`{"data": {"user": {"name": "a", "email": "a@b.c"}}}`, 5)
+	name := add(`{"data": {"user": {"name": "b", "email": "a@b.c"}}}`, 2)
+	name2 := add(`{"data": {"user": {"name": "c", "email": "a@b.c"}}}`, 2)
+	both := add(`{"data": {"user": {"name": "c", "email": "c@b.c"}}}`, 3)
+	text := add(`Bad gateway`, 1)
+
+	got, err := bm.Summarize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Reference != ref || got.ReferenceCount != 5 {
+		t.Errorf("Expected the most common variant as reference, got %s (%d)", got.Reference, got.ReferenceCount)
+	}
+	want := map[string]VariantCluster{
+		"data.user.name":                 {Paths: []string{"data.user.name"}, Count: 4, Hashes: []Hash{name, name2}},
+		"data.user.email data.user.name": {Paths: []string{"data.user.email", "data.user.name"}, Count: 3, Hashes: []Hash{both}},
+		"":                               {Paths: []string{""}, Count: 1, Hashes: []Hash{text}},
+	}
+	if len(got.Clusters) != len(want) {
+		t.Fatalf("Expected %d clusters, got %d: %#v", len(want), len(got.Clusters), got.Clusters)
+	}
+	for i, c := range got.Clusters {
+		if i > 0 && c.Count > got.Clusters[i-1].Count {
+			t.Errorf("Expected clusters to be sorted by count")
+		}
+		key := strings.Join(c.Paths, " ")
+		w, ok := want[key]
+		if !ok {
+			t.Errorf("Unexpected cluster %#v", c)
+			continue
+		}
+		if w.Count != c.Count || len(w.Hashes) != len(c.Hashes) {
+			t.Errorf("Cluster %s: got %#v, want %#v", key, c, w)
+		}
+	}
+}
+
+func TestByteHashMap_Diff(t *testing.T) {
+	bm := ByteHashMap{}
+	a := bm.Add("application/json", []byte(`{"data": {"files": [{"id": 1}, {"id": 2}], "total": 2}}`), nil)
+	b := bm.Add("application/json", []byte(`{"data": {"files": [{"id": 1}, {"id": 3}]}}`), nil)
+
+	// Round-trip through json, like when reading an output-file
+	j, err := json.Marshal(bm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var read ByteHashMap
+	if err := json.Unmarshal(j, &read); err != nil {
+		t.Fatal(err)
+	}
+	d, err := read.Diff(*a, *b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := changedPaths(d.Changes)
+	want := []string{"data.files.1.id", "data.total"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff() paths = %v, want %v", got, want)
+	}
+	if _, err := read.Diff(*a, Hash{}); err == nil {
+		t.Errorf("Expected an error for an unknown hash")
+	}
+}
+
+func TestParseHash(t *testing.T) {
+	h := Hash{1, 2, 3}
+	got, err := ParseHash(h.String())
+	if err != nil || got != h {
+		t.Errorf("ParseHash() = %v, %v, want %v", got, err, h)
+	}
+	for _, s := range []string{"", "not base64!", "AQID"} {
+		if _, err := ParseHash(s); err == nil {
+			t.Errorf("ParseHash(%s) expected error", s)
+		}
+	}
+}
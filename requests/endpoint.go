@@ -18,9 +18,19 @@ type Endpoint struct {
 	// Required: true
 	Url     string      `json:"url,omitempty"`
 	Headers http.Header `json:"headers,omitempty"`
-	ts      TimeSeriePusher
-	l       logger.AppLogger
-	client  HttpClient
+	// Rules for putting failed requests into named buckets. The first matching rule wins.
+	ClassificationRules []ClassificationRule `json:"classificationRules,omitempty"`
+	// Rules for normalizing responses before they are hashed, like dropping timestamps.
+	// If neither the endpoint nor the request has any rules, the DefaultNormalizationRule is used.
+	Normalization []NormalizationRule `json:"normalization,omitempty"`
+	// If set, GraphQL-responses with errors are still successful if they have partial data.
+	// The errors are recorded either way.
+	PartialDataOk bool `json:"partialDataOk,omitempty"`
+	// Objective which each run is scored against, unless the request has its own
+	SLO    *SLO `json:"slo,omitempty"`
+	ts     TimeSeriePusher
+	l      logger.AppLogger
+	client HttpClient
 }
 
 func NewEndpoint(l logger.AppLogger, url string, ts TimeSeriePusher) Endpoint {
@@ -72,14 +82,29 @@ type HttpClient interface {
 	Do(req *http.Request) (*http.Response, error)
 }
 
-func (g *Endpoint) RunQuery(startTime time.Time, query Request, okStatusCodes []int) (*http.Response, RequestStat, error) {
+// RunQuery performs the request. The virtual user is optional, and holds state (cookies, variables) across requests.
+func (g *Endpoint) RunQuery(startTime time.Time, query Request, okStatusCodes []int, vu *VirtualUser) (*http.Response, RequestStat, error) {
 	stat := NewStat(time.Now().Sub(startTime), g.ts)
+	if vu != nil {
+		stat.WorkerID = vu.ID
+	}
+	if len(g.Normalization) > 0 || len(query.Normalization) > 0 {
+		stat.Normalization = make([]NormalizationRule, 0, len(g.Normalization)+len(query.Normalization))
+		stat.Normalization = append(append(stat.Normalization, g.Normalization...), query.Normalization...)
+	}
 	l := logger.AppLogger{Logger: g.l.With().Str("operationName", query.OperationName).Str("endpoint", g.Url).Str("requestId", stat.RequestID).Logger()}
 	var b []byte
 	var err error
 	if query.Method == "" {
 		query.Method = http.MethodPost
 	}
+	vars := templateVars{query.Vars, vu}
+	if query.Variables != nil {
+		query.Variables = renderVariables(l, query.Variables, vars).(map[string]interface{})
+	}
+	if str, ok := query.Body.(string); ok {
+		query.Body = renderTemplate(l, str, "body", vars)
+	}
 	if query.Query != "" {
 		b, err = json.MarshalIndent(struct {
 			Query         string                 `json:"query"`
@@ -120,12 +145,14 @@ func (g *Endpoint) RunQuery(startTime time.Time, query Request, okStatusCodes []
 	r.Body.Close()
 	if query.Headers != nil {
 		for k, v := range query.Headers {
-			r.Header.Add(k, v)
+			r.Header.Add(k, renderTemplate(l, v, "header", vars))
 		}
 	}
-	return g.DoRequest(l, r, stat, okStatusCodes)
+	res, stat, err := g.DoRequest(l, r, stat, okStatusCodes, query, vu)
+	vu.capture(l, query.Capture, stat.ContentType, stat.RawResponse)
+	return res, stat, err
 }
-func (g *Endpoint) DoRequest(l logger.AppLogger, r *http.Request, stat RequestStat, okStatusCodes []int) (*http.Response, RequestStat, error) {
+func (g *Endpoint) DoRequest(l logger.AppLogger, r *http.Request, stat RequestStat, okStatusCodes []int, query Request, vu *VirtualUser) (*http.Response, RequestStat, error) {
 	debug := g.l.HasDebug()
 	if debug {
 		l.Debug().Msg("Creating request")
@@ -139,18 +166,39 @@ func (g *Endpoint) DoRequest(l logger.AppLogger, r *http.Request, stat RequestSt
 	if r.Header.Get("X-Request-Id") == "" {
 		r.Header.Set("X-Request-Id", stat.RequestID)
 	}
+	// With a traceparent set in the headers, each request is a child-span of it, so that the requests can be part of an existing trace
+	if tc, err := ParseTraceparent(r.Header.Get("traceparent")); err == nil {
+		stat.TraceContext = tc.Child()
+	} else {
+		stat.TraceContext = NewTraceContext()
+	}
+	r.Header.Set("traceparent", stat.TraceContext.Traceparent())
 	if r.Header.Get("Content-Type") == "" {
 		r.Header.Set("Content-Type", "application/json")
 	}
+	if vu != nil {
+		for _, c := range vu.Jar.Cookies(r.URL) {
+			r.AddCookie(c)
+		}
+	}
 	if debug {
 		l.Debug().Interface("headers", r.Header).Msg("Doing request")
 	}
+	stat.Trace = newRequestTrace(r)
+	r = stat.Trace.withClientTrace(r)
+	stat.started()
 	res, err := g.client.Do(r)
 	if err != nil {
 		l.ErrErr(err).Msg("Failed to run request")
-		return nil, stat.End(nil, Unknwon+"Request", err), err
+		return nil, g.fail(stat, failure{errorType: Unknwon + "Request", err: err}), err
+	}
+	if vu != nil {
+		if cookies := res.Cookies(); len(cookies) > 0 {
+			vu.Jar.SetCookies(r.URL, cookies)
+		}
 	}
 	stat.StatusCode = int16(res.StatusCode)
+	stat.Trace.ResponseHeaders = res.Header
 	contentType := res.Header.Get("Content-Type")
 	stat.ContentType = contentType
 	l = logger.AppLogger{Logger: l.With().Str("contentType", contentType).Int("statusCode", res.StatusCode).Logger()}
@@ -158,7 +206,7 @@ func (g *Endpoint) DoRequest(l logger.AppLogger, r *http.Request, stat RequestSt
 	if err != nil {
 		l.ErrErr(err).Msg("failed to ready body")
 		err = fmt.Errorf("failed to read body")
-		return nil, stat.End(nil, Unknwon+"Body", err), err
+		return nil, g.fail(stat, failure{errorType: Unknwon + "Body", err: err, statusCode: res.StatusCode}), err
 	}
 	res.Body.Close()
 	if l.HasTrace() {
@@ -176,10 +224,23 @@ func (g *Endpoint) DoRequest(l logger.AppLogger, r *http.Request, stat RequestSt
 		if err != nil {
 			l.ErrWarn(err).Msg("Failed to unmarshal body")
 		} else {
-			if gqlResponse.Errors != nil && len(gqlResponse.Errors) > 0 {
+			if len(gqlResponse.Errors) > 0 {
+				stat.GqlErrors = gqlResponse.Errors
+			}
+			if len(gqlResponse.Errors) > 0 && g.PartialDataOk && len(gqlResponse.Data) > 0 {
+				stat.PartialData = true
+				l.Warn().Int("errors", len(gqlResponse.Errors)).Str("firstMessage", gqlResponse.Errors[0].Message).Msg("got errors with partial data in request")
+			} else if len(gqlResponse.Errors) > 0 {
 				firstMessage := gqlResponse.Errors[0].Message
-				l.Error().Str("firstMessage", firstMessage).Interface("json-response", gqlResponseRaw).Msg("got errors in request")
-				return nil, stat.End(body, ErrorType(firstMessage), err), err
+				l.Error().Str("firstMessage", firstMessage).Int("errors", len(gqlResponse.Errors)).Interface("json-response", gqlResponseRaw).Msg("got errors in request")
+				return nil, g.fail(stat, failure{
+					errorType:  ErrorType(firstMessage),
+					err:        err,
+					statusCode: res.StatusCode,
+					message:    firstMessage,
+					gqlErrors:  gqlResponse.Errors,
+					body:       body,
+				}), err
 			}
 
 		}
@@ -199,7 +260,8 @@ func (g *Endpoint) DoRequest(l logger.AppLogger, r *http.Request, stat RequestSt
 			Msg("Got response")
 	}
 
-	statusOk := false
+	// A status-assertion decides which status-codes are accepted, and fails as AssertionFailed-<name>
+	statusOk := hasStatusAssertion(query.Assertions)
 	for _, s := range okStatusCodes {
 		if res.StatusCode == s {
 			statusOk = true
@@ -207,14 +269,57 @@ func (g *Endpoint) DoRequest(l logger.AppLogger, r *http.Request, stat RequestSt
 		}
 
 	}
-	if len(okStatusCodes) == 0 {
+	if len(okStatusCodes) == 0 && !statusOk {
 		statusOk = res.StatusCode < 299
 	}
 	if !statusOk {
 		stat.RawResponse = body
 		err := fmt.Errorf("Got non-ok-statusCode: %d", res.StatusCode)
 		l.Error().Msg("Statuscode is not 2xx")
-		return res, stat.End(body, ErrorType(fmt.Sprintf("%s-%d", NonOK, res.StatusCode)), err), err
+		return res, g.fail(stat, failure{
+			errorType:  ErrorType(fmt.Sprintf("%s-%d", NonOK, res.StatusCode)),
+			err:        err,
+			statusCode: res.StatusCode,
+			body:       body,
+		}), err
+	}
+	if len(query.Assertions) > 0 {
+		errorType, err := checkAssertions(query.Assertions, &assertionResponse{
+			statusCode:  res.StatusCode,
+			headers:     res.Header,
+			contentType: contentType,
+			body:        body,
+			latency:     time.Now().Sub(stat.Start),
+		})
+		if err != nil {
+			stat.RawResponse = body
+			l.Error().Err(err).Str("errorType", string(errorType)).Msg("Assertion failed")
+			return res, g.fail(stat, failure{errorType: errorType, err: err, statusCode: res.StatusCode, body: body}), err
+		}
+	}
+	if query.ResponseSchema != nil && len(body) > 0 && strings.Contains(contentType, "json") && query.ResponseSchema.sampled() {
+		violations, err := query.ResponseSchema.validateBody(body)
+		if err != nil {
+			l.ErrErr(err).Msg("Failed to validate response against schema")
+		} else if len(violations) > 0 {
+			stat.RawResponse = body
+			stat.SchemaViolations = violations
+			err := fmt.Errorf("response does not match schema: %s at '%s'", violations[0].Message, violations[0].Pointer)
+			l.Error().Err(err).Int("violations", len(violations)).Msg("Schema-violation")
+			return res, g.fail(stat, failure{errorType: violations[0].ErrorType(), err: err, statusCode: res.StatusCode, body: body}), err
+		}
+	}
+	if query.Golden != nil && len(query.Golden.Response.Content) > 0 {
+		changes, err := query.Golden.compare(body, stat.Normalization)
+		if err != nil {
+			l.ErrErr(err).Msg("Failed to compare response with the golden response")
+		} else if len(changes) > 0 {
+			stat.RawResponse = body
+			stat.GoldenDiff = changes
+			err := goldenMismatchError(changes)
+			l.Error().Err(err).Int("changes", len(changes)).Msg("Golden mismatch")
+			return res, g.fail(stat, failure{errorType: GoldenMismatch, err: err, statusCode: res.StatusCode, body: body}), err
+		}
 	}
 	switch contentType {
 	case "text/html":
@@ -0,0 +1,135 @@
+package requests
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// GoldenResponse is a known-good response for a request.
+// Each later response is normalized and compared to it, and mismatches fail with the ErrorType GoldenMismatch.
+type GoldenResponse struct {
+	// Where the golden response was picked from, like the id of a run or the path of an output-file
+	Source string `json:"source,omitempty"`
+	// The hash of the response, as in the ResponseHashMap of the source
+	Hash     Hash        `json:"hash"`
+	Response ByteContent `json:"response"`
+}
+
+// GoldenChange is a single difference between a response and the golden response.
+// The values are kept as json, so that the changes can be stored as is.
+type GoldenChange struct {
+	// create, update or delete
+	Type string   `json:"type"`
+	Path []string `json:"path"`
+	// The value in the golden response
+	From json.RawMessage `json:"from,omitempty"`
+	// The value in the response
+	To json.RawMessage `json:"to,omitempty"`
+}
+
+// GoldenDiffs for each mismatching response, by the hash of the response, like the ByteHashMap
+type GoldenDiffMap map[[32]byte][]GoldenChange
+
+// NewGoldenResponse picks the response with the hash from the ByteHashMap as golden.
+// Without a hash, the most common response is used.
+func NewGoldenResponse(bm ByteHashMap, hash *Hash, source string) (*GoldenResponse, error) {
+	if hash == nil {
+		summary, err := bm.Summarize()
+		if err != nil {
+			return nil, err
+		}
+		if summary.ReferenceCount == 0 {
+			return nil, fmt.Errorf("there are no responses to pick a golden response from in %s", source)
+		}
+		hash = &summary.Reference
+	}
+	content, ok := bm[*hash]
+	if !ok {
+		return nil, fmt.Errorf("hash %s was not found in %s", hash, source)
+	}
+	// The count is only relevant within the source
+	content.Count = 0
+	return &GoldenResponse{Source: source, Hash: *hash, Response: content}, nil
+}
+
+// compare returns the differences between the normalized body and the normalized golden response.
+// Nil is returned if they are equal.
+func (g GoldenResponse) compare(body []byte, rules []NormalizationRule) ([]GoldenChange, error) {
+	golden := normalize(g.Response.Content, rules)
+	normalized := normalize(body, rules)
+	if bytes.Equal(golden, normalized) {
+		return nil, nil
+	}
+	a := ByteContent{Content: golden, ContentType: g.Response.ContentType}
+	b := ByteContent{Content: normalized, ContentType: g.Response.ContentType}
+	changes, err := diffValues(a.diffValue(), b.diffValue())
+	if err != nil {
+		return nil, err
+	}
+	result := make([]GoldenChange, len(changes))
+	for i, c := range changes {
+		result[i] = GoldenChange{Type: c.Type, Path: c.Path}
+		if c.From != nil {
+			result[i].From, _ = json.Marshal(c.From)
+		}
+		if c.To != nil {
+			result[i].To, _ = json.Marshal(c.To)
+		}
+	}
+	// Json which only differs in formatting or key-order is equal
+	if len(result) == 0 {
+		return nil, nil
+	}
+	return result, nil
+}
+
+func goldenMismatchError(changes []GoldenChange) error {
+	paths := []string{}
+	seen := map[string]bool{}
+	for _, c := range changes {
+		p := strings.Join(c.Path, ".")
+		if p == "" {
+			p = "(root)"
+		}
+		if !seen[p] {
+			seen[p] = true
+			paths = append(paths, p)
+		}
+	}
+	return fmt.Errorf("response differs from the golden response at %s", strings.Join(paths, ", "))
+}
+
+func (c GoldenDiffMap) Add(hash Hash, changes []GoldenChange) {
+	if c == nil || len(changes) == 0 {
+		return
+	}
+	c[hash] = changes
+}
+
+func (c GoldenDiffMap) MarshalJSON() ([]byte, error) {
+	u := map[string][]GoldenChange{}
+	for kb, vb := range c {
+		u[base64.URLEncoding.EncodeToString(kb[:])] = vb
+	}
+	return json.Marshal(u)
+}
+
+func (c *GoldenDiffMap) UnmarshalJSON(b []byte) error {
+	var u map[string][]GoldenChange
+	if err := json.Unmarshal(b, &u); err != nil {
+		return err
+	}
+	m := GoldenDiffMap{}
+	for k, v := range u {
+		h, err := ParseHash(k)
+		if err != nil {
+			return err
+		}
+		m[h] = v
+	}
+	*c = m
+	return nil
+}
@@ -0,0 +1,52 @@
+package requests
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGoldenResponse_compare(t *testing.T) {
+	bm := ByteHashMap{}
+	golden := `{"data": {"user": {"name": "a", "updatedAt": 1}, "files": [1, 2]}}`
+	bm.Add("application/json", []byte(golden), nil)
+	bm.Add("application/json", []byte(golden), nil)
+	bm.Add("application/json", []byte(`{"data": null}`), nil)
+	g, err := NewGoldenResponse(bm, nil, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(g.Response.Content) != golden {
+		t.Fatalf("Expected the most common response as golden, got %s", g.Response.Content)
+	}
+	rules := []NormalizationRule{{Key: "updatedAt"}}
+	tests := []struct {
+		name      string
+		body      string
+		wantPaths []string
+	}{
+		{"equal", golden, nil},
+		{"equal after normalization", `{"data": {"files": [1, 2], "user": {"updatedAt": 2, "name": "a"}}}`, nil},
+		{"changed value", `{"data": {"user": {"name": "b"}, "files": [1, 2]}}`, []string{"data.user.name"}},
+		{"changed shape", `{"data": {"user": {"name": "a"}, "files": [1]}, "errors": []}`, []string{"data.files.1", "errors"}},
+		{"not json", `Bad gateway`, []string{""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes, err := g.compare([]byte(tt.body), rules)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, c := range changes {
+				got = append(got, strings.Join(c.Path, "."))
+			}
+			if !reflect.DeepEqual(got, tt.wantPaths) {
+				t.Errorf("compare() paths = %v, want %v", got, tt.wantPaths)
+			}
+		})
+	}
+	if _, err := NewGoldenResponse(bm, &Hash{}, "test"); err == nil {
+		t.Errorf("Expected an error for an unknown hash")
+	}
+}
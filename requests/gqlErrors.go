@@ -0,0 +1,101 @@
+package requests
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Used in breakdowns for errors without a path or code
+const gqlErrorNone = "(none)"
+
+// Location of a GraphQL-error within the query
+type Location struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// Code returns extensions.code of the error, if any
+func (e Error) Code() string {
+	code, _ := e.Extensions["code"].(string)
+	return code
+}
+
+// PathPattern returns the path of the error with list-indexes collapsed, like user.files[*].name,
+// so that errors from different items in a list are counted together.
+func (e Error) PathPattern() string {
+	var sb strings.Builder
+	for _, p := range e.Path {
+		switch v := p.(type) {
+		case string:
+			if sb.Len() > 0 {
+				sb.WriteByte('.')
+			}
+			sb.WriteString(v)
+		case float64, int:
+			sb.WriteString("[*]")
+		default:
+			if sb.Len() > 0 {
+				sb.WriteByte('.')
+			}
+			sb.WriteString(fmt.Sprint(v))
+		}
+	}
+	return sb.String()
+}
+
+// GqlErrorBreakdown counts GraphQL-errors by their path and by their code
+type GqlErrorBreakdown struct {
+	// Number of errors, which may be more than one per response
+	Total int `json:"total"`
+	// Responses with errors, which were accepted since they also had partial data
+	PartialResponses int            `json:"partialResponses"`
+	ByPath           map[string]int `json:"byPath,omitempty"`
+	ByCode           map[string]int `json:"byCode,omitempty"`
+}
+
+// Merge adds the counts of another breakdown, like the one of a previous part of the run
+func (b *GqlErrorBreakdown) Merge(other GqlErrorBreakdown) {
+	b.Total += other.Total
+	b.PartialResponses += other.PartialResponses
+	if len(other.ByPath) > 0 && b.ByPath == nil {
+		b.ByPath = map[string]int{}
+	}
+	for path, n := range other.ByPath {
+		b.ByPath[path] += n
+	}
+	if len(other.ByCode) > 0 && b.ByCode == nil {
+		b.ByCode = map[string]int{}
+	}
+	for code, n := range other.ByCode {
+		b.ByCode[code] += n
+	}
+}
+
+// Add counts all the errors of a response
+func (b *GqlErrorBreakdown) Add(errors []Error, partial bool) {
+	if len(errors) == 0 {
+		return
+	}
+	if b.ByPath == nil {
+		b.ByPath = map[string]int{}
+	}
+	if b.ByCode == nil {
+		b.ByCode = map[string]int{}
+	}
+	if partial {
+		b.PartialResponses++
+	}
+	for _, e := range errors {
+		b.Total++
+		path := e.PathPattern()
+		if path == "" {
+			path = gqlErrorNone
+		}
+		b.ByPath[path]++
+		code := e.Code()
+		if code == "" {
+			code = gqlErrorNone
+		}
+		b.ByCode[code]++
+	}
+}
@@ -0,0 +1,81 @@
+package requests
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/runar-rkmedia/gabyoall/logger"
+)
+
+type fakeClient struct {
+	body string
+}
+
+func (c fakeClient) Do(req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: 200,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(bytes.NewBufferString(c.body)),
+	}, nil
+}
+
+func TestEndpoint_PartialData(t *testing.T) {
+	partial := `{"data": {"user": {"name": "a", "files": [null, {"id": 2}]}}, "errors": [
+		{"message": "File not found", "path": ["user", "files", 0], "locations": [{"line": 1, "column": 12}], "extensions": {"code": "NOT_FOUND"}},
+		{"message": "Deprecated", "extensions": {}}
+	]}`
+	tests := []struct {
+		name          string
+		body          string
+		partialDataOk bool
+		wantErrorType ErrorType
+		wantPartial   bool
+	}{
+		{"errors fail by default", partial, false, "File not found", false},
+		{"partial data is accepted", partial, true, "", true},
+		{"errors without data fail", `{"data": null, "errors": [{"message": "Denied"}]}`, true, "Denied", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := NewTimeSeriesWithLabel(time.Now())
+			g := Endpoint{
+				Url:           "http://localhost",
+				Headers:       http.Header{},
+				PartialDataOk: tt.partialDataOk,
+				ts:            &ts,
+				l:             logger.GetLogger("test"),
+				client:        fakeClient{tt.body},
+			}
+			_, stat, _ := g.RunQuery(time.Now(), Request{Query: "{ user { name } }"}, nil, nil)
+			if stat.ErrorType != tt.wantErrorType || stat.PartialData != tt.wantPartial {
+				t.Errorf("RunQuery() = %q (partial %v), want %q (partial %v)", stat.ErrorType, stat.PartialData, tt.wantErrorType, tt.wantPartial)
+			}
+			if len(stat.GqlErrors) == 0 {
+				t.Errorf("Expected all the errors to be recorded")
+			}
+		})
+	}
+}
+
+func TestGqlErrorBreakdown_Add(t *testing.T) {
+	var b GqlErrorBreakdown
+	b.Add([]Error{
+		{Message: "a", Path: []interface{}{"user", "files", float64(0), "name"}, Extensions: map[string]interface{}{"code": "NOT_FOUND"}},
+		{Message: "b", Path: []interface{}{"user", "files", float64(3), "name"}, Extensions: map[string]interface{}{"code": "NOT_FOUND"}},
+		{Message: "c"},
+	}, true)
+	b.Add(nil, false)
+	want := GqlErrorBreakdown{
+		Total:            3,
+		PartialResponses: 1,
+		ByPath:           map[string]int{"user.files[*].name": 2, "(none)": 1},
+		ByCode:           map[string]int{"NOT_FOUND": 2, "(none)": 1},
+	}
+	if !reflect.DeepEqual(b, want) {
+		t.Errorf("Add() = %#v, want %#v", b, want)
+	}
+}
@@ -0,0 +1,196 @@
+package requests
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+)
+
+// Range and precision of the histograms. Latencies are recorded in microseconds, up to an hour.
+// Two significant digits keeps each histogram small, since there is one for every ErrorType,
+// at the cost of about 1% precision on the percentiles.
+const (
+	histogramLowest      = 1
+	histogramHighest     = int64(time.Hour / time.Microsecond)
+	histogramSignificant = 2
+)
+
+// Histogram is a mergeable HDR-histogram of latencies.
+// It is serialized in the compressed, base64-encoded format of HdrHistogram,
+// so histograms from multiple sources can be merged exactly.
+type Histogram struct {
+	h *hdrhistogram.Histogram
+}
+
+// Percentiles of the latencies
+type Percentiles struct {
+	P50  time.Duration
+	P75  time.Duration
+	P90  time.Duration
+	P95  time.Duration
+	P99  time.Duration
+	P999 time.Duration
+}
+
+func NewHistogram() *Histogram {
+	return &Histogram{hdrhistogram.New(histogramLowest, histogramHighest, histogramSignificant)}
+}
+
+// Record adds the duration. Durations outside of the range of the histogram are clamped.
+func (h *Histogram) Record(d time.Duration) {
+	v := int64(d / time.Microsecond)
+	if v < histogramLowest {
+		v = histogramLowest
+	}
+	if v > histogramHighest {
+		v = histogramHighest
+	}
+	h.h.RecordValue(v)
+}
+
+// Merge adds all the values of the other histogram
+func (h *Histogram) Merge(other *Histogram) {
+	if other == nil {
+		return
+	}
+	h.h.Merge(other.h)
+}
+
+// Count returns the number of recorded values. A nil histogram, like in runs stored before histograms were added, is empty.
+func (h *Histogram) Count() int64 {
+	if h == nil {
+		return 0
+	}
+	return h.h.TotalCount()
+}
+
+// Percentile returns the latency at the percentile, like 99.9
+func (h *Histogram) Percentile(p float64) time.Duration {
+	if h.Count() == 0 {
+		return 0
+	}
+	return time.Duration(h.h.ValueAtQuantile(p)) * time.Microsecond
+}
+
+func (h *Histogram) Percentiles() Percentiles {
+	return Percentiles{
+		P50:  h.Percentile(50),
+		P75:  h.Percentile(75),
+		P90:  h.Percentile(90),
+		P95:  h.Percentile(95),
+		P99:  h.Percentile(99),
+		P999: h.Percentile(99.9),
+	}
+}
+
+// Stats returns the count, min, max, mean and percentiles of the recorded values.
+// The total is approximated from the mean, like for runs where the durations themselves were not kept.
+func (h *Histogram) Stats() Stats {
+	if h.Count() == 0 {
+		return Stats{}
+	}
+	mean := time.Duration(h.h.Mean() * float64(time.Microsecond))
+	return Stats{
+		Count:       int(h.Count()),
+		Min:         time.Duration(h.h.Min()) * time.Microsecond,
+		Max:         time.Duration(h.h.Max()) * time.Microsecond,
+		Average:     mean,
+		Total:       mean * time.Duration(h.Count()),
+		Percentiles: h.Percentiles(),
+	}
+}
+
+// HistogramBucket is the number of values within a bucket of a histogram, starting at Value
+type HistogramBucket struct {
+	Value time.Duration
+	Count int64
+}
+
+// Buckets returns the non-empty buckets, in ascending order
+func (h *Histogram) Buckets() []HistogramBucket {
+	if h.Count() == 0 {
+		return nil
+	}
+	var buckets []HistogramBucket
+	for _, b := range h.h.Distribution() {
+		if b.Count == 0 {
+			continue
+		}
+		buckets = append(buckets, HistogramBucket{time.Duration(b.From) * time.Microsecond, b.Count})
+	}
+	return buckets
+}
+
+// CountAtOrBelow returns the number of values at or below d, to within the precision of the histogram
+func (h *Histogram) CountAtOrBelow(d time.Duration) int64 {
+	if h.Count() == 0 {
+		return 0
+	}
+	var n int64
+	for _, b := range h.h.Distribution() {
+		if time.Duration(b.From)*time.Microsecond > d {
+			break
+		}
+		n += b.Count
+	}
+	return n
+}
+
+func (h *Histogram) encode() ([]byte, error) {
+	return h.h.Encode(hdrhistogram.V2CompressedEncodingCookieBase)
+}
+
+func (h *Histogram) decode(b []byte) error {
+	decoded, err := hdrhistogram.Decode(b)
+	if err != nil {
+		return fmt.Errorf("failed to decode histogram: %w", err)
+	}
+	h.h = decoded
+	return nil
+}
+
+func (h *Histogram) MarshalJSON() ([]byte, error) {
+	b, err := h.encode()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(string(b))
+}
+
+func (h *Histogram) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	return h.decode([]byte(s))
+}
+
+// Used when stored in the database
+func (h *Histogram) GobEncode() ([]byte, error) {
+	return h.encode()
+}
+
+func (h *Histogram) GobDecode(b []byte) error {
+	return h.decode(b)
+}
+
+func (p Percentiles) MarshalJSON() ([]byte, error) {
+	t := struct {
+		P50  int64 `json:"p50"`
+		P75  int64 `json:"p75"`
+		P90  int64 `json:"p90"`
+		P95  int64 `json:"p95"`
+		P99  int64 `json:"p99"`
+		P999 int64 `json:"p99_9"`
+	}{
+		P50:  p.P50.Milliseconds(),
+		P75:  p.P75.Milliseconds(),
+		P90:  p.P90.Milliseconds(),
+		P95:  p.P95.Milliseconds(),
+		P99:  p.P99.Milliseconds(),
+		P999: p.P999.Milliseconds(),
+	}
+	return json.Marshal(t)
+}
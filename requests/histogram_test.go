@@ -0,0 +1,74 @@
+package requests
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestHistogram_Percentiles(t *testing.T) {
+	h := NewHistogram()
+	for i := 1; i <= 1000; i++ {
+		h.Record(time.Duration(i) * time.Millisecond)
+	}
+	tests := []struct {
+		p    float64
+		want time.Duration
+	}{
+		{50, 500 * time.Millisecond},
+		{90, 900 * time.Millisecond},
+		{99, 990 * time.Millisecond},
+		{99.9, 999 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		got := h.Percentile(tt.p)
+		// Two significant digits gives about 1% precision
+		if diff := got - tt.want; diff < -tt.want/100 || diff > tt.want/100 {
+			t.Errorf("Percentile(%v) = %v, want %v", tt.p, got, tt.want)
+		}
+	}
+	if got := NewHistogram().Percentile(99); got != 0 {
+		t.Errorf("Expected 0 for an empty histogram, got %v", got)
+	}
+}
+
+func TestHistogram_MergeSerialized(t *testing.T) {
+	all, a, b := NewHistogram(), NewHistogram(), NewHistogram()
+	for i := 1; i <= 500; i++ {
+		d := time.Duration(i*i) * time.Microsecond
+		all.Record(d)
+		if i%2 == 0 {
+			a.Record(d)
+		} else {
+			b.Record(d)
+		}
+	}
+	// One through json, one through gob, like from an output-file and from the database
+	j, err := json.Marshal(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromJson Histogram
+	if err := json.Unmarshal(j, &fromJson); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(b); err != nil {
+		t.Fatal(err)
+	}
+	var fromGob Histogram
+	if err := gob.NewDecoder(&buf).Decode(&fromGob); err != nil {
+		t.Fatal(err)
+	}
+	merged := NewHistogram()
+	merged.Merge(&fromJson)
+	merged.Merge(&fromGob)
+	if merged.Count() != all.Count() {
+		t.Fatalf("Count() = %d, want %d", merged.Count(), all.Count())
+	}
+	if got, want := merged.Percentiles(), all.Percentiles(); got != want {
+		t.Errorf("Percentiles() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,180 @@
+package requests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// NormalizationRule is applied to responses before they are hashed, so that responses which only differ
+// in unique values, like timestamps, trace-ids or cursors, are deduplicated in the ResponseHashMap.
+// Each rule should set one of Regex, JmesPath or Key.
+type NormalizationRule struct {
+	// Regular expression, whose matches are replaced with Replacement
+	Regex string `json:"regex,omitempty"`
+	// Used with Regex. Capture-groups can be used, like `$1`
+	Replacement string `json:"replacement,omitempty"`
+	// Drops the value at the path from json-responses.
+	// Only field-names and wildcards are supported, like `data.files[*].updatedAt`
+	JmesPath string `json:"jmes_path,omitempty" mapstructure:"jmes_path"`
+	// Drops the key from json-responses, wherever it occurs
+	Key string `json:"key,omitempty"`
+}
+
+// Used when no normalization-rules are set.
+// Matches uuids, and the request-id that this project creates for each request.
+var DefaultNormalizationRule = NormalizationRule{
+	Regex:       `([a-fA-F0-9-]{25,32}|srv-test-[-_\w]*)`,
+	Replacement: "__UID__",
+}
+
+var defaultNormalization = []NormalizationRule{DefaultNormalizationRule}
+
+func (r NormalizationRule) Validate() error {
+	set := 0
+	for _, s := range []string{r.Regex, r.JmesPath, r.Key} {
+		if s != "" {
+			set++
+		}
+	}
+	if set != 1 {
+		return fmt.Errorf("normalization-rule: exactly one of regex, jmes_path or key must be set")
+	}
+	if r.Regex != "" {
+		if _, err := compileRegex(r.Regex); err != nil {
+			return fmt.Errorf("normalization-rule: %w", err)
+		}
+	}
+	if r.JmesPath != "" {
+		if _, err := parseDropPath(r.JmesPath); err != nil {
+			return fmt.Errorf("normalization-rule: %w", err)
+		}
+	}
+	return nil
+}
+
+func ValidateNormalizationRules(rules []NormalizationRule) error {
+	for _, r := range rules {
+		if err := r.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Segments of identifiers, or [*] / []
+var dropPathRegex = regexp.MustCompile(`^(?:[A-Za-z_][\w]*|"[^"]+")(?:\.(?:[A-Za-z_][\w]*|"[^"]+")|\[\*?\])*$`)
+var dropPathSegmentRegex = regexp.MustCompile(`[A-Za-z_][\w]*|"[^"]+"|\[\*?\]`)
+
+// Returns the segments of the path, where "*" means all items of an array
+func parseDropPath(path string) ([]string, error) {
+	if !dropPathRegex.MatchString(path) {
+		return nil, fmt.Errorf("jmes-path '%s' is not supported for dropping values, only field-names and [*] are", path)
+	}
+	matches := dropPathSegmentRegex.FindAllString(path, -1)
+	segments := make([]string, len(matches))
+	for i, m := range matches {
+		switch {
+		case strings.HasPrefix(m, "["):
+			segments[i] = "*"
+		case strings.HasPrefix(m, `"`):
+			segments[i] = strings.Trim(m, `"`)
+		default:
+			segments[i] = m
+		}
+	}
+	return segments, nil
+}
+
+func dropPath(v interface{}, path []string) {
+	if len(path) == 0 {
+		return
+	}
+	switch value := v.(type) {
+	case map[string]interface{}:
+		if path[0] == "*" {
+			return
+		}
+		if len(path) == 1 {
+			delete(value, path[0])
+			return
+		}
+		dropPath(value[path[0]], path[1:])
+	case []interface{}:
+		if path[0] != "*" {
+			return
+		}
+		if len(path) == 1 {
+			for i := range value {
+				value[i] = nil
+			}
+			return
+		}
+		for _, item := range value {
+			dropPath(item, path[1:])
+		}
+	}
+}
+
+func dropKey(v interface{}, key string) {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		delete(value, key)
+		for _, item := range value {
+			dropKey(item, key)
+		}
+	case []interface{}:
+		for _, item := range value {
+			dropKey(item, key)
+		}
+	}
+}
+
+// normalize applies the rules to the content. If no rules are set, the DefaultNormalizationRule is used.
+// Rules for json are applied first, and only if the content is json.
+func normalize(content []byte, rules []NormalizationRule) []byte {
+	if len(rules) == 0 {
+		rules = defaultNormalization
+	}
+	hasJsonRules := false
+	for _, r := range rules {
+		if r.JmesPath != "" || r.Key != "" {
+			hasJsonRules = true
+			break
+		}
+	}
+	if hasJsonRules {
+		var JSON interface{}
+		// Numbers are kept as they are, since large integer-ids would otherwise be rounded to the same float64
+		d := json.NewDecoder(bytes.NewReader(content))
+		d.UseNumber()
+		if err := d.Decode(&JSON); err == nil {
+			for _, r := range rules {
+				switch {
+				case r.Key != "":
+					dropKey(JSON, r.Key)
+				case r.JmesPath != "":
+					if path, err := parseDropPath(r.JmesPath); err == nil {
+						dropPath(JSON, path)
+					}
+				}
+			}
+			if b, err := json.Marshal(JSON); err == nil {
+				content = b
+			}
+		}
+	}
+	for _, r := range rules {
+		if r.Regex == "" {
+			continue
+		}
+		re, err := compileRegex(r.Regex)
+		if err != nil {
+			continue
+		}
+		content = re.ReplaceAll(content, []byte(r.Replacement))
+	}
+	return content
+}
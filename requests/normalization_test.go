@@ -0,0 +1,88 @@
+package requests
+
+import (
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []NormalizationRule
+		input string
+		want  string
+	}{
+		{
+			"default rule replaces request-ids",
+			nil,
+			`{"id": "srv-test-abc_123", "trace": "4f2a3b1c11aa22bb33cc44dd55ee66ff"}`,
+			`{"id": "__UID__", "trace": "__UID__"}`,
+		},
+		{
+			"drops keys anywhere",
+			[]NormalizationRule{{Key: "updatedAt"}},
+			`{"data": {"updatedAt": 1, "files": [{"id": 1, "updatedAt": 2}]}, "updatedAt": 3}`,
+			`{"data":{"files":[{"id":1}]}}`,
+		},
+		{
+			"drops values by path",
+			[]NormalizationRule{{JmesPath: "data.files[*].cursor"}, {JmesPath: `extensions."trace-id"`}},
+			`{"data": {"files": [{"id": 1, "cursor": "a"}, {"id": 2, "cursor": "b"}]}, "extensions": {"trace-id": "x", "cost": 3}}`,
+			`{"data":{"files":[{"id":1},{"id":2}]},"extensions":{"cost":3}}`,
+		},
+		{
+			"large integer-ids are kept as they are",
+			[]NormalizationRule{{Key: "updatedAt"}},
+			`{"id": 9007199254740993, "updatedAt": 1}`,
+			`{"id":9007199254740993}`,
+		},
+		{
+			"regex-replacements with capture-groups",
+			[]NormalizationRule{{Regex: `"(\w+At)": "[^"]*"`, Replacement: `"$1": "__TIME__"`}},
+			`{"createdAt": "2021-10-29T15:58:10Z"}`,
+			`{"createdAt": "__TIME__"}`,
+		},
+		{
+			"json-rules are skipped for non-json",
+			[]NormalizationRule{{Key: "id"}, {Regex: `\d+`, Replacement: "N"}},
+			`<p>Error 500</p>`,
+			`<p>Error N</p>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateNormalizationRules(tt.rules); err != nil {
+				t.Fatalf("ValidateNormalizationRules() error = %v", err)
+			}
+			if got := string(normalize([]byte(tt.input), tt.rules)); got != tt.want {
+				t.Errorf("normalize() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestByteHashMap_Add(t *testing.T) {
+	rules := []NormalizationRule{{Key: "timestamp"}}
+	bm := ByteHashMap{}
+	a := bm.Add("application/json", []byte(`{"ok": true, "timestamp": 1}`), rules)
+	b := bm.Add("application/json", []byte(`{"timestamp": 2, "ok": true}`), rules)
+	if a == nil || b == nil || *a != *b {
+		t.Errorf("Expected the responses to hash to the same value, got %v and %v", a, b)
+	}
+	if len(bm) != 1 {
+		t.Errorf("Expected a single entry, got %d", len(bm))
+	}
+}
+
+func TestNormalizationRule_Validate(t *testing.T) {
+	for _, r := range []NormalizationRule{
+		{},
+		{Regex: "(", Key: "a"},
+		{Regex: "("},
+		{JmesPath: "data.files[?id > 1]"},
+		{JmesPath: "length(data)"},
+	} {
+		if err := r.Validate(); err == nil {
+			t.Errorf("Validate() expected error for %#v", r)
+		}
+	}
+}
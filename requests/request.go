@@ -9,4 +9,19 @@ type Request struct {
 	// For some reason, the server does not like operationName.
 	OperationName string `json:"operationName,omitempty"` //`json:"operationName"`
 	Method        string `json:"method,omitempty"`
+	// Values to capture from json-responses into the variables of the virtual user, as name: jmes-path.
+	// These are available in templating as {{ .VU.Vars.name }}
+	Capture map[string]string `json:"capture,omitempty"`
+	// Variables from the config, available in templating as {{ .Vars.key }}. Set when running.
+	Vars map[string]string `json:"-"`
+	// Checks to perform on each response, in addition to the status-code.
+	Assertions []Assertion `json:"assertions,omitempty"`
+	// JSON Schema which json-responses are validated against
+	ResponseSchema *ResponseSchema `json:"responseSchema,omitempty"`
+	// Rules for normalizing responses before they are hashed. Applied after the rules of the endpoint.
+	Normalization []NormalizationRule `json:"normalization,omitempty"`
+	// Known-good response, which each response is compared to after normalization
+	Golden *GoldenResponse `json:"golden,omitempty"`
+	// Objective which each run is scored against. Overrides the SLO of the endpoint.
+	SLO *SLO `json:"slo,omitempty"`
 }
@@ -0,0 +1,354 @@
+package requests
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LogEntry is a line in the request-log, for a single completed request
+type LogEntry struct {
+	// When the request was started
+	Time         time.Time     `json:"time"`
+	RequestID    string        `json:"requestId"`
+	Duration     time.Duration `json:"duration"`
+	StatusCode   int           `json:"status,omitempty"`
+	ErrorType    ErrorType     `json:"errorType,omitempty"`
+	ResponseHash *Hash         `json:"responseHash,omitempty"`
+	WorkerID     int           `json:"workerId"`
+}
+
+var requestLogHeader = []string{"time", "request_id", "duration_ms", "status", "error_type", "response_hash", "worker_id"}
+
+// NewLogEntry creates the entry for a completed request
+func NewLogEntry(stat RequestStat) LogEntry {
+	e := LogEntry{
+		Time:       stat.Start,
+		RequestID:  stat.RequestID,
+		Duration:   stat.Duration,
+		StatusCode: int(stat.StatusCode),
+		ErrorType:  stat.ErrorType,
+		WorkerID:   stat.WorkerID,
+	}
+	if stat.ResponseHash != nil {
+		h := *stat.ResponseHash
+		e.ResponseHash = &h
+	}
+	return e
+}
+
+// Stat returns the entry as a RequestStat, without the response-body
+func (e LogEntry) Stat() RequestStat {
+	stat := RequestStat{
+		Start:     e.Time,
+		RequestID: e.RequestID,
+		Duration:  e.Duration,
+		ErrorType: e.ErrorType,
+		WorkerID:  e.WorkerID,
+	}
+	stat.StatusCode = int16(e.StatusCode)
+	stat.CompactStat.ErrorType = e.ErrorType
+	stat.ResponseHash = e.ResponseHash
+	return stat
+}
+
+func (e LogEntry) csvRecord() []string {
+	hash := ""
+	if e.ResponseHash != nil {
+		hash = e.ResponseHash.String()
+	}
+	return []string{
+		e.Time.Format(time.RFC3339Nano),
+		e.RequestID,
+		strconv.FormatFloat(float64(e.Duration)/float64(time.Millisecond), 'f', -1, 64),
+		strconv.Itoa(e.StatusCode),
+		string(e.ErrorType),
+		hash,
+		strconv.Itoa(e.WorkerID),
+	}
+}
+
+func parseCSVRecord(record []string) (LogEntry, error) {
+	var e LogEntry
+	if len(record) != len(requestLogHeader) {
+		return e, fmt.Errorf("expected %d fields, got %d", len(requestLogHeader), len(record))
+	}
+	var err error
+	if e.Time, err = time.Parse(time.RFC3339Nano, record[0]); err != nil {
+		return e, err
+	}
+	e.RequestID = record[1]
+	ms, err := strconv.ParseFloat(record[2], 64)
+	if err != nil {
+		return e, fmt.Errorf("invalid duration: %w", err)
+	}
+	e.Duration = time.Duration(ms * float64(time.Millisecond))
+	if e.StatusCode, err = strconv.Atoi(record[3]); err != nil {
+		return e, fmt.Errorf("invalid status: %w", err)
+	}
+	e.ErrorType = ErrorType(record[4])
+	if record[5] != "" {
+		h, err := ParseHash(record[5])
+		if err != nil {
+			return e, err
+		}
+		e.ResponseHash = &h
+	}
+	if e.WorkerID, err = strconv.Atoi(record[6]); err != nil {
+		return e, fmt.Errorf("invalid worker-id: %w", err)
+	}
+	return e, nil
+}
+
+// Durations are written as milliseconds, like in the rest of the output
+func (e LogEntry) MarshalJSON() ([]byte, error) {
+	type alias LogEntry
+	return json.Marshal(struct {
+		alias
+		Duration float64 `json:"duration"`
+	}{alias(e), float64(e.Duration) / float64(time.Millisecond)})
+}
+
+func (e *LogEntry) UnmarshalJSON(b []byte) error {
+	type alias LogEntry
+	var j struct {
+		*alias
+		Duration float64 `json:"duration"`
+	}
+	j.alias = (*alias)(e)
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+	e.Duration = time.Duration(j.Duration * float64(time.Millisecond))
+	return nil
+}
+
+// IsCSVLog returns true if the request-log at the path is written as csv, rather than jsonl
+func IsCSVLog(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".csv")
+}
+
+// RequestLog streams a line for each completed request to a file, as jsonl or csv.
+// Each line is written as the request completes, so that the log survives a crash,
+// and so that the details of each request do not need to be kept in memory.
+type RequestLog struct {
+	f    *os.File
+	w    *bufio.Writer
+	csv  *csv.Writer
+	lock sync.Mutex
+}
+
+// NewRequestLog creates (or truncates) the file. Paths ending in .csv are written as csv, anything else as jsonl.
+func NewRequestLog(path string) (*RequestLog, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request-log %s: %w", path, err)
+	}
+	return newRequestLog(f, path, true)
+}
+
+// AppendRequestLog continues an existing request-log, like when a run is resumed. It is created if it does not exist.
+// The log is first truncated to size, like the size at the checkpoint of the run, since the requests after it are run again.
+// A negative size keeps the whole log. A partial last line, like after a crash, is removed either way.
+func AppendRequestLog(path string, size int64) (*RequestLog, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0666)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open request-log %s: %w", path, err)
+	}
+	if size >= 0 {
+		if err := truncateTo(f, size); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("failed to open request-log %s: %w", path, err)
+		}
+	}
+	size, err = truncatePartialLine(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to open request-log %s: %w", path, err)
+	}
+	return newRequestLog(f, path, size == 0)
+}
+
+func truncateTo(f *os.File, size int64) error {
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() < size {
+		return fmt.Errorf("the log has %d bytes, which is less than the %d bytes it had at the checkpoint", info.Size(), size)
+	}
+	return f.Truncate(size)
+}
+
+// Truncates the file after its last newline, and seeks to the end of it. Lines are short, so only the tail is read.
+func truncatePartialLine(f *os.File) (int64, error) {
+	size, err := f.Seek(0, io.SeekEnd)
+	if err != nil || size == 0 {
+		return size, err
+	}
+	tail := int64(64 * 1024)
+	if tail > size {
+		tail = size
+	}
+	b := make([]byte, tail)
+	if _, err := f.ReadAt(b, size-tail); err != nil {
+		return 0, err
+	}
+	end := size - tail + int64(bytes.LastIndexByte(b, '\n')) + 1
+	if end == size {
+		return size, nil
+	}
+	if err := f.Truncate(end); err != nil {
+		return 0, err
+	}
+	return f.Seek(end, io.SeekStart)
+}
+
+// The csv-header is only written to new logs
+func newRequestLog(f *os.File, path string, header bool) (*RequestLog, error) {
+	l := &RequestLog{f: f, w: bufio.NewWriter(f)}
+	if IsCSVLog(path) {
+		l.csv = csv.NewWriter(l.w)
+		if header {
+			if err := l.csv.Write(requestLogHeader); err != nil {
+				f.Close()
+				return nil, err
+			}
+		}
+	}
+	return l, l.flush()
+}
+
+// Write appends the request to the log
+func (l *RequestLog) Write(stat RequestStat) error {
+	if l == nil {
+		return nil
+	}
+	e := NewLogEntry(stat)
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.csv != nil {
+		if err := l.csv.Write(e.csvRecord()); err != nil {
+			return err
+		}
+		return l.flush()
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	l.w.Write(b)
+	l.w.WriteByte('\n')
+	return l.flush()
+}
+
+// Must be called with the lock held, or before the log is shared
+func (l *RequestLog) flush() error {
+	if l.csv != nil {
+		l.csv.Flush()
+		if err := l.csv.Error(); err != nil {
+			return err
+		}
+	}
+	return l.w.Flush()
+}
+
+// Size returns the number of bytes in the log, including those of previous sessions
+func (l *RequestLog) Size() (int64, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if err := l.flush(); err != nil {
+		return 0, err
+	}
+	return l.f.Seek(0, io.SeekCurrent)
+}
+
+func (l *RequestLog) Close() error {
+	if l == nil {
+		return nil
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if err := l.flush(); err != nil {
+		l.f.Close()
+		return err
+	}
+	return l.f.Close()
+}
+
+// ReadRequestLog calls fn for each entry in the log, in the order they were written.
+// A partial last line, like from a crash, is ignored.
+func ReadRequestLog(r io.Reader, isCSV bool, fn func(e LogEntry) error) error {
+	if isCSV {
+		return readCSVLog(r, fn)
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	var pending []byte
+	line := 0
+	for scanner.Scan() {
+		line++
+		b := scanner.Bytes()
+		if len(strings.TrimSpace(string(b))) == 0 {
+			continue
+		}
+		if pending != nil {
+			return fmt.Errorf("line %d of the request-log is invalid", line-1)
+		}
+		var e LogEntry
+		if err := json.Unmarshal(b, &e); err != nil {
+			pending = append([]byte{}, b...)
+			continue
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func readCSVLog(r io.Reader, fn func(e LogEntry) error) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if strings.Join(header, ",") != strings.Join(requestLogHeader, ",") {
+		return fmt.Errorf("the csv-header of the request-log does not match: %s", strings.Join(header, ","))
+	}
+	var pendingErr error
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if pendingErr != nil {
+			return pendingErr
+		}
+		if err != nil {
+			return err
+		}
+		line, _ := reader.FieldPos(0)
+		e, err := parseCSVRecord(record)
+		if err != nil {
+			pendingErr = fmt.Errorf("line %d of the request-log is invalid: %w", line, err)
+			continue
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package requests
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRequestLog(t *testing.T) {
+	start := time.Date(2021, 11, 3, 12, 0, 0, 123456789, time.UTC)
+	hash := Hash{1, 2, 3}
+	stats := []RequestStat{
+		{Start: start, RequestID: "a", Duration: 1500 * time.Microsecond, WorkerID: 1, CompactStat: CompactStat{StatusCode: 200, ResponseHash: &hash}},
+		{Start: start.Add(time.Second), RequestID: "b", Duration: 30 * time.Millisecond, ErrorType: "ServerError", WorkerID: 2, CompactStat: CompactStat{StatusCode: 500}},
+	}
+	want := []LogEntry{
+		{Time: start, RequestID: "a", Duration: 1500 * time.Microsecond, StatusCode: 200, ResponseHash: &hash, WorkerID: 1},
+		{Time: start.Add(time.Second), RequestID: "b", Duration: 30 * time.Millisecond, StatusCode: 500, ErrorType: "ServerError", WorkerID: 2},
+	}
+	tests := []struct {
+		name string
+		file string
+		// Appended to the log, like a line which was cut short by a crash
+		tail    string
+		wantErr bool
+	}{
+		{"jsonl", "log.jsonl", "", false},
+		{"csv", "log.csv", "", false},
+		{"jsonl with partial last line", "log.jsonl", `{"time":"2021-11-03T12:00:02Z","reque`, false},
+		{"csv with partial last line", "log.csv", "2021-11-03T12:00:02Z,c,1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			l, err := NewRequestLog(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, s := range stats {
+				if err := l.Write(s); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := l.Close(); err != nil {
+				t.Fatal(err)
+			}
+			b, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b = append(b, tt.tail...)
+			var got []LogEntry
+			err = ReadRequestLog(bytes.NewReader(b), IsCSVLog(path), func(e LogEntry) error {
+				got = append(got, e)
+				return nil
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReadRequestLog() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("ReadRequestLog() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestReadRequestLog_invalidLine(t *testing.T) {
+	log := strings.Join([]string{
+		`{"time":"2021-11-03T12:00:00Z","requestId":"a","duration":1}`,
+		`not json`,
+		`{"time":"2021-11-03T12:00:01Z","requestId":"b","duration":1}`,
+	}, "\n")
+	err := ReadRequestLog(strings.NewReader(log), false, func(e LogEntry) error { return nil })
+	if err == nil {
+		t.Error("expected an error for an invalid line which is not the last")
+	}
+}
+
+func TestAppendRequestLog(t *testing.T) {
+	start := time.Date(2021, 11, 3, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		file string
+		// Written between the sessions, like a line which was cut short by a crash
+		tail string
+		// Whether to continue from the size at the checkpoint, which was taken before the request x
+		checkpoint bool
+	}{
+		{"jsonl", "log.jsonl", "", false},
+		{"csv", "log.csv", "", false},
+		{"jsonl with partial last line", "log.jsonl", `{"time":"2021-11-03T12:00:02Z","reque`, false},
+		{"csv with partial last line", "log.csv", "2021-11-03T12:00:02Z,c,1", false},
+		{"jsonl from checkpoint", "log.jsonl", `{"time":"2021-11-03T12:00:02Z","reque`, true},
+		{"csv from checkpoint", "log.csv", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			l, err := NewRequestLog(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			l.Write(RequestStat{Start: start, RequestID: "a", Duration: time.Millisecond})
+			size := int64(-1)
+			if tt.checkpoint {
+				if size, err = l.Size(); err != nil {
+					t.Fatal(err)
+				}
+				// Completed after the checkpoint, so it is run again when resuming
+				l.Write(RequestStat{Start: start, RequestID: "x", Duration: time.Millisecond})
+			}
+			l.Close()
+			f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f.WriteString(tt.tail)
+			f.Close()
+
+			l, err = AppendRequestLog(path, size)
+			if err != nil {
+				t.Fatal(err)
+			}
+			l.Write(RequestStat{Start: start.Add(time.Second), RequestID: "b", Duration: time.Millisecond})
+			l.Close()
+			f, err = os.Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			var got []string
+			err = ReadRequestLog(f, IsCSVLog(path), func(e LogEntry) error {
+				got = append(got, e.RequestID)
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := []string{"a", "b"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("got %v, want %v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,283 @@
+package requests
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultSampleSize is the number of samples kept of the slowest requests, and of each ErrorType
+	DefaultSampleSize = 10
+	// Bodies larger than this are truncated in the samples
+	maxSampleBodySize = 64 * 1024
+	redacted          = "**REDACTED**"
+)
+
+// Headers which are redacted in samples
+var sensitiveHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key", "X-Auth-Token"}
+
+// Json-keys in request-bodies which are redacted in samples, if the key contains any of these
+var sensitiveKeys = []string{"password", "secret", "token"}
+
+// Sample is a single request with full details, so that it can be looked up by its request-id
+type Sample struct {
+	RequestID string `json:"requestId"`
+	// The trace which the request was sent with, to find the trace of the server
+	TraceID    string    `json:"traceId,omitempty"`
+	ErrorType  ErrorType `json:"errorType,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	StatusCode int       `json:"statusCode,omitempty"`
+	Start      time.Time `json:"start"`
+	// The request, with credentials redacted
+	Request  SampleRequest  `json:"request"`
+	Response SampleResponse `json:"response"`
+	Timing   Timing         `json:"timing"`
+}
+
+type SampleRequest struct {
+	Method  string      `json:"method,omitempty"`
+	Url     string      `json:"url,omitempty"`
+	Headers http.Header `json:"headers,omitempty"`
+	Body    string      `json:"body,omitempty"`
+	// Set if the body was larger than what is kept. Such requests cannot be replayed.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+type SampleResponse struct {
+	Headers http.Header `json:"headers,omitempty"`
+	Body    string      `json:"body,omitempty"`
+	// Set if the body was larger than what is kept
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// Samples is a bounded reservoir of the slowest successful requests,
+// and of random samples of the failed requests for each ErrorType.
+type Samples struct {
+	Size int `json:"size"`
+	// The slowest successful requests, slowest first
+	Slowest []Sample `json:"slowest,omitempty"`
+	// Uniformly sampled failed requests, by ErrorType
+	Failures map[ErrorType][]Sample `json:"failures,omitempty"`
+	// Number of failed requests seen by ErrorType, which each sample is drawn from
+	Seen map[ErrorType]int `json:"seen,omitempty"`
+	// Headers which are redacted in addition to the sensitiveHeaders
+	sensitiveHeaders []string
+	rand             *rand.Rand
+}
+
+// NewSamples creates a reservoir of the given size. Zero uses the DefaultSampleSize, and a negative size disables sampling.
+// The sensitiveHeaders are redacted in addition to the common ones, like a custom header for the auth-token.
+func NewSamples(size int, sensitiveHeaders ...string) *Samples {
+	if size == 0 {
+		size = DefaultSampleSize
+	}
+	if size < 0 {
+		size = 0
+	}
+	return &Samples{
+		Size:             size,
+		Failures:         map[ErrorType][]Sample{},
+		Seen:             map[ErrorType]int{},
+		sensitiveHeaders: sensitiveHeaders,
+	}
+}
+
+// Add keeps the request if it is among the slowest, or is drawn as a sample of its ErrorType.
+// The details of the request are only copied if it is kept.
+func (s *Samples) Add(stat RequestStat) {
+	if s == nil || s.Size <= 0 {
+		return
+	}
+	if stat.ErrorType != "" {
+		if s.Failures == nil {
+			s.Failures = map[ErrorType][]Sample{}
+		}
+		if s.Seen == nil {
+			s.Seen = map[ErrorType]int{}
+		}
+		s.Seen[stat.ErrorType]++
+		list := s.Failures[stat.ErrorType]
+		if len(list) < s.Size {
+			s.Failures[stat.ErrorType] = append(list, newSample(stat, s.sensitiveHeaders))
+			return
+		}
+		// Reservoir-sampling (algorithm R), so that each failure has the same chance of being kept
+		if s.rand == nil {
+			s.rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+		}
+		if j := s.rand.Intn(s.Seen[stat.ErrorType]); j < s.Size {
+			list[j] = newSample(stat, s.sensitiveHeaders)
+		}
+		return
+	}
+	n := len(s.Slowest)
+	if n >= s.Size && stat.Duration <= s.Slowest[n-1].Timing.Total {
+		return
+	}
+	i := sort.Search(n, func(i int) bool { return s.Slowest[i].Timing.Total < stat.Duration })
+	s.Slowest = append(s.Slowest, Sample{})
+	copy(s.Slowest[i+1:], s.Slowest[i:])
+	s.Slowest[i] = newSample(stat, s.sensitiveHeaders)
+	if len(s.Slowest) > s.Size {
+		s.Slowest = s.Slowest[:s.Size]
+	}
+}
+
+func newSample(stat RequestStat, extraSensitiveHeaders []string) Sample {
+	sample := Sample{
+		RequestID:  stat.RequestID,
+		TraceID:    stat.TraceContext.TraceID,
+		ErrorType:  stat.ErrorType,
+		Error:      stat.Error,
+		StatusCode: int(stat.StatusCode),
+		Start:      stat.Start,
+		Timing:     Timing{Total: stat.Duration},
+	}
+	sample.Response.Body, sample.Response.Truncated = truncateBody(stat.RawResponse)
+	if t := stat.Trace; t != nil {
+		t.lock.Lock()
+		t.captureRequest()
+		sample.Timing = t.Timing
+		t.lock.Unlock()
+		sample.Timing.Total = stat.Duration
+		sample.Request = SampleRequest{
+			Method:  t.Method,
+			Url:     t.Url,
+			Headers: redactHeaders(t.RequestHeaders, extraSensitiveHeaders),
+		}
+		sample.Request.Body, sample.Request.Truncated = redactBody(t.RequestBody)
+		sample.Response.Headers = redactHeaders(t.ResponseHeaders, extraSensitiveHeaders)
+	}
+	return sample
+}
+
+func truncateBody(body []byte) (string, bool) {
+	if len(body) > maxSampleBodySize {
+		return string(body[:maxSampleBodySize]), true
+	}
+	return string(body), false
+}
+
+// IsSensitiveHeader returns whether the values of the header should be redacted, like Authorization
+func IsSensitiveHeader(name string) bool {
+	name = http.CanonicalHeaderKey(name)
+	for _, k := range sensitiveHeaders {
+		if k == name {
+			return true
+		}
+	}
+	return false
+}
+
+func redactHeaders(h http.Header, extraSensitiveHeaders []string) http.Header {
+	if h == nil {
+		return nil
+	}
+	h = h.Clone()
+	for _, list := range [][]string{sensitiveHeaders, extraSensitiveHeaders} {
+		for _, k := range list {
+			k = http.CanonicalHeaderKey(k)
+			if _, ok := h[k]; ok && k != "" {
+				h[k] = []string{redacted}
+			}
+		}
+	}
+	return h
+}
+
+func isSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, s := range sensitiveKeys {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func redactValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, vv := range t {
+			if isSensitiveKey(k) {
+				t[k] = redacted
+				continue
+			}
+			t[k] = redactValue(vv)
+		}
+	case []interface{}:
+		for i, vv := range t {
+			t[i] = redactValue(vv)
+		}
+	}
+	return v
+}
+
+// Redacts the values of sensitive keys in json-bodies, like the variables of a GraphQL-request.
+// Other bodies are kept as is. Either is truncated like the bodies of responses.
+func redactBody(body []byte) (string, bool) {
+	var j interface{}
+	if err := json.Unmarshal(body, &j); err != nil {
+		return truncateBody(body)
+	}
+	b, err := json.Marshal(redactValue(j))
+	if err != nil {
+		return "", false
+	}
+	return truncateBody(b)
+}
+
+// Headers which are set anew for each request, and are therefore not replayed
+var replayIgnoredHeaders = []string{"X-Request-Id", "Traceparent", "Connection", "Content-Length"}
+
+// RedactedHeaders returns the names of the headers which were redacted, like Authorization.
+// These are not replayed, so fresh credentials must be set instead.
+func (s SampleRequest) RedactedHeaders() []string {
+	var names []string
+	for k, v := range s.Headers {
+		if len(v) == 1 && v[0] == redacted {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// HasRedactedBody returns whether sensitive values in the body were redacted, in which case the replayed body differs from the original
+func (s SampleRequest) HasRedactedBody() bool {
+	return strings.Contains(s.Body, redacted)
+}
+
+// NewRequest recreates the request, with the same method, url, headers and rendered body, so that it can be sent again.
+// Redacted headers are left out. Requests with a truncated body are refused, since that would not be the same request.
+func (s SampleRequest) NewRequest() (*http.Request, error) {
+	if s.Url == "" {
+		return nil, fmt.Errorf("the sample has no request-details")
+	}
+	if s.Truncated {
+		return nil, fmt.Errorf("the body of the sample was truncated to %d bytes, so the request cannot be replayed", maxSampleBodySize)
+	}
+	method := s.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+	r, err := http.NewRequest(method, s.Url, strings.NewReader(s.Body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range s.Headers {
+		if len(v) == 1 && v[0] == redacted {
+			continue
+		}
+		r.Header[k] = append([]string(nil), v...)
+	}
+	for _, k := range replayIgnoredHeaders {
+		r.Header.Del(k)
+	}
+	return r, nil
+}
@@ -0,0 +1,171 @@
+package requests
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func sampleStat(id string, errorType ErrorType, ms int) RequestStat {
+	return RequestStat{
+		RequestID: id,
+		ErrorType: errorType,
+		Duration:  time.Duration(ms) * time.Millisecond,
+	}
+}
+
+func TestSamples_Add(t *testing.T) {
+	s := NewSamples(3)
+	for i, ms := range []int{50, 10, 70, 30, 90, 20} {
+		s.Add(sampleStat(string(rune('a'+i)), "", ms))
+	}
+	for i := 0; i < 25; i++ {
+		s.Add(sampleStat("t", "Timeout", 1000))
+	}
+	s.Add(sampleStat("x", "NonOK-500", 5))
+
+	tests := []struct {
+		name string
+		got  interface{}
+		want interface{}
+	}{
+		{"slowest count", len(s.Slowest), 3},
+		{"slowest first", s.Slowest[0].RequestID, "e"},
+		{"slowest second", s.Slowest[1].RequestID, "c"},
+		{"slowest third", s.Slowest[2].RequestID, "a"},
+		{"slowest timing", s.Slowest[0].Timing.Total, 90 * time.Millisecond},
+		{"failures are bounded", len(s.Failures["Timeout"]), 3},
+		{"failures seen", s.Seen["Timeout"], 25},
+		{"single failure", len(s.Failures["NonOK-500"]), 1},
+		{"failures are not among the slowest", s.Slowest[0].ErrorType, ErrorType("")},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+
+	disabled := NewSamples(-1)
+	disabled.Add(sampleStat("a", "", 10))
+	if len(disabled.Slowest) != 0 {
+		t.Errorf("expected a negative size to disable sampling")
+	}
+}
+
+func TestNewSample_Redacts(t *testing.T) {
+	stat := sampleStat("a", "Timeout", 10)
+	stat.RawResponse = []byte(`{"errors":[{"message":"timeout"}]}`)
+	stat.Trace = &RequestTrace{
+		Method:          "POST",
+		Url:             "https://example.com/graphql",
+		RequestHeaders:  http.Header{"Authorization": {"Bearer abc"}, "X-Custom-Auth": {"Bearer abc"}, "X-Request-Id": {"a"}},
+		RequestBody:     []byte(`{"query":"{ a }","variables":{"input":{"password":"hunter2","name":"bob"},"accessToken":"abc"}}`),
+		ResponseHeaders: http.Header{"Set-Cookie": {"session=abc"}},
+		Timing:          Timing{FirstByte: 8 * time.Millisecond},
+	}
+	sample := newSample(stat, []string{"x-custom-auth"})
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"authorization", sample.Request.Headers.Get("Authorization"), redacted},
+		{"custom auth-header", sample.Request.Headers.Get("X-Custom-Auth"), redacted},
+		{"request-id", sample.Request.Headers.Get("X-Request-Id"), "a"},
+		{"set-cookie", sample.Response.Headers.Get("Set-Cookie"), redacted},
+		{"response body", sample.Response.Body, string(stat.RawResponse)},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, tt.got, tt.want)
+		}
+	}
+	for _, secret := range []string{"hunter2", `"abc"`} {
+		if strings.Contains(sample.Request.Body, secret) {
+			t.Errorf("expected %s to be redacted from the body: %s", secret, sample.Request.Body)
+		}
+	}
+	if !strings.Contains(sample.Request.Body, "bob") {
+		t.Errorf("expected other values to be kept: %s", sample.Request.Body)
+	}
+	if stat.Trace.RequestHeaders.Get("Authorization") != "Bearer abc" {
+		t.Errorf("expected the original headers to be kept")
+	}
+	if sample.Timing.FirstByte != 8*time.Millisecond || sample.Timing.Total != 10*time.Millisecond {
+		t.Errorf("unexpected timing: %#v", sample.Timing)
+	}
+}
+
+func TestNewSample_CapturesRequestLazily(t *testing.T) {
+	r, _ := http.NewRequest("POST", "https://example.com/graphql", strings.NewReader(`{"query":"{ a }"}`))
+	r.Header.Set("X-Tenant", "t1")
+	stat := sampleStat("a", "Timeout", 10)
+	stat.Trace = newRequestTrace(r)
+	if stat.Trace.RequestHeaders != nil || stat.Trace.RequestBody != nil {
+		t.Fatalf("expected the request to only be captured when it is kept as a sample")
+	}
+	sample := newSample(stat, nil)
+	if sample.Request.Headers.Get("X-Tenant") != "t1" || sample.Request.Body != `{"query":"{ a }"}` {
+		t.Errorf("expected the headers and body to be captured, got %#v", sample.Request)
+	}
+}
+
+func TestSampleRequest_NewRequest(t *testing.T) {
+	s := SampleRequest{
+		Method: "PUT",
+		Url:    "https://example.com/graphql",
+		Headers: http.Header{
+			"Authorization": {redacted},
+			"X-Request-Id":  {"a"},
+			"Traceparent":   {"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"},
+			"X-Tenant":      {"t1"},
+		},
+		Body: `{"query":"{ a }"}`,
+	}
+	r, err := s.NewRequest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(r.Body)
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"method", r.Method, "PUT"},
+		{"url", r.URL.String(), s.Url},
+		{"body", string(body), s.Body},
+		{"header", r.Header.Get("X-Tenant"), "t1"},
+		{"redacted header", r.Header.Get("Authorization"), ""},
+		{"request-id", r.Header.Get("X-Request-Id"), ""},
+		{"traceparent", r.Header.Get("Traceparent"), ""},
+		{"redacted headers", strings.Join(s.RedactedHeaders(), ","), "Authorization"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, tt.got, tt.want)
+		}
+	}
+	if s.HasRedactedBody() {
+		t.Errorf("expected the body not to be redacted")
+	}
+	if _, err := (SampleRequest{}).NewRequest(); err == nil {
+		t.Errorf("expected an error for a sample without request-details")
+	}
+}
+
+func TestNewSample_TruncatesRequestBody(t *testing.T) {
+	large := `{"query":"` + strings.Repeat("a", maxSampleBodySize) + `"}`
+	r, _ := http.NewRequest("POST", "https://example.com/graphql", strings.NewReader(large))
+	stat := sampleStat("a", "Timeout", 10)
+	stat.Trace = newRequestTrace(r)
+	sample := newSample(stat, nil)
+	if !sample.Request.Truncated || len(sample.Request.Body) != maxSampleBodySize {
+		t.Fatalf("expected the body to be truncated to %d bytes, got %d (truncated: %v)", maxSampleBodySize, len(sample.Request.Body), sample.Request.Truncated)
+	}
+	if _, err := sample.Request.NewRequest(); err == nil {
+		t.Errorf("expected a truncated request not to be replayed")
+	}
+}
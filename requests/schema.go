@@ -0,0 +1,253 @@
+package requests
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// ResponseSchema is a JSON Schema which json-responses are validated against.
+// Each violation is bucketed by the JSON pointer of the failing value, like `SchemaViolation-/data/user/email`,
+// with array-indexes collapsed, like `SchemaViolation-/data/items/*/id`
+type ResponseSchema struct {
+	// The schema, either as an object or a json-string. It is kept as json, since stored requests cannot hold interface-values.
+	// Keys in config-files are lower-cased when read, so there a json-string or File should be used.
+	Schema json.RawMessage `json:"schema,omitempty"`
+	// Path to a file containing the schema. Used if Schema is not set.
+	File string `json:"file,omitempty"`
+	// Fraction of the responses to validate, between 0 and 1. Defaults to 1 (all responses)
+	SampleRate float64 `json:"sampleRate,omitempty"`
+}
+
+type SchemaViolation struct {
+	// JSON pointer to the failing value within the response
+	Pointer string `json:"pointer"`
+	// The failing keyword within the schema, like required or type
+	Keyword string `json:"keyword"`
+	Message string `json:"message"`
+}
+
+// SchemaViolations for each response, by the hash of the response, like the ByteHashMap
+type SchemaViolationMap map[[32]byte][]SchemaViolation
+
+// SchemaViolationBreakdown counts every violation by its pointer-pattern,
+// since a request is only bucketed by its first violation
+type SchemaViolationBreakdown struct {
+	// Number of violations, which may be more than one per response
+	Total     int            `json:"total"`
+	ByPointer map[string]int `json:"byPointer,omitempty"`
+}
+
+func (s ResponseSchema) key() string {
+	if len(s.Schema) == 0 {
+		return "file:" + s.File
+	}
+	return "inline:" + string(s.Schema)
+}
+
+// The inline schema, which may be wrapped in a json-string
+func (s ResponseSchema) inline() (string, error) {
+	if !bytes.HasPrefix(bytes.TrimSpace(s.Schema), []byte(`"`)) {
+		return string(s.Schema), nil
+	}
+	var str string
+	if err := json.Unmarshal(s.Schema, &str); err != nil {
+		return "", fmt.Errorf("response-schema could not be read: %w", err)
+	}
+	return str, nil
+}
+
+// Validate checks that the schema can be read and compiled
+func (s ResponseSchema) Validate() error {
+	if s.SampleRate < 0 || s.SampleRate > 1 {
+		return fmt.Errorf("response-schema: sampleRate must be between 0 and 1, got %f", s.SampleRate)
+	}
+	_, err := s.compile()
+	return err
+}
+
+var schemaCache = map[string]*jsonschema.Schema{}
+
+func (s ResponseSchema) compile() (*jsonschema.Schema, error) {
+	key := s.key()
+	compileLock.RLock()
+	schema, ok := schemaCache[key]
+	compileLock.RUnlock()
+	if ok {
+		return schema, nil
+	}
+	var err error
+	switch {
+	case len(s.Schema) > 0:
+		var str string
+		if str, err = s.inline(); err != nil {
+			return nil, err
+		}
+		schema, err = jsonschema.CompileString("schema.json", str)
+	case s.File != "":
+		schema, err = jsonschema.Compile(s.File)
+	default:
+		return nil, fmt.Errorf("response-schema: schema or file is required")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("response-schema failed to compile: %w", err)
+	}
+	compileLock.Lock()
+	schemaCache[key] = schema
+	compileLock.Unlock()
+	return schema, nil
+}
+
+func (s ResponseSchema) sampled() bool {
+	return s.SampleRate == 0 || s.SampleRate >= 1 || rand.Float64() < s.SampleRate
+}
+
+// Returns the violations of the body, sorted by pointer
+func (s ResponseSchema) validateBody(body []byte) ([]SchemaViolation, error) {
+	schema, err := s.compile()
+	if err != nil {
+		return nil, err
+	}
+	var JSON interface{}
+	d := json.NewDecoder(bytes.NewReader(body))
+	d.UseNumber()
+	if err := d.Decode(&JSON); err != nil {
+		return []SchemaViolation{{Pointer: "", Keyword: "json", Message: "body is not valid json: " + err.Error()}}, nil
+	}
+	err = schema.Validate(JSON)
+	if err == nil {
+		return nil, nil
+	}
+	ve, ok := err.(*jsonschema.ValidationError)
+	if !ok {
+		return nil, err
+	}
+	violations := flattenViolations(ve, nil)
+	sort.SliceStable(violations, func(i, j int) bool {
+		return violations[i].Pointer < violations[j].Pointer
+	})
+	return violations, nil
+}
+
+// matches the quoted property-names in messages like: missing properties: 'email', 'name'
+var missingPropertyRegex = regexp.MustCompile(`'((?:[^'\\]|\\.)*)'`)
+
+// Only the leaf-errors are of interest, the rest are summaries
+func flattenViolations(ve *jsonschema.ValidationError, violations []SchemaViolation) []SchemaViolation {
+	if len(ve.Causes) > 0 {
+		for _, c := range ve.Causes {
+			violations = flattenViolations(c, violations)
+		}
+		return violations
+	}
+	keyword := ve.KeywordLocation
+	if i := strings.LastIndex(keyword, "/"); i >= 0 {
+		keyword = keyword[i+1:]
+	}
+	if keyword != "required" {
+		return append(violations, SchemaViolation{ve.InstanceLocation, keyword, ve.Message})
+	}
+	// The pointer should be to the missing property, not its parent,
+	// so that the violations are bucketed by the property.
+	for _, m := range missingPropertyRegex.FindAllStringSubmatch(ve.Message, -1) {
+		name := strings.ReplaceAll(m[1], `\'`, `'`)
+		name = strings.NewReplacer("~", "~0", "/", "~1").Replace(name)
+		violations = append(violations, SchemaViolation{ve.InstanceLocation + "/" + name, keyword, "missing property"})
+	}
+	return violations
+}
+
+// PointerPattern returns the pointer with array-indexes collapsed, like /data/items/*/id,
+// so that violations from different items in an array are counted together.
+func (v SchemaViolation) PointerPattern() string {
+	if v.Pointer == "" {
+		return "(root)"
+	}
+	tokens := strings.Split(v.Pointer, "/")
+	for i, t := range tokens {
+		if i > 0 && isArrayIndex(t) {
+			tokens[i] = "*"
+		}
+	}
+	return strings.Join(tokens, "/")
+}
+
+func isArrayIndex(token string) bool {
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return false
+	}
+	for _, r := range token {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func (v SchemaViolation) ErrorType() ErrorType {
+	return ErrorType(fmt.Sprintf("%s-%s", SchemaViolationError, v.PointerPattern()))
+}
+
+// Add counts all the violations of a response
+func (b *SchemaViolationBreakdown) Add(violations []SchemaViolation) {
+	if len(violations) == 0 {
+		return
+	}
+	if b.ByPointer == nil {
+		b.ByPointer = map[string]int{}
+	}
+	for _, v := range violations {
+		b.Total++
+		b.ByPointer[v.PointerPattern()]++
+	}
+}
+
+// Merge adds the counts of another breakdown, like the one of a previous part of the run
+func (b *SchemaViolationBreakdown) Merge(other SchemaViolationBreakdown) {
+	b.Total += other.Total
+	if len(other.ByPointer) > 0 && b.ByPointer == nil {
+		b.ByPointer = map[string]int{}
+	}
+	for pointer, n := range other.ByPointer {
+		b.ByPointer[pointer] += n
+	}
+}
+
+func (c SchemaViolationMap) Add(hash Hash, violations []SchemaViolation) {
+	if c == nil || len(violations) == 0 {
+		return
+	}
+	c[hash] = violations
+}
+
+func (c SchemaViolationMap) MarshalJSON() ([]byte, error) {
+	u := map[string][]SchemaViolation{}
+	for kb, vb := range c {
+		u[base64.URLEncoding.EncodeToString(kb[:])] = vb
+	}
+	return json.Marshal(u)
+}
+
+func (c *SchemaViolationMap) UnmarshalJSON(b []byte) error {
+	var u map[string][]SchemaViolation
+	if err := json.Unmarshal(b, &u); err != nil {
+		return err
+	}
+	m := SchemaViolationMap{}
+	for k, v := range u {
+		h, err := ParseHash(k)
+		if err != nil {
+			return err
+		}
+		m[h] = v
+	}
+	*c = m
+	return nil
+}
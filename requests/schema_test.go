@@ -0,0 +1,110 @@
+package requests
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/runar-rkmedia/gabyoall/internal"
+)
+
+const userSchema = `{
+  "type": "object",
+  "required": ["data"],
+  "properties": {
+    "data": {
+      "type": "object",
+      "required": ["user"],
+      "properties": {
+        "user": {
+          "type": "object",
+          "required": ["id", "email"],
+          "properties": {
+            "id": {"type": "integer"},
+            "email": {"type": "string"},
+            "roles": {"type": "array", "items": {"type": "string"}}
+          }
+        }
+      }
+    }
+  }
+}`
+
+func TestResponseSchema_validateBody(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema ResponseSchema
+		body   string
+		want   []SchemaViolation
+	}{
+		{
+			"valid response",
+			ResponseSchema{Schema: json.RawMessage(userSchema)},
+			`{"data": {"user": {"id": 1, "email": "john@example.com"}}}`,
+			nil,
+		},
+		{
+			"missing property is bucketed by its own pointer",
+			ResponseSchema{Schema: json.RawMessage(userSchema)},
+			`{"data": {"user": {"id": 1}}}`,
+			[]SchemaViolation{{"/data/user/email", "required", "missing property"}},
+		},
+		{
+			"multiple violations are sorted by pointer",
+			ResponseSchema{Schema: json.RawMessage(userSchema)},
+			`{"data": {"user": {"id": "1", "roles": ["admin", 2]}}}`,
+			[]SchemaViolation{
+				{"/data/user/email", "required", "missing property"},
+				{"/data/user/id", "type", "expected integer, but got string"},
+				{"/data/user/roles/1", "type", "expected string, but got number"},
+			},
+		},
+		{
+			"schema as json-string",
+			ResponseSchema{Schema: json.RawMessage(`"{\"type\": \"array\", \"maxItems\": 1}"`)},
+			`[1, 2]`,
+			[]SchemaViolation{{"", "maxItems", "maximum 1 items required, but found 2 items"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.schema.Validate(); err != nil {
+				t.Fatalf("ResponseSchema.Validate() error = %v", err)
+			}
+			got, err := tt.schema.validateBody([]byte(tt.body))
+			if err != nil {
+				t.Fatalf("ResponseSchema.validateBody() error = %v", err)
+			}
+			if err := internal.Compare("validateBody()", got, tt.want); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestSchemaViolationBreakdown_Add(t *testing.T) {
+	var b SchemaViolationBreakdown
+	violations := []SchemaViolation{
+		{"/data/items/0/id", "type", "expected integer, but got string"},
+		{"/data/items/12/id", "type", "expected integer, but got string"},
+		{"/data/items/1/0x", "required", "missing property"},
+		{"/data/01", "type", "expected string, but got number"},
+		{"", "type", "expected object, but got array"},
+	}
+	b.Add(violations)
+	b.Add(nil)
+	want := SchemaViolationBreakdown{
+		Total: 5,
+		ByPointer: map[string]int{
+			"/data/items/*/id": 2,
+			"/data/items/*/0x": 1,
+			"/data/01":         1,
+			"(root)":           1,
+		},
+	}
+	if err := internal.Compare("Add()", b, want); err != nil {
+		t.Error(err)
+	}
+	if got := violations[1].ErrorType(); got != "SchemaViolation-/data/items/*/id" {
+		t.Errorf("ErrorType() = %q, want %q", got, "SchemaViolation-/data/items/*/id")
+	}
+}
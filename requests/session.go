@@ -0,0 +1,125 @@
+package requests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+
+	"github.com/jmespath/go-jmespath"
+	"github.com/runar-rkmedia/gabyoall/logger"
+	"github.com/runar-rkmedia/gabyoall/utils"
+)
+
+// VirtualUser is the state of a single worker, which persists across its iterations.
+type VirtualUser struct {
+	ID int
+	// Number of requests made since the state was last reset.
+	Iteration int
+	Jar       http.CookieJar
+	// Values captured from responses. Available in templating as {{ .VU.Vars.key }}
+	Vars map[string]interface{}
+	// Cookies which the jar is seeded with, for instance from a login-step.
+	seedUrl     *url.URL
+	seedCookies []*http.Cookie
+}
+
+func NewVirtualUser(id int, seedUrl string, seedCookies []*http.Cookie) *VirtualUser {
+	vu := VirtualUser{
+		ID:          id,
+		seedCookies: seedCookies,
+	}
+	if len(seedCookies) > 0 {
+		if !strings.Contains(seedUrl, "://") {
+			seedUrl = "https://" + seedUrl
+		}
+		vu.seedUrl, _ = url.Parse(seedUrl)
+	}
+	vu.Reset()
+	return &vu
+}
+
+// Reset clears the cookies and variables, as if a new user arrived.
+func (vu *VirtualUser) Reset() {
+	// cookiejar.New only errors if options.PublicSuffixList errors
+	vu.Jar, _ = cookiejar.New(nil)
+	vu.Vars = map[string]interface{}{}
+	vu.Iteration = 0
+	if vu.seedUrl == nil {
+		return
+	}
+	// The cookies may have been set by a different host, for instance an auth-server,
+	// so they are added as host-only cookies for the endpoint.
+	cookies := make([]*http.Cookie, len(vu.seedCookies))
+	for i, c := range vu.seedCookies {
+		cookie := *c
+		cookie.Domain = ""
+		cookies[i] = &cookie
+	}
+	vu.Jar.SetCookies(vu.seedUrl, cookies)
+}
+
+// Next starts the next iteration of the user.
+// With resetEvery, the state is reset every n iterations, as if a new user arrived. Zero disables.
+func (vu *VirtualUser) Next(resetEvery int) {
+	if resetEvery > 0 && vu.Iteration >= resetEvery {
+		vu.Reset()
+	}
+	vu.Iteration++
+}
+
+// Stores values from the json-body into the variables, as described by capture (name: jmes-path)
+func (vu *VirtualUser) capture(l logger.AppLogger, capture map[string]string, contentType string, body []byte) {
+	if vu == nil || len(capture) == 0 || len(body) == 0 || !strings.Contains(contentType, "json") {
+		return
+	}
+	var JSON interface{}
+	if err := json.Unmarshal(body, &JSON); err != nil {
+		return
+	}
+	for name, jmesPath := range capture {
+		result, err := jmespath.Search(jmesPath, JSON)
+		if err != nil {
+			l.ErrWarn(err).Str("name", name).Str("jmesPath", jmesPath).Msg("Failed to capture value from response")
+			continue
+		}
+		if result != nil {
+			vu.Vars[name] = result
+		}
+	}
+}
+
+// The values available while templating a request
+type templateVars struct {
+	Vars map[string]string
+	VU   *VirtualUser
+}
+
+func renderTemplate(l logger.AppLogger, s, name string, vars templateVars) string {
+	if !strings.Contains(s, "{{") {
+		return s
+	}
+	return utils.RunTemplating(l, s, name, vars)
+}
+
+// Renders string-values within the graphql-variables
+func renderVariables(l logger.AppLogger, v interface{}, vars templateVars) interface{} {
+	switch value := v.(type) {
+	case string:
+		return renderTemplate(l, value, "variable", vars)
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(value))
+		for k, vv := range value {
+			m[k] = renderVariables(l, vv, vars)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(value))
+		for i, vv := range value {
+			s[i] = renderVariables(l, vv, vars)
+		}
+		return s
+	}
+	return v
+}
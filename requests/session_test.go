@@ -0,0 +1,102 @@
+package requests
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/runar-rkmedia/gabyoall/logger"
+)
+
+// Starts a new session for requests without a session-cookie, and echoes the session and the X-Echo-header
+func newSessionServer(t *testing.T) *httptest.Server {
+	var sessions int32
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		session := ""
+		if c, err := r.Cookie("session"); err == nil {
+			session = c.Value
+		} else {
+			session = fmt.Sprintf("s%d", atomic.AddInt32(&sessions, 1))
+			http.SetCookie(rw, &http.Cookie{Name: "session", Value: session})
+		}
+		rw.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(rw, `{"data": {"session": %q, "echo": %q}}`, session, r.Header.Get("X-Echo"))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestVirtualUser(t *testing.T) {
+	query := Request{
+		Query:   "{ session }",
+		Capture: map[string]string{"session": "data.session", "echo": "data.echo"},
+		// The session captured by the previous request
+		Headers: map[string]string{"X-Echo": "{{ with .VU.Vars.session }}{{ . }}{{ end }}"},
+	}
+	tests := []struct {
+		name        string
+		seedCookies []*http.Cookie
+		resetEvery  int
+		iterations  int
+		// The session and echo captured by each iteration
+		want [][2]string
+	}{
+		{
+			"Should keep the session-cookie and variables across iterations",
+			nil, 0, 3,
+			[][2]string{{"s1", ""}, {"s1", "s1"}, {"s1", "s1"}},
+		},
+		{
+			"Should seed the cookie-jar, also with cookies of other hosts",
+			[]*http.Cookie{{Name: "session", Value: "login", Domain: "auth.example.com"}}, 0, 2,
+			[][2]string{{"login", ""}, {"login", "login"}},
+		},
+		{
+			"Should drop the cookies and variables when reset",
+			nil, 2, 4,
+			[][2]string{{"s1", ""}, {"s1", "s1"}, {"s2", ""}, {"s2", "s2"}},
+		},
+		{
+			"Should seed the cookie-jar again when reset",
+			[]*http.Cookie{{Name: "session", Value: "login"}}, 1, 2,
+			[][2]string{{"login", ""}, {"login", ""}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newSessionServer(t)
+			ts := NewTimeSeriesWithLabel(time.Now())
+			g := NewEndpoint(logger.GetLogger("test"), srv.URL, &ts)
+			vu := NewVirtualUser(1, srv.URL, tt.seedCookies)
+			for i := 0; i < tt.iterations; i++ {
+				vu.Next(tt.resetEvery)
+				_, stat, err := g.RunQuery(time.Now(), query, nil, vu)
+				if err != nil || stat.ErrorType != "" {
+					t.Fatalf("RunQuery() failed with %q: %v", stat.ErrorType, err)
+				}
+				got := [2]string{fmt.Sprint(vu.Vars["session"]), fmt.Sprint(vu.Vars["echo"])}
+				if got != tt.want[i] {
+					t.Errorf("iteration %d: captured %v, want %v", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestVirtualUser_separate(t *testing.T) {
+	srv := newSessionServer(t)
+	ts := NewTimeSeriesWithLabel(time.Now())
+	g := NewEndpoint(logger.GetLogger("test"), srv.URL, &ts)
+	query := Request{Query: "{ session }", Capture: map[string]string{"session": "data.session"}}
+	a, b := NewVirtualUser(1, srv.URL, nil), NewVirtualUser(2, srv.URL, nil)
+	for _, vu := range []*VirtualUser{a, b, a, b} {
+		vu.Next(0)
+		g.RunQuery(time.Now(), query, nil, vu)
+	}
+	if a.Vars["session"] != "s1" || b.Vars["session"] != "s2" {
+		t.Errorf("expected each virtual user to keep its own session, got %v and %v", a.Vars, b.Vars)
+	}
+}
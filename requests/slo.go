@@ -0,0 +1,106 @@
+package requests
+
+import (
+	"fmt"
+	"time"
+)
+
+// SLO is a service-level objective, which each run is scored against.
+// Endpoints and requests may both declare one; the one on the request wins.
+type SLO struct {
+	// Successful requests within this duration are satisfied, and within four times it are tolerated, like 300ms.
+	// Used for the Apdex-score.
+	LatencyTarget string `json:"latency_target" mapstructure:"latency_target"`
+	// Percentage of the successful requests which should be within the latency-target, like 95. Defaults to 95.
+	LatencyObjective float64 `json:"latency_objective,omitempty" mapstructure:"latency_objective"`
+	// Percentage of requests which should succeed, like 99.9
+	Availability float64 `json:"availability,omitempty"`
+}
+
+// DefaultLatencyObjective is used if the SLO does not set a LatencyObjective
+const DefaultLatencyObjective = 95
+
+// SLOResult is the score of a single run against its SLO
+type SLOResult struct {
+	SLO
+	// (satisfied + tolerating/2) / total, between 0 and 1
+	Apdex      float64 `json:"apdex"`
+	Satisfied  int64   `json:"satisfied"`
+	Tolerating int64   `json:"tolerating"`
+	// Slower than four times the latency-target, or failed
+	Frustrated int64 `json:"frustrated"`
+	// Percentage of the requests which succeeded
+	ActualAvailability float64 `json:"actual_availability"`
+	// Percentage of the error-budget used, which is the allowed failures by the availability-target. Above 100 means it is exceeded.
+	ErrorBudgetUsed float64 `json:"error_budget_used"`
+	// Percentage of the successful requests which were within the latency-target
+	WithinLatencyTarget float64 `json:"within_latency_target"`
+	// Set if the run is within the availability-target
+	AvailabilityCompliant bool `json:"availability_compliant"`
+	// Set if the run is within the latency-objective
+	LatencyCompliant bool `json:"latency_compliant"`
+	// Set if the run is within both the availability-target and the latency-objective
+	Compliant bool `json:"compliant"`
+}
+
+func (s SLO) Validate() error {
+	d, err := time.ParseDuration(s.LatencyTarget)
+	if err != nil {
+		return fmt.Errorf("slo: latency_target: %w", err)
+	}
+	if d <= 0 {
+		return fmt.Errorf("slo: latency_target must be positive")
+	}
+	if s.Availability < 0 || s.Availability > 100 {
+		return fmt.Errorf("slo: availability must be a percentage between 0 and 100")
+	}
+	if s.LatencyObjective < 0 || s.LatencyObjective > 100 {
+		return fmt.Errorf("slo: latency_objective must be a percentage between 0 and 100")
+	}
+	return nil
+}
+
+// Evaluate scores the run, by the latencies of each ErrorType. The successful requests are in the histogram for the empty ErrorType.
+// Nil is returned if the SLO is nil or invalid, or there are no requests.
+func (s *SLO) Evaluate(histograms map[ErrorType]*Histogram) *SLOResult {
+	if s == nil || s.Validate() != nil {
+		return nil
+	}
+	var total int64
+	for _, h := range histograms {
+		total += h.Count()
+	}
+	if total == 0 {
+		return nil
+	}
+	target, _ := time.ParseDuration(s.LatencyTarget)
+	success := histograms[""]
+	r := SLOResult{SLO: *s}
+	if r.LatencyObjective == 0 {
+		r.LatencyObjective = DefaultLatencyObjective
+	}
+	r.Satisfied = success.CountAtOrBelow(target)
+	r.Tolerating = success.CountAtOrBelow(4*target) - r.Satisfied
+	r.Frustrated = total - r.Satisfied - r.Tolerating
+	r.Apdex = (float64(r.Satisfied) + float64(r.Tolerating)/2) / float64(total)
+	r.ActualAvailability = float64(success.Count()) / float64(total) * 100
+	if n := success.Count(); n > 0 {
+		r.WithinLatencyTarget = float64(r.Satisfied) / float64(n) * 100
+	}
+	r.AvailabilityCompliant = r.ActualAvailability >= s.Availability
+	r.LatencyCompliant = success.Count() > 0 && r.WithinLatencyTarget >= r.LatencyObjective
+	r.Compliant = r.AvailabilityCompliant && r.LatencyCompliant
+	// With an availability-target of 100, there is no budget to use
+	if budget := 100 - s.Availability; budget > 0 {
+		r.ErrorBudgetUsed = (100 - r.ActualAvailability) / budget * 100
+	}
+	return &r
+}
+
+// PickSLO returns the SLO of the request if it has one, otherwise the SLO of the endpoint.
+func PickSLO(endpoint, request *SLO) *SLO {
+	if request != nil {
+		return request
+	}
+	return endpoint
+}
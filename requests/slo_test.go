@@ -0,0 +1,132 @@
+package requests
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func histogramOf(ms ...int) *Histogram {
+	h := NewHistogram()
+	for _, v := range ms {
+		h.Record(time.Duration(v) * time.Millisecond)
+	}
+	return h
+}
+
+func TestSLO_Evaluate(t *testing.T) {
+	tests := []struct {
+		name           string
+		slo            *SLO
+		histograms     map[ErrorType]*Histogram
+		wantNil        bool
+		wantApdex      float64
+		wantSatisfied  int64
+		wantTolerating int64
+		wantFrustrated int64
+		wantAvail      float64
+		wantBudget     float64
+		wantCompliant  bool
+	}{
+		{
+			name:    "no slo",
+			wantNil: true,
+		},
+		{
+			name:       "invalid slo",
+			slo:        &SLO{LatencyTarget: "fast"},
+			histograms: map[ErrorType]*Histogram{"": histogramOf(10)},
+			wantNil:    true,
+		},
+		{
+			name:       "no requests",
+			slo:        &SLO{LatencyTarget: "100ms"},
+			histograms: map[ErrorType]*Histogram{},
+			wantNil:    true,
+		},
+		{
+			name:           "all satisfied",
+			slo:            &SLO{LatencyTarget: "100ms", Availability: 99},
+			histograms:     map[ErrorType]*Histogram{"": histogramOf(10, 50, 100)},
+			wantApdex:      1,
+			wantSatisfied:  3,
+			wantAvail:      100,
+			wantCompliant:  true,
+			wantTolerating: 0,
+		},
+		{
+			name: "mixed",
+			slo:  &SLO{LatencyTarget: "100ms", LatencyObjective: 50, Availability: 90},
+			histograms: map[ErrorType]*Histogram{
+				"":        histogramOf(10, 20, 30, 40, 50, 200, 300, 500, 1000),
+				"Timeout": histogramOf(30000),
+			},
+			// (5 + 2/2) / 10
+			wantApdex:      0.6,
+			wantSatisfied:  5,
+			wantTolerating: 2,
+			wantFrustrated: 3,
+			wantAvail:      90,
+			wantBudget:     100,
+			wantCompliant:  true,
+		},
+		{
+			name: "breached",
+			slo:  &SLO{LatencyTarget: "100ms", Availability: 99},
+			histograms: map[ErrorType]*Histogram{
+				"":        histogramOf(10, 20, 30),
+				"Timeout": histogramOf(30000),
+			},
+			wantApdex:      0.75,
+			wantSatisfied:  3,
+			wantFrustrated: 1,
+			wantAvail:      75,
+			wantBudget:     2500,
+		},
+		{
+			name:           "latency breached",
+			slo:            &SLO{LatencyTarget: "100ms", Availability: 99},
+			histograms:     map[ErrorType]*Histogram{"": histogramOf(10, 200, 300, 500)},
+			wantApdex:      0.5,
+			wantSatisfied:  1,
+			wantTolerating: 2,
+			wantFrustrated: 1,
+			wantAvail:      100,
+		},
+		{
+			name:       "invalid latency-objective",
+			slo:        &SLO{LatencyTarget: "100ms", LatencyObjective: 101},
+			histograms: map[ErrorType]*Histogram{"": histogramOf(10)},
+			wantNil:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.slo.Evaluate(tt.histograms)
+			if tt.wantNil {
+				if got != nil {
+					t.Errorf("expected nil, got %#v", got)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatal("expected a result")
+			}
+			if math.Abs(got.Apdex-tt.wantApdex) > 1e-9 {
+				t.Errorf("Apdex: got %v, want %v", got.Apdex, tt.wantApdex)
+			}
+			if got.Satisfied != tt.wantSatisfied || got.Tolerating != tt.wantTolerating || got.Frustrated != tt.wantFrustrated {
+				t.Errorf("got %d/%d/%d, want %d/%d/%d", got.Satisfied, got.Tolerating, got.Frustrated, tt.wantSatisfied, tt.wantTolerating, tt.wantFrustrated)
+			}
+			if math.Abs(got.ActualAvailability-tt.wantAvail) > 1e-9 {
+				t.Errorf("ActualAvailability: got %v, want %v", got.ActualAvailability, tt.wantAvail)
+			}
+			if math.Abs(got.ErrorBudgetUsed-tt.wantBudget) > 1e-6 {
+				t.Errorf("ErrorBudgetUsed: got %v, want %v", got.ErrorBudgetUsed, tt.wantBudget)
+			}
+			if got.Compliant != tt.wantCompliant {
+				t.Errorf("Compliant: got %v, want %v", got.Compliant, tt.wantCompliant)
+			}
+		})
+	}
+}
@@ -15,6 +15,24 @@ type RequestStat struct {
 	Start       time.Time `json:"-"`
 	RequestID   string
 	Duration    time.Duration `json:"duration,omitempty"`
+	// Set if the response did not match the ResponseSchema of the request
+	SchemaViolations []SchemaViolation `json:"-"`
+	// Set if the response did not match the golden response of the request
+	GoldenDiff []GoldenChange `json:"-"`
+	// All the GraphQL-errors of the response, also when partial data was accepted
+	GqlErrors []Error `json:"-"`
+	// Set if the response had GraphQL-errors, but was accepted since it had partial data
+	PartialData bool `json:"-"`
+	// Rules for normalizing the response before it is hashed, from the endpoint and the request
+	Normalization []NormalizationRule `json:"-"`
+	// Set when the request was sent, so that it is counted as in flight until it ends
+	inFlight bool
+	// Details of the request and response, for samples of slow and failing requests
+	Trace *RequestTrace `json:"-"`
+	// ID of the virtual user (worker) which made the request
+	WorkerID int `json:"-"`
+	// The client-span of the request, which was sent in the traceparent-header
+	TraceContext TraceContext `json:"-"`
 	CompactStat
 }
 
@@ -23,7 +41,14 @@ func (r *RequestStat) End(body []byte, errorType ErrorType, err error) RequestSt
 	endTime := time.Now()
 	r.Duration = endTime.Sub(r.Start)
 	r.ts.Push(string(errorType), endTime, float64(r.Duration))
+	if tr, ok := r.ts.(throughputRecorder); ok && r.inFlight {
+		r.inFlight = false
+		tr.RequestDone(string(errorType), endTime, len(body))
+	}
 	r.RawResponse = body
+	if r.Trace != nil {
+		r.Trace.end(r.Duration)
+	}
 	if err != nil {
 		r.Error = err.Error()
 	}
@@ -37,6 +62,61 @@ type Stats struct {
 	Min     time.Duration
 	Max     time.Duration
 	Average time.Duration
+	// Number of requests
+	Count int
+	// Calculated from a Histogram
+	Percentiles Percentiles
+}
+
+// Add updates the stats with the duration of a single request
+func (c *Stats) Add(d time.Duration) {
+	if c.Count == 0 || d < c.Min {
+		c.Min = d
+	}
+	if d > c.Max {
+		c.Max = d
+	}
+	c.Total += d
+	c.Count++
+	c.Average = c.Total / time.Duration(c.Count)
+}
+
+// Merge adds the stats of another part of the run. The percentiles must be set again afterwards.
+func (c *Stats) Merge(other Stats) {
+	if other.Count == 0 {
+		return
+	}
+	if c.Count == 0 || other.Min < c.Min {
+		c.Min = other.Min
+	}
+	if other.Max > c.Max {
+		c.Max = other.Max
+	}
+	c.Total += other.Total
+	c.Count += other.Count
+	c.Average = c.Total / time.Duration(c.Count)
+}
+
+// SetPercentiles sets the percentiles, limited to the exact Min and Max,
+// since the histogram only knows the values to within its precision.
+func (c *Stats) SetPercentiles(p Percentiles) {
+	for _, d := range []*time.Duration{&p.P50, &p.P75, &p.P90, &p.P95, &p.P99, &p.P999} {
+		if *d < c.Min {
+			*d = c.Min
+		}
+		if *d > c.Max {
+			*d = c.Max
+		}
+	}
+	c.Percentiles = p
+}
+
+// started marks the request as sent
+func (r *RequestStat) started() {
+	if tr, ok := r.ts.(throughputRecorder); ok {
+		r.inFlight = true
+		tr.RequestStarted(time.Now())
+	}
 }
 
 func NewStat(offset time.Duration, ts TimeSeriePusher) RequestStat {
@@ -51,15 +131,19 @@ func NewStat(offset time.Duration, ts TimeSeriePusher) RequestStat {
 
 func (c *Stats) MarshalJSON() ([]byte, error) {
 	t := struct {
-		Total   int64 `json:"total,omitempty"`
-		Min     int64 `json:"min,omitempty"`
-		Max     int64 `json:"max,omitempty"`
-		Average int64 `json:"average,omitempty"`
+		Total       int64       `json:"total,omitempty"`
+		Min         int64       `json:"min,omitempty"`
+		Max         int64       `json:"max,omitempty"`
+		Average     int64       `json:"average,omitempty"`
+		Count       int         `json:"count,omitempty"`
+		Percentiles Percentiles `json:"percentiles"`
 	}{
-		Total:   c.Total.Milliseconds(),
-		Min:     c.Min.Milliseconds(),
-		Max:     c.Max.Milliseconds(),
-		Average: c.Average.Milliseconds(),
+		Count:       c.Count,
+		Total:       c.Total.Milliseconds(),
+		Min:         c.Min.Milliseconds(),
+		Max:         c.Max.Milliseconds(),
+		Average:     c.Average.Milliseconds(),
+		Percentiles: c.Percentiles,
 	}
 	return json.Marshal(t)
 
@@ -71,7 +155,10 @@ type GqlResponse struct {
 }
 
 type Error struct {
-	Message string `json:"message"`
+	Message    string                 `json:"message"`
+	Path       []interface{}          `json:"path,omitempty"`
+	Locations  []Location             `json:"locations,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
 }
 
 type RequestStats []RequestStat
@@ -0,0 +1,189 @@
+package requests
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// ThroughputSeries has per-second series for a run: completed requests, overall and by label,
+// bytes received and the number of requests in flight.
+// Seeing these next to the latencies shows where a service saturates.
+type ThroughputSeries struct {
+	StartTime time.Time
+	// When the last request was completed
+	EndTime time.Time
+	// Completed requests for each second since StartTime
+	Completed []int64
+	// Completed requests for each second, by label (ErrorType)
+	CompletedByLabel map[string][]int64
+	// Bytes received for each second
+	BytesReceived []int64
+	// The highest number of requests in flight during each second
+	InFlight []int64
+	inFlight int64
+	lock     sync.Mutex
+}
+
+// Used for recording requests which are started and completed. Implemented by TimeSeriesMap.
+type throughputRecorder interface {
+	RequestStarted(t time.Time)
+	RequestDone(label string, t time.Time, bytes int)
+}
+
+func NewThroughputSeries(startTime time.Time) *ThroughputSeries {
+	return &ThroughputSeries{
+		StartTime:        startTime,
+		CompletedByLabel: map[string][]int64{},
+	}
+}
+
+// Returns the index of the second, growing the series as needed. Must be called with the lock held.
+func (s *ThroughputSeries) second(t time.Time) int {
+	i := int(t.Sub(s.StartTime) / time.Second)
+	if i < 0 {
+		i = 0
+	}
+	for len(s.Completed) <= i {
+		s.Completed = append(s.Completed, 0)
+		s.BytesReceived = append(s.BytesReceived, 0)
+		// Requests which are still in flight, are in flight in the new second too
+		s.InFlight = append(s.InFlight, s.inFlight)
+	}
+	return i
+}
+
+func (s *ThroughputSeries) RequestStarted(t time.Time) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	i := s.second(t)
+	s.inFlight++
+	if s.inFlight > s.InFlight[i] {
+		s.InFlight[i] = s.inFlight
+	}
+}
+
+func (s *ThroughputSeries) RequestDone(label string, t time.Time, bytes int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	i := s.second(t)
+	s.inFlight--
+	if t.After(s.EndTime) {
+		s.EndTime = t
+	}
+	s.Completed[i]++
+	s.BytesReceived[i] += int64(bytes)
+	byLabel := s.CompletedByLabel[label]
+	for len(byLabel) <= i {
+		byLabel = append(byLabel, 0)
+	}
+	byLabel[i]++
+	s.CompletedByLabel[label] = byLabel
+}
+
+// Prepend puts the seconds of a previous series before the seconds of this one, like when a run is resumed.
+// The time between the series is left out, so the StartTime is moved back by the length of the previous series.
+// Must be called before any requests are recorded.
+func (s *ThroughputSeries) Prepend(previous *ThroughputSeries) {
+	if s == nil || previous == nil {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	n := len(previous.Completed)
+	s.StartTime = s.StartTime.Add(-time.Duration(n) * time.Second)
+	if s.EndTime.IsZero() && !previous.EndTime.IsZero() {
+		s.EndTime = s.StartTime.Add(previous.EndTime.Sub(previous.StartTime))
+	}
+	s.Completed = append(padSeconds(previous.Completed, n), s.Completed...)
+	s.BytesReceived = append(padSeconds(previous.BytesReceived, n), s.BytesReceived...)
+	s.InFlight = append(padSeconds(previous.InFlight, n), s.InFlight...)
+	if s.CompletedByLabel == nil {
+		s.CompletedByLabel = map[string][]int64{}
+	}
+	for label, byLabel := range previous.CompletedByLabel {
+		s.CompletedByLabel[label] = append(padSeconds(byLabel, n), s.CompletedByLabel[label]...)
+	}
+	for label, byLabel := range s.CompletedByLabel {
+		if _, ok := previous.CompletedByLabel[label]; !ok {
+			s.CompletedByLabel[label] = append(make([]int64, n), byLabel...)
+		}
+	}
+}
+
+// Returns a copy of the values, padded with zeroes to n seconds
+func padSeconds(values []int64, n int) []int64 {
+	padded := make([]int64, n)
+	copy(padded, values)
+	return padded
+}
+
+// InFlightNow returns the number of requests which are in flight right now
+func (s *ThroughputSeries) InFlightNow() int64 {
+	if s == nil {
+		return 0
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.inFlight
+}
+
+// Elapsed returns the time from the start until the last request was completed
+func (s *ThroughputSeries) Elapsed() time.Duration {
+	if s == nil {
+		return 0
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.EndTime.Before(s.StartTime) {
+		return 0
+	}
+	return s.EndTime.Sub(s.StartTime)
+}
+
+func (s *ThroughputSeries) MarshalJSON() ([]byte, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return json.Marshal(throughputSeriesJSON{
+		StartTime:        s.StartTime,
+		EndTime:          s.EndTime,
+		Completed:        s.Completed,
+		CompletedByLabel: s.CompletedByLabel,
+		BytesReceived:    s.BytesReceived,
+		InFlight:         s.InFlight,
+	})
+}
+
+func (s *ThroughputSeries) UnmarshalJSON(b []byte) error {
+	var j throughputSeriesJSON
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.StartTime = j.StartTime
+	s.EndTime = j.EndTime
+	s.Completed = j.Completed
+	s.CompletedByLabel = j.CompletedByLabel
+	s.BytesReceived = j.BytesReceived
+	s.InFlight = j.InFlight
+	return nil
+}
+
+// Used when stored in the database, which happens while the run is in progress
+func (s *ThroughputSeries) GobEncode() ([]byte, error) {
+	return s.MarshalJSON()
+}
+
+func (s *ThroughputSeries) GobDecode(b []byte) error {
+	return s.UnmarshalJSON(b)
+}
+
+type throughputSeriesJSON struct {
+	StartTime        time.Time          `json:"startTime"`
+	EndTime          time.Time          `json:"endTime"`
+	Completed        []int64            `json:"completed"`
+	CompletedByLabel map[string][]int64 `json:"completedByLabel"`
+	BytesReceived    []int64            `json:"bytesReceived"`
+	InFlight         []int64            `json:"inFlight"`
+}
@@ -0,0 +1,81 @@
+package requests
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestThroughputSeries(t *testing.T) {
+	start := time.Date(2021, 10, 29, 14, 0, 0, 0, time.UTC)
+	at := func(ms int) time.Time { return start.Add(time.Duration(ms) * time.Millisecond) }
+	s := NewThroughputSeries(start)
+	s.RequestStarted(at(0))
+	s.RequestStarted(at(100))
+	s.RequestStarted(at(200))
+	s.RequestDone("", at(900), 10)
+	s.RequestDone("Timeout", at(1500), 0)
+	// The last request is in flight for the whole third second
+	s.RequestDone("", at(3100), 20)
+
+	want := throughputSeriesJSON{
+		StartTime:        start,
+		EndTime:          at(3100),
+		Completed:        []int64{1, 1, 0, 1},
+		CompletedByLabel: map[string][]int64{"": {1, 0, 0, 1}, "Timeout": {0, 1}},
+		BytesReceived:    []int64{10, 0, 0, 20},
+		InFlight:         []int64{3, 2, 1, 1},
+	}
+	b, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got throughputSeriesJSON
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if got := s.Elapsed(); got != 3100*time.Millisecond {
+		t.Errorf("Elapsed() = %v", got)
+	}
+}
+
+func TestThroughputSeries_Prepend(t *testing.T) {
+	start := time.Date(2021, 10, 29, 14, 0, 0, 0, time.UTC)
+	previous := NewThroughputSeries(start)
+	previous.RequestStarted(start)
+	previous.RequestDone("Timeout", start.Add(1500*time.Millisecond), 5)
+
+	// Resumed an hour later
+	resumed := start.Add(time.Hour)
+	s := NewThroughputSeries(resumed)
+	s.Prepend(previous)
+	s.RequestStarted(resumed)
+	s.RequestDone("", resumed.Add(100*time.Millisecond), 10)
+
+	want := throughputSeriesJSON{
+		StartTime:        resumed.Add(-2 * time.Second),
+		EndTime:          resumed.Add(100 * time.Millisecond),
+		Completed:        []int64{0, 1, 1},
+		CompletedByLabel: map[string][]int64{"": {0, 0, 1}, "Timeout": {0, 1}},
+		BytesReceived:    []int64{0, 5, 10},
+		InFlight:         []int64{1, 1, 1},
+	}
+	b, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got throughputSeriesJSON
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if got := s.Elapsed(); got != 2100*time.Millisecond {
+		t.Errorf("Elapsed() = %v", got)
+	}
+}
@@ -30,7 +30,7 @@ type TimeSeries struct {
 type orderingSlice []Serie
 
 func (o orderingSlice) Len() int           { return len(o) }
-func (o orderingSlice) Less(i, j int) bool { return o[i][0] > o[j][0] }
+func (o orderingSlice) Less(i, j int) bool { return o[i][0] < o[j][0] }
 func (o orderingSlice) Swap(i, j int)      { o[i], o[j] = o[j], o[i] }
 
 type TimeSeriesOptions struct {
@@ -86,12 +86,19 @@ func (ts *TimeSeries) Push(t time.Time, v float64) {
 	ts.Series.Push(st, v)
 	return
 }
+
+// The values are buffered until Finish, if they may be pushed out of order.
+// Series which are decoded from storage have no buffer.
+func (ts *TimeSeries) buffered() bool {
+	return !ts.finished && !ts.ordered && ts.orderingValues != nil
+}
+
 func (ts *TimeSeries) Expand() *TimeSeriesExpanded {
 	if ts == nil {
 		return nil
 	}
 	exp := TimeSeriesExpanded{}
-	if !ts.finished && !ts.ordered {
+	if ts.buffered() {
 		if len(*ts.orderingValues) == 0 {
 			return &exp
 		}
@@ -133,7 +140,21 @@ func TimeSeriePointToTime(v uint64) time.Time {
 type TimeSeriesMap struct {
 	Map       map[string]*TimeSeries
 	StartTime time.Time
-	lock      sync.RWMutex
+	// Per-second series for throughput and concurrency
+	Throughput *ThroughputSeries `json:"-"`
+	lock       sync.RWMutex
+}
+
+func (tsm *TimeSeriesMap) RequestStarted(t time.Time) {
+	if tsm.Throughput != nil {
+		tsm.Throughput.RequestStarted(t)
+	}
+}
+
+func (tsm *TimeSeriesMap) RequestDone(label string, t time.Time, bytes int) {
+	if tsm.Throughput != nil {
+		tsm.Throughput.RequestDone(label, t, bytes)
+	}
 }
 
 func (tsm *TimeSeriesMap) Push(label string, t time.Time, value float64) {
@@ -156,8 +177,9 @@ func (tsm *TimeSeriesMap) Push(label string, t time.Time, value float64) {
 
 func NewTimeSeriesWithLabel(startTime time.Time) TimeSeriesMap {
 	return TimeSeriesMap{
-		StartTime: startTime,
-		Map:       map[string]*TimeSeries{},
+		StartTime:  startTime,
+		Map:        map[string]*TimeSeries{},
+		Throughput: NewThroughputSeries(startTime),
 	}
 }
 func (s *TimeSeriesMap) MarshalJSON() ([]byte, error) {
@@ -187,6 +209,23 @@ func (tsm *TimeSeriesMap) Expand() map[string]*TimeSeriesExpanded {
 	return maps
 }
 
+// Latencies returns the series of each label with the values in milliseconds, like for charts.
+// Expand only converts the values of series which are not buffered from the pushed nanoseconds.
+func (tsm *TimeSeriesMap) Latencies() map[string]*TimeSeriesExpanded {
+	maps := tsm.Expand()
+	tsm.lock.RLock()
+	defer tsm.lock.RUnlock()
+	for k, exp := range maps {
+		if exp == nil || !tsm.Map[k].buffered() {
+			continue
+		}
+		for i := range exp.Series {
+			exp.Series[i][1] /= 1_000_000
+		}
+	}
+	return maps
+}
+
 type TimeSeriesExpanded struct {
 	StartTime time.Time
 	Series    []Serie
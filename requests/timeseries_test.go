@@ -7,6 +7,7 @@ import (
 
 	"github.com/go-test/deep"
 	"github.com/runar-rkmedia/gabyoall/internal"
+	"github.com/tsenart/go-tsz"
 )
 
 var (
@@ -118,3 +119,39 @@ func ParseTimeOrDie(s string) *time.Time {
 	utc := n.UTC()
 	return &utc
 }
+
+func TestTimeSeriesMap_Latencies(t *testing.T) {
+	start := time.Date(2021, 11, 3, 12, 0, 0, 0, time.UTC)
+	// Series which are decoded from storage have neither a buffer nor are finished
+	decoded := &TimeSeries{Series: *tsz.New(uint64(start.UnixMilli()))}
+	decoded.Series.Push(uint64(start.UnixMilli()), float64(5*time.Millisecond))
+	tests := []struct {
+		name string
+		tsm  *TimeSeriesMap
+		want []Serie
+	}{
+		{
+			"buffered, pushed out of order",
+			func() *TimeSeriesMap {
+				tsm := NewTimeSeriesWithLabel(start)
+				tsm.Push("", start.Add(2*time.Second), float64(30*time.Millisecond))
+				tsm.Push("", start.Add(time.Second), float64(10*time.Millisecond))
+				return &tsm
+			}(),
+			[]Serie{{0, 10}, {1000, 30}},
+		},
+		{
+			"decoded",
+			&TimeSeriesMap{StartTime: start, Map: map[string]*TimeSeries{"": decoded}},
+			[]Serie{{0, 5}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.tsm.Latencies()[""]
+			if got == nil || !reflect.DeepEqual(got.Series, tt.want) {
+				t.Errorf("Latencies() = %+v, want %v", got, tt.want)
+			}
+		})
+	}
+}
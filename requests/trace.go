@@ -0,0 +1,166 @@
+package requests
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Timing is the breakdown of the duration of a request.
+// Phases which did not happen, like DNS for an ip-address, are zero.
+type Timing struct {
+	// Resolving the hostname
+	DNS time.Duration
+	// Establishing the tcp-connection
+	Connect time.Duration
+	// The tls-handshake
+	TLS time.Duration
+	// From the request was written until the first byte of the response, which is roughly the time spent by the server
+	Wait time.Duration
+	// From the start of the request until the first byte of the response
+	FirstByte time.Duration
+	// The whole request, including reading the body of the response
+	Total time.Duration
+}
+
+// RequestTrace holds the details of a request and its response, for samples of slow and failing requests.
+type RequestTrace struct {
+	Method          string
+	Url             string
+	RequestHeaders  http.Header
+	RequestBody     []byte
+	ResponseHeaders http.Header
+	Timing          Timing
+
+	// The request is kept until its headers and body are captured,
+	// which is only done if the request is kept as a sample
+	request *http.Request
+
+	start, dnsStart, connectStart, tlsStart, wroteRequest time.Time
+	lock                                                  sync.Mutex
+}
+
+func newRequestTrace(r *http.Request) *RequestTrace {
+	return &RequestTrace{
+		Method:  r.Method,
+		Url:     r.URL.String(),
+		request: r,
+	}
+}
+
+// captureRequest copies the headers and body of the request.
+// The caller must hold the lock.
+func (t *RequestTrace) captureRequest() {
+	r := t.request
+	if r == nil {
+		return
+	}
+	t.request = nil
+	t.RequestHeaders = r.Header.Clone()
+	if r.GetBody != nil {
+		if body, err := r.GetBody(); err == nil {
+			t.RequestBody, _ = io.ReadAll(body)
+			body.Close()
+		}
+	}
+}
+
+// withClientTrace returns the request with a context which records the timing of each phase
+func (t *RequestTrace) withClientTrace(r *http.Request) *http.Request {
+	// The callbacks may be called from other goroutines, like when dialing multiple addresses
+	set := func(f func()) {
+		t.lock.Lock()
+		f()
+		t.lock.Unlock()
+	}
+	since := func(start time.Time) time.Duration {
+		if start.IsZero() {
+			return 0
+		}
+		return time.Now().Sub(start)
+	}
+	t.start = time.Now()
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { set(func() { t.dnsStart = time.Now() }) },
+		DNSDone:  func(httptrace.DNSDoneInfo) { set(func() { t.Timing.DNS = since(t.dnsStart) }) },
+		ConnectStart: func(string, string) {
+			set(func() {
+				if t.connectStart.IsZero() {
+					t.connectStart = time.Now()
+				}
+			})
+		},
+		ConnectDone: func(_, _ string, err error) {
+			set(func() {
+				if err == nil && t.Timing.Connect == 0 {
+					t.Timing.Connect = since(t.connectStart)
+				}
+			})
+		},
+		TLSHandshakeStart: func() { set(func() { t.tlsStart = time.Now() }) },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { set(func() { t.Timing.TLS = since(t.tlsStart) }) },
+		WroteRequest:      func(httptrace.WroteRequestInfo) { set(func() { t.wroteRequest = time.Now() }) },
+		GotFirstResponseByte: func() {
+			set(func() {
+				t.Timing.FirstByte = since(t.start)
+				t.Timing.Wait = since(t.wroteRequest)
+			})
+		},
+	}
+	return r.WithContext(httptrace.WithClientTrace(r.Context(), trace))
+}
+
+func (t *RequestTrace) end(duration time.Duration) {
+	t.lock.Lock()
+	t.Timing.Total = duration
+	t.lock.Unlock()
+}
+
+type timingJSON struct {
+	DNS       float64 `json:"dns,omitempty"`
+	Connect   float64 `json:"connect,omitempty"`
+	TLS       float64 `json:"tls,omitempty"`
+	Wait      float64 `json:"wait,omitempty"`
+	FirstByte float64 `json:"firstByte,omitempty"`
+	Total     float64 `json:"total"`
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+func msDuration(ms float64) time.Duration {
+	return time.Duration(ms * float64(time.Millisecond))
+}
+
+// MarshalJSON writes the durations in milliseconds
+func (t Timing) MarshalJSON() ([]byte, error) {
+	return json.Marshal(timingJSON{
+		DNS:       durationMs(t.DNS),
+		Connect:   durationMs(t.Connect),
+		TLS:       durationMs(t.TLS),
+		Wait:      durationMs(t.Wait),
+		FirstByte: durationMs(t.FirstByte),
+		Total:     durationMs(t.Total),
+	})
+}
+
+func (t *Timing) UnmarshalJSON(b []byte) error {
+	var j timingJSON
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+	*t = Timing{
+		DNS:       msDuration(j.DNS),
+		Connect:   msDuration(j.Connect),
+		TLS:       msDuration(j.TLS),
+		Wait:      msDuration(j.Wait),
+		FirstByte: msDuration(j.FirstByte),
+		Total:     msDuration(j.Total),
+	}
+	return nil
+}
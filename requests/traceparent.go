@@ -0,0 +1,63 @@
+package requests
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// TraceContext identifies the client-span of a request, as in W3C Trace Context.
+// It is sent in the traceparent-header, so that the traces of the server show which spans came from a run.
+type TraceContext struct {
+	// 32 hex-characters
+	TraceID string
+	// 16 hex-characters
+	SpanID string
+	// The span which this span is a child of, if any
+	ParentSpanID string
+}
+
+// Child returns the context of a new span within the same trace, with this span as its parent
+func (tc TraceContext) Child() TraceContext {
+	var b [8]byte
+	rand.Read(b[:])
+	return TraceContext{
+		TraceID:      tc.TraceID,
+		SpanID:       hex.EncodeToString(b[:]),
+		ParentSpanID: tc.SpanID,
+	}
+}
+
+// NewTraceContext creates a sampled trace-context with random ids
+func NewTraceContext() TraceContext {
+	var b [24]byte
+	rand.Read(b[:])
+	return TraceContext{
+		TraceID: hex.EncodeToString(b[:16]),
+		SpanID:  hex.EncodeToString(b[16:]),
+	}
+}
+
+// ParseTraceparent parses a traceparent-header, like 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+func ParseTraceparent(header string) (TraceContext, error) {
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return TraceContext{}, fmt.Errorf("invalid traceparent '%s'", header)
+	}
+	for _, p := range parts[:4] {
+		if _, err := hex.DecodeString(p); err != nil {
+			return TraceContext{}, fmt.Errorf("invalid traceparent '%s': %w", header, err)
+		}
+	}
+	tc := TraceContext{TraceID: strings.ToLower(parts[1]), SpanID: strings.ToLower(parts[2])}
+	if strings.Trim(tc.TraceID, "0") == "" || strings.Trim(tc.SpanID, "0") == "" {
+		return TraceContext{}, fmt.Errorf("invalid traceparent '%s': the ids cannot be all zeroes", header)
+	}
+	return tc, nil
+}
+
+// Traceparent returns the value of the traceparent-header, with the sampled-flag set
+func (tc TraceContext) Traceparent() string {
+	return "00-" + tc.TraceID + "-" + tc.SpanID + "-01"
+}
@@ -0,0 +1,74 @@
+package requests
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/runar-rkmedia/gabyoall/logger"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		want    TraceContext
+		wantErr bool
+	}{
+		{"valid", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", TraceContext{"4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7", ""}, false},
+		{"uppercase", "00-4BF92F3577B34DA6A3CE929D0E0E4736-00F067AA0BA902B7-00", TraceContext{"4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7", ""}, false},
+		{"empty", "", TraceContext{}, true},
+		{"short trace-id", "00-4bf92f3577b34da6-00f067aa0ba902b7-01", TraceContext{}, true},
+		{"not hex", "00-4bf92f3577b34da6a3ce929d0e0e473x-00f067aa0ba902b7-01", TraceContext{}, true},
+		{"zero span-id", "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", TraceContext{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTraceparent(tt.header)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTraceparent() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseTraceparent() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	t.Run("child", func(t *testing.T) {
+		parent := NewTraceContext()
+		child := parent.Child()
+		if child.TraceID != parent.TraceID || child.ParentSpanID != parent.SpanID || child.SpanID == parent.SpanID || len(child.SpanID) != 16 {
+			t.Errorf("Child() = %v, of parent %v", child, parent)
+		}
+	})
+	t.Run("round-trip", func(t *testing.T) {
+		tc := NewTraceContext()
+		got, err := ParseTraceparent(tc.Traceparent())
+		if err != nil || got != tc {
+			t.Errorf("ParseTraceparent(%s) = %v, %v", tc.Traceparent(), got, err)
+		}
+	})
+}
+
+func TestEndpoint_ConfiguredTraceparent(t *testing.T) {
+	parent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	ts := NewTimeSeriesWithLabel(time.Now())
+	g := Endpoint{
+		Url:     "http://localhost",
+		Headers: http.Header{"Traceparent": {parent}},
+		ts:      &ts,
+		l:       logger.GetLogger("test"),
+		client:  fakeClient{`{"data": {}}`},
+	}
+	spans := map[string]bool{}
+	for i := 0; i < 3; i++ {
+		_, stat, _ := g.RunQuery(time.Now(), Request{Query: "{ a }"}, nil, nil)
+		tc := stat.TraceContext
+		if tc.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || tc.ParentSpanID != "00f067aa0ba902b7" {
+			t.Errorf("expected a child-span of the configured traceparent, got %v", tc)
+		}
+		spans[tc.SpanID] = true
+	}
+	if len(spans) != 3 {
+		t.Errorf("expected a new span-id for each request, got %v", spans)
+	}
+}
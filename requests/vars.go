@@ -3,8 +3,11 @@ package requests
 type ErrorType string
 
 var (
-	GQLError        ErrorType = "GQLError"
-	NonOK           ErrorType = "NonOK"
-	ServerTestError ErrorType = "ServerTestError"
-	Unknwon         ErrorType = "UnknownError"
+	GQLError             ErrorType = "GQLError"
+	NonOK                ErrorType = "NonOK"
+	ServerTestError      ErrorType = "ServerTestError"
+	Unknwon              ErrorType = "UnknownError"
+	AssertionFailed      ErrorType = "AssertionFailed"
+	SchemaViolationError ErrorType = "SchemaViolation"
+	GoldenMismatch       ErrorType = "GoldenMismatch"
 )
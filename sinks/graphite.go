@@ -0,0 +1,81 @@
+package sinks
+
+import (
+	"bytes"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Writes in the plaintext-protocol of Graphite, with the tags of the run as graphite-tags, like
+//
+//	gobyoall.requests.p95;run_id=abc 12.5 1636000000
+func graphiteLines(a Aggregate, prefix string) []byte {
+	var buf bytes.Buffer
+	ts := strconv.FormatInt(a.Time.Unix(), 10)
+	tags := graphiteTags(a.Tags)
+	for _, f := range a.fields() {
+		buf.WriteString(prefix + ".requests." + f.name + tags + " " + strconv.FormatFloat(f.value, 'f', -1, 64) + " " + ts + "\n")
+	}
+	for _, errorType := range sortedErrorTypes(a.Errors) {
+		buf.WriteString(prefix + ".errors." + sanitize(string(errorType)) + tags + " " + strconv.Itoa(a.Errors[errorType]) + " " + ts + "\n")
+	}
+	return buf.Bytes()
+}
+
+func graphiteTags(tags map[string]string) string {
+	s := ""
+	for _, k := range sortedKeys(tags) {
+		if tags[k] == "" {
+			continue
+		}
+		s += ";" + sanitize(k) + "=" + sanitize(tags[k])
+	}
+	return s
+}
+
+// The connection is reused, and redialed if writing to it fails
+type graphite struct {
+	address string
+	prefix  string
+	conn    net.Conn
+	lock    sync.Mutex
+}
+
+func newGraphite(address, prefix string) *graphite {
+	return &graphite{address: address, prefix: prefix}
+}
+
+func (s *graphite) Write(a Aggregate) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	b := graphiteLines(a, s.prefix)
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if s.conn == nil {
+			s.conn, err = net.DialTimeout("tcp", s.address, 5*time.Second)
+			if err != nil {
+				return err
+			}
+		}
+		s.conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+		if _, err = s.conn.Write(b); err == nil {
+			return nil
+		}
+		s.conn.Close()
+		s.conn = nil
+	}
+	return err
+}
+
+func (s *graphite) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
@@ -0,0 +1,131 @@
+package sinks
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Writes in the InfluxDB line protocol, which is also accepted by Telegraf and VictoriaMetrics.
+// Each aggregate is a line for the requests, and a line for each ErrorType.
+func influxLines(a Aggregate, prefix string) []byte {
+	var buf bytes.Buffer
+	ts := strconv.FormatInt(a.Time.UnixNano(), 10)
+	buf.WriteString(escapeInfluxName(prefix + "_requests"))
+	writeInfluxTags(&buf, a.Tags)
+	for i, f := range a.fields() {
+		if i == 0 {
+			buf.WriteByte(' ')
+		} else {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(f.name)
+		buf.WriteByte('=')
+		if f.integer {
+			buf.WriteString(strconv.FormatInt(int64(f.value), 10) + "i")
+		} else {
+			buf.WriteString(strconv.FormatFloat(f.value, 'f', -1, 64))
+		}
+	}
+	buf.WriteString(" " + ts + "\n")
+	for _, errorType := range sortedErrorTypes(a.Errors) {
+		buf.WriteString(escapeInfluxName(prefix + "_errors"))
+		writeInfluxTags(&buf, a.Tags)
+		buf.WriteString(",error_type=" + escapeInfluxTag(string(errorType)))
+		buf.WriteString(fmt.Sprintf(" count=%di %s\n", a.Errors[errorType], ts))
+	}
+	return buf.Bytes()
+}
+
+func writeInfluxTags(buf *bytes.Buffer, tags map[string]string) {
+	for _, k := range sortedKeys(tags) {
+		if tags[k] == "" {
+			continue
+		}
+		buf.WriteString("," + escapeInfluxTag(k) + "=" + escapeInfluxTag(tags[k]))
+	}
+}
+
+var (
+	influxNameEscaper = strings.NewReplacer(",", `\,`, " ", `\ `, "\n", " ")
+	influxTagEscaper  = strings.NewReplacer(",", `\,`, " ", `\ `, "=", `\=`, "\n", " ")
+)
+
+func escapeInfluxName(s string) string { return influxNameEscaper.Replace(s) }
+func escapeInfluxTag(s string) string  { return influxTagEscaper.Replace(s) }
+
+type influxHTTP struct {
+	url    string
+	token  string
+	prefix string
+	client *http.Client
+}
+
+// The token-parameter is sent as the Authorization-header of InfluxDB v2, and is not part of the url which is written to.
+func newInfluxHTTP(u *url.URL, prefix string) *influxHTTP {
+	q := u.Query()
+	token := q.Get("token")
+	q.Del("token")
+	q.Del("prefix")
+	if q.Get("precision") == "" {
+		q.Set("precision", "ns")
+	}
+	target := *u
+	target.Scheme = strings.TrimPrefix(u.Scheme, "influx+")
+	target.RawQuery = q.Encode()
+	return &influxHTTP{
+		url:    target.String(),
+		token:  token,
+		prefix: prefix,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (s *influxHTTP) Write(a Aggregate) error {
+	r, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(influxLines(a, s.prefix)))
+	if err != nil {
+		return err
+	}
+	r.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if s.token != "" {
+		r.Header.Set("Authorization", "Token "+s.token)
+	}
+	res, err := s.client.Do(r)
+	if err != nil {
+		return err
+	}
+	body, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+	res.Body.Close()
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("influx responded with status %d: %s", res.StatusCode, body)
+	}
+	return nil
+}
+
+func (s *influxHTTP) Close() error { return nil }
+
+type influxUDP struct {
+	conn   net.Conn
+	prefix string
+}
+
+func newInfluxUDP(address, prefix string) (*influxUDP, error) {
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		return nil, err
+	}
+	return &influxUDP{conn, prefix}, nil
+}
+
+func (s *influxUDP) Write(a Aggregate) error {
+	_, err := s.conn.Write(influxLines(a, s.prefix))
+	return err
+}
+
+func (s *influxUDP) Close() error { return s.conn.Close() }
@@ -0,0 +1,258 @@
+// Package sinks pushes per-second aggregates of a run to time-series databases,
+// like InfluxDB, Graphite and StatsD, so that runs can be charted on existing dashboards.
+package sinks
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/runar-rkmedia/gabyoall/logger"
+	"github.com/runar-rkmedia/gabyoall/requests"
+	"github.com/runar-rkmedia/gabyoall/utils"
+)
+
+const defaultPrefix = "gobyoall"
+
+// Aggregate is the requests which completed within an interval
+type Aggregate struct {
+	Time     time.Time
+	Interval time.Duration
+	Count    int
+	Failed   int
+	// Failed requests by ErrorType
+	Errors  map[requests.ErrorType]int
+	Min     time.Duration
+	Max     time.Duration
+	Mean    time.Duration
+	Latency requests.Percentiles
+	// Tags of the run, like its run_id
+	Tags map[string]string
+}
+
+// Sink receives an aggregate for each interval of the run
+type Sink interface {
+	Write(a Aggregate) error
+	Close() error
+}
+
+// New creates a sink from a url. The scheme selects the sink:
+//
+//	influx+http://localhost:8086/api/v2/write?org=o&bucket=b&token=t
+//	influx+udp://localhost:8089
+//	graphite://localhost:2003
+//	statsd://localhost:8125
+//
+// The metrics are prefixed with the prefix-parameter, which defaults to gobyoall.
+func New(rawURL string) (Sink, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		// The url.Error would repeat the url, with its credentials
+		return nil, fmt.Errorf("invalid sink '%s': %w", utils.RedactURL(rawURL), errors.Unwrap(err))
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("the sink '%s' has no host", utils.RedactURL(rawURL))
+	}
+	prefix := u.Query().Get("prefix")
+	if prefix == "" {
+		prefix = defaultPrefix
+	}
+	switch u.Scheme {
+	case "influx+http", "influx+https":
+		return newInfluxHTTP(u, prefix), nil
+	case "influx+udp":
+		return newInfluxUDP(u.Host, prefix)
+	case "graphite", "graphite+tcp":
+		return newGraphite(u.Host, prefix), nil
+	case "statsd", "statsd+udp":
+		return newStatsD(u.Host, prefix)
+	}
+	return nil, fmt.Errorf("unsupported sink '%s', the scheme must be one of influx+http, influx+https, influx+udp, graphite or statsd", u.Scheme)
+}
+
+// Pusher aggregates the completed requests, and writes the aggregate to each sink at every interval
+type Pusher struct {
+	Sinks    []Sink
+	Interval time.Duration
+	Tags     map[string]string
+	l        logger.AppLogger
+	current  *bucket
+	quit     chan struct{}
+	done     chan struct{}
+	lock     sync.Mutex
+}
+
+type bucket struct {
+	histogram *requests.Histogram
+	stats     requests.Stats
+	failed    int
+	errors    map[requests.ErrorType]int
+}
+
+func newBucket() *bucket {
+	return &bucket{histogram: requests.NewHistogram(), errors: map[requests.ErrorType]int{}}
+}
+
+// NewSinks creates a sink for each url
+func NewSinks(urls []string) ([]Sink, error) {
+	sinks := make([]Sink, 0, len(urls))
+	for _, u := range urls {
+		s, err := New(u)
+		if err != nil {
+			for _, s := range sinks {
+				s.Close()
+			}
+			return nil, err
+		}
+		sinks = append(sinks, s)
+	}
+	return sinks, nil
+}
+
+// NewPusher starts pushing to the sinks every interval, until it is closed
+func NewPusher(l logger.AppLogger, sinks []Sink, interval time.Duration, tags map[string]string) *Pusher {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	p := &Pusher{
+		Sinks:    sinks,
+		Interval: interval,
+		Tags:     tags,
+		l:        l,
+		current:  newBucket(),
+		quit:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go p.run()
+	return p
+}
+
+// Observe adds a completed request to the current interval. A nil Pusher is a no-op.
+func (p *Pusher) Observe(stat requests.RequestStat) {
+	if p == nil {
+		return
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	b := p.current
+	b.histogram.Record(stat.Duration)
+	b.stats.Add(stat.Duration)
+	if stat.ErrorType != "" {
+		b.failed++
+		b.errors[stat.ErrorType]++
+	}
+}
+
+func (p *Pusher) run() {
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+	defer close(p.done)
+	for {
+		select {
+		case now := <-ticker.C:
+			p.push(now)
+		case <-p.quit:
+			p.push(time.Now())
+			return
+		}
+	}
+}
+
+// Takes the current interval, and writes it to the sinks. Empty intervals are written too, so that dashboards show a drop to zero.
+func (p *Pusher) push(now time.Time) {
+	p.lock.Lock()
+	b := p.current
+	p.current = newBucket()
+	p.lock.Unlock()
+	a := Aggregate{
+		Time:     now,
+		Interval: p.Interval,
+		Count:    b.stats.Count,
+		Failed:   b.failed,
+		Errors:   b.errors,
+		Min:      b.stats.Min,
+		Max:      b.stats.Max,
+		Latency:  b.histogram.Percentiles(),
+		Tags:     p.Tags,
+	}
+	if b.stats.Count > 0 {
+		a.Mean = b.stats.Total / time.Duration(b.stats.Count)
+	}
+	for _, s := range p.Sinks {
+		if err := s.Write(a); err != nil {
+			p.l.Warn().Err(err).Msg("Failed to write to sink")
+		}
+	}
+}
+
+// Close writes the last interval, and closes the sinks
+func (p *Pusher) Close() error {
+	if p == nil {
+		return nil
+	}
+	close(p.quit)
+	<-p.done
+	var errs []string
+	for _, s := range p.Sinks {
+		if err := s.Close(); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to close sinks: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// The fields of the aggregate, in a stable order, with latencies in milliseconds
+func (a Aggregate) fields() []field {
+	ms := func(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+	return []field{
+		{"count", float64(a.Count), true},
+		{"failed", float64(a.Failed), true},
+		{"min", ms(a.Min), false},
+		{"mean", ms(a.Mean), false},
+		{"max", ms(a.Max), false},
+		{"p50", ms(a.Latency.P50), false},
+		{"p95", ms(a.Latency.P95), false},
+		{"p99", ms(a.Latency.P99), false},
+	}
+}
+
+type field struct {
+	name    string
+	value   float64
+	integer bool
+}
+
+func sortedErrorTypes(errors map[requests.ErrorType]int) []requests.ErrorType {
+	keys := make([]requests.ErrorType, 0, len(errors))
+	for k := range errors {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Replaces characters which are not safe in metric-paths, like the ones of graphite and statsd
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, s)
+}
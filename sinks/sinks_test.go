@@ -0,0 +1,186 @@
+package sinks
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/runar-rkmedia/gabyoall/logger"
+	"github.com/runar-rkmedia/gabyoall/requests"
+)
+
+var testAggregate = Aggregate{
+	Time:     time.Unix(1700000000, 0),
+	Interval: time.Second,
+	Count:    3,
+	Failed:   1,
+	Errors:   map[requests.ErrorType]int{"File not found, id=1": 1},
+	Min:      5 * time.Millisecond,
+	Max:      20 * time.Millisecond,
+	Mean:     10 * time.Millisecond,
+	Latency:  requests.Percentiles{P50: 5 * time.Millisecond, P95: 20 * time.Millisecond, P99: 20 * time.Millisecond},
+	Tags:     map[string]string{"run_id": "abc", "schedule_id": ""},
+}
+
+func TestSinks(t *testing.T) {
+	tests := []struct {
+		name string
+		// Starts a listener, and returns the url of the sink and a channel which receives what was written
+		listen func(t *testing.T) (string, <-chan string)
+		want   []string
+	}{
+		{
+			"influx over http",
+			func(t *testing.T) (string, <-chan string) {
+				ch := make(chan string, 1)
+				srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+					if r.URL.Query().Get("token") != "" || r.Header.Get("Authorization") != "Token secret" || r.URL.Query().Get("bucket") != "b" {
+						t.Errorf("unexpected request %s with authorization %s", r.URL, r.Header.Get("Authorization"))
+					}
+					b, _ := io.ReadAll(r.Body)
+					ch <- string(b)
+					rw.WriteHeader(http.StatusNoContent)
+				}))
+				t.Cleanup(srv.Close)
+				return "influx+" + srv.URL + "/api/v2/write?bucket=b&token=secret", ch
+			},
+			[]string{
+				"gobyoall_requests,run_id=abc count=3i,failed=1i,min=5,mean=10,max=20,p50=5,p95=20,p99=20 1700000000000000000\n",
+				"gobyoall_errors,run_id=abc,error_type=File\\ not\\ found\\,\\ id\\=1 count=1i 1700000000000000000\n",
+			},
+		},
+		{
+			"influx over udp",
+			func(t *testing.T) (string, <-chan string) {
+				addr, ch := listenUDP(t)
+				return "influx+udp://" + addr + "?prefix=load", ch
+			},
+			[]string{"load_requests,run_id=abc count=3i,"},
+		},
+		{
+			"graphite",
+			func(t *testing.T) (string, <-chan string) {
+				l, err := net.Listen("tcp", "127.0.0.1:0")
+				if err != nil {
+					t.Fatal(err)
+				}
+				t.Cleanup(func() { l.Close() })
+				ch := make(chan string, 1)
+				go func() {
+					conn, err := l.Accept()
+					if err != nil {
+						return
+					}
+					defer conn.Close()
+					var lines []string
+					scanner := bufio.NewScanner(conn)
+					for scanner.Scan() {
+						lines = append(lines, scanner.Text())
+					}
+					ch <- strings.Join(lines, "\n") + "\n"
+				}()
+				return "graphite://" + l.Addr().String(), ch
+			},
+			[]string{
+				"gobyoall.requests.count;run_id=abc 3 1700000000\n",
+				"gobyoall.requests.p95;run_id=abc 20 1700000000\n",
+				"gobyoall.errors.File_not_found__id_1;run_id=abc 1 1700000000\n",
+			},
+		},
+		{
+			"statsd",
+			func(t *testing.T) (string, <-chan string) {
+				addr, ch := listenUDP(t)
+				return "statsd://" + addr, ch
+			},
+			[]string{
+				"gobyoall.requests.count:3|c\n",
+				"gobyoall.requests.p95:20|g\n",
+				"gobyoall.errors.File_not_found__id_1:1|c",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, ch := tt.listen(t)
+			s, err := New(u)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Write(testAggregate); err != nil {
+				t.Fatal(err)
+			}
+			// Graphite only delivers what was written when the connection is closed
+			if err := s.Close(); err != nil {
+				t.Fatal(err)
+			}
+			select {
+			case got := <-ch:
+				for _, want := range tt.want {
+					if !strings.Contains(got, want) {
+						t.Errorf("expected %q in\n%s", want, got)
+					}
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatal("nothing was received")
+			}
+		})
+	}
+}
+
+func listenUDP(t *testing.T) (string, <-chan string) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	ch := make(chan string, 1)
+	go func() {
+		b := make([]byte, 65536)
+		n, _, err := conn.ReadFrom(b)
+		if err == nil {
+			ch <- string(b[:n])
+		}
+	}()
+	return conn.LocalAddr().String(), ch
+}
+
+func TestNew_invalid(t *testing.T) {
+	for _, u := range []string{"localhost:2003", "kafka://localhost:9092", "graphite://"} {
+		if _, err := New(u); err == nil {
+			t.Errorf("expected an error for %s", u)
+		}
+	}
+}
+
+type recordingSink struct{ written []Aggregate }
+
+func (s *recordingSink) Write(a Aggregate) error { s.written = append(s.written, a); return nil }
+func (s *recordingSink) Close() error            { return nil }
+
+func TestPusher(t *testing.T) {
+	sink := &recordingSink{}
+	p := NewPusher(logger.GetLogger("test"), []Sink{sink}, time.Hour, map[string]string{"run_id": "abc"})
+	for _, s := range []requests.RequestStat{
+		{Duration: 10 * time.Millisecond},
+		{Duration: 30 * time.Millisecond},
+		{Duration: 20 * time.Millisecond, ErrorType: "Timeout"},
+	} {
+		p.Observe(s)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(sink.written) != 1 {
+		t.Fatalf("expected the last interval to be written on close, got %d", len(sink.written))
+	}
+	a := sink.written[0]
+	if a.Count != 3 || a.Failed != 1 || a.Errors["Timeout"] != 1 || a.Min != 10*time.Millisecond || a.Max != 30*time.Millisecond || a.Mean != 20*time.Millisecond || a.Tags["run_id"] != "abc" {
+		t.Errorf("unexpected aggregate %+v", a)
+	}
+}
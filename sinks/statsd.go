@@ -0,0 +1,68 @@
+package sinks
+
+import (
+	"bytes"
+	"net"
+	"strconv"
+)
+
+// StatsD-packets are kept below the common MTU
+const maxStatsDPacket = 1432
+
+// Writes the counts as counters, and the latencies as gauges, since they are already aggregated, like
+//
+//	gobyoall.requests.count:10|c
+//	gobyoall.requests.p95:12.5|g
+//
+// StatsD has no tags, so they are not written.
+func statsDLines(a Aggregate, prefix string) [][]byte {
+	var lines [][]byte
+	for _, f := range a.fields() {
+		kind := "|g"
+		if f.integer {
+			kind = "|c"
+		}
+		lines = append(lines, []byte(prefix+".requests."+f.name+":"+strconv.FormatFloat(f.value, 'f', -1, 64)+kind))
+	}
+	for _, errorType := range sortedErrorTypes(a.Errors) {
+		lines = append(lines, []byte(prefix+".errors."+sanitize(string(errorType))+":"+strconv.Itoa(a.Errors[errorType])+"|c"))
+	}
+	return lines
+}
+
+type statsD struct {
+	conn   net.Conn
+	prefix string
+}
+
+func newStatsD(address, prefix string) (*statsD, error) {
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		return nil, err
+	}
+	return &statsD{conn, prefix}, nil
+}
+
+// Lines are packed into as few packets as possible
+func (s *statsD) Write(a Aggregate) error {
+	var packet bytes.Buffer
+	for _, line := range statsDLines(a, s.prefix) {
+		if packet.Len() > 0 && packet.Len()+1+len(line) > maxStatsDPacket {
+			if _, err := s.conn.Write(packet.Bytes()); err != nil {
+				return err
+			}
+			packet.Reset()
+		}
+		if packet.Len() > 0 {
+			packet.WriteByte('\n')
+		}
+		packet.Write(line)
+	}
+	if packet.Len() == 0 {
+		return nil
+	}
+	_, err := s.conn.Write(packet.Bytes())
+	return err
+}
+
+func (s *statsD) Close() error { return s.conn.Close() }
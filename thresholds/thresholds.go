@@ -0,0 +1,288 @@
+// Package thresholds evaluates pass/fail-criteria on the results of a run,
+// like `p95 < 300ms`, `error_rate < 1%`, `rps > 200` or `count{errorType="Timeout"} == 0`
+package thresholds
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/runar-rkmedia/gabyoall/requests"
+)
+
+// Stats are the results of a run, which the thresholds are evaluated against.
+// Successful requests have an empty ErrorType.
+type Stats struct {
+	// Number of completed requests, by ErrorType
+	Count map[requests.ErrorType]int
+	// Min, max and total of the latencies, by ErrorType
+	Stats map[requests.ErrorType]*requests.Stats
+	// Latencies by ErrorType, which the percentiles are calculated from
+	Histograms map[requests.ErrorType]*requests.Histogram
+	// Stats of all requests combined
+	Overall *requests.Stats
+	// Latencies of all requests combined
+	Histogram *requests.Histogram
+	// Time since the run started
+	Elapsed time.Duration
+}
+
+type Threshold struct {
+	// The expression as written by the user
+	Expression string
+	Metric     string
+	// Only requests of this ErrorType are included, if set. An empty string means successful requests.
+	ErrorType *requests.ErrorType
+	Operator  string
+	// The value to compare against. Durations are in milliseconds, and percentages as fractions.
+	Value float64
+	// Set for percentiles, like 95 for p95
+	percentile float64
+}
+
+type Result struct {
+	Threshold string  `json:"threshold"`
+	Actual    float64 `json:"actual"`
+	// Human-readable actual value, like 312ms or 1.2%
+	ActualString string `json:"actualString"`
+	Ok           bool   `json:"ok"`
+}
+
+// Metrics which are measured in milliseconds
+var durationMetrics = map[string]bool{"avg": true, "min": true, "max": true, "med": true}
+
+var expressionRegex = regexp.MustCompile(`^\s*([a-z_]+[0-9.]*)\s*(?:\{\s*(\w+)\s*=\s*"([^"]*)"\s*\})?\s*(<=|>=|==|!=|<|>)\s*(\S+)\s*$`)
+
+// Parse parses an expression like `p95 < 300ms` or `count{errorType="Timeout"} == 0`
+func Parse(expression string) (Threshold, error) {
+	t := Threshold{Expression: strings.TrimSpace(expression)}
+	m := expressionRegex.FindStringSubmatch(expression)
+	if m == nil {
+		return t, fmt.Errorf("threshold '%s' could not be parsed, expected something like 'p95 < 300ms'", expression)
+	}
+	t.Metric, t.Operator = m[1], m[4]
+	switch m[2] {
+	case "":
+	case "errorType":
+		errorType := requests.ErrorType(m[3])
+		t.ErrorType = &errorType
+	default:
+		return t, fmt.Errorf("threshold '%s': unknown filter '%s', only errorType is supported", expression, m[2])
+	}
+	isDuration := durationMetrics[t.Metric]
+	switch {
+	case isDuration:
+	case strings.HasPrefix(t.Metric, "p"):
+		p, err := strconv.ParseFloat(t.Metric[1:], 64)
+		if err != nil || p <= 0 || p > 100 {
+			return t, fmt.Errorf("threshold '%s': invalid percentile '%s'", expression, t.Metric)
+		}
+		t.percentile = p
+		isDuration = true
+	case t.Metric == "error_rate", t.Metric == "rps", t.Metric == "count":
+	default:
+		return t, fmt.Errorf("threshold '%s': unknown metric '%s'. Use one of p<N>, avg, min, max, med, error_rate, rps or count", expression, t.Metric)
+	}
+	if t.ErrorType != nil && (t.Metric == "error_rate" || t.Metric == "rps") {
+		return t, fmt.Errorf("threshold '%s': the errorType-filter cannot be used with %s", expression, t.Metric)
+	}
+	value, err := parseValue(m[5], isDuration)
+	if err != nil {
+		return t, fmt.Errorf("threshold '%s': %w", expression, err)
+	}
+	t.Value = value
+	return t, nil
+}
+
+// ParseAll parses all the expressions, returning the first error
+func ParseAll(expressions []string) ([]Threshold, error) {
+	ts := make([]Threshold, len(expressions))
+	for i, e := range expressions {
+		t, err := Parse(e)
+		if err != nil {
+			return nil, err
+		}
+		ts[i] = t
+	}
+	return ts, nil
+}
+
+func parseValue(s string, isDuration bool) (float64, error) {
+	if strings.HasSuffix(s, "%") {
+		if isDuration {
+			return 0, fmt.Errorf("expected a duration, got a percentage '%s'", s)
+		}
+		f, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid percentage '%s'", s)
+		}
+		return f / 100, nil
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f, nil
+	}
+	if !isDuration {
+		return 0, fmt.Errorf("invalid value '%s'", s)
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration '%s'", s)
+	}
+	return toMs(d), nil
+}
+
+func toMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func (t Threshold) compare(actual float64) bool {
+	switch t.Operator {
+	case "<":
+		return actual < t.Value
+	case "<=":
+		return actual <= t.Value
+	case ">":
+		return actual > t.Value
+	case ">=":
+		return actual >= t.Value
+	case "==":
+		return actual == t.Value
+	case "!=":
+		return actual != t.Value
+	}
+	return false
+}
+
+func (s Stats) counts() (total, failed int) {
+	for errorType, n := range s.Count {
+		total += n
+		if errorType != "" {
+			failed += n
+		}
+	}
+	return
+}
+
+// Returns the stats and histogram of the error-type, or of all requests if it is nil
+func (s Stats) latencies(errorType *requests.ErrorType) (*requests.Stats, *requests.Histogram) {
+	if errorType != nil {
+		return s.Stats[*errorType], s.Histograms[*errorType]
+	}
+	return s.Overall, s.Histogram
+}
+
+// Returns the value of the metric. Without any requests, the value is NaN, except for counts and rates.
+func (t Threshold) actual(s Stats) float64 {
+	switch t.Metric {
+	case "count":
+		if t.ErrorType != nil {
+			return float64(s.Count[*t.ErrorType])
+		}
+		total, _ := s.counts()
+		return float64(total)
+	case "error_rate":
+		total, failed := s.counts()
+		if total == 0 {
+			return 0
+		}
+		return float64(failed) / float64(total)
+	case "rps":
+		total, _ := s.counts()
+		if s.Elapsed <= 0 {
+			return 0
+		}
+		return float64(total) / s.Elapsed.Seconds()
+	}
+	stats, histogram := s.latencies(t.ErrorType)
+	if stats == nil || stats.Count == 0 {
+		return math.NaN()
+	}
+	switch t.Metric {
+	case "avg":
+		return toMs(stats.Total / time.Duration(stats.Count))
+	case "min":
+		return toMs(stats.Min)
+	case "max":
+		return toMs(stats.Max)
+	case "med":
+		return toMs(histogram.Percentile(50))
+	}
+	return toMs(histogram.Percentile(t.percentile))
+}
+
+func (t Threshold) format(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "n/a"
+	case t.Metric == "error_rate":
+		return strconv.FormatFloat(v*100, 'f', 2, 64) + "%"
+	case t.Metric == "count":
+		return strconv.FormatFloat(v, 'f', 0, 64)
+	case t.Metric == "rps":
+		return strconv.FormatFloat(v, 'f', 1, 64)
+	}
+	return time.Duration(v * float64(time.Millisecond)).Round(time.Microsecond).String()
+}
+
+// Evaluate evaluates the threshold. A threshold on a metric without any values (NaN) fails.
+func (t Threshold) Evaluate(s Stats) Result {
+	actual := t.actual(s)
+	r := Result{
+		Threshold:    t.Expression,
+		ActualString: t.format(actual),
+		Ok:           !math.IsNaN(actual) && t.compare(actual),
+	}
+	if !math.IsNaN(actual) {
+		r.Actual = actual
+	}
+	return r
+}
+
+// Breached reports whether the threshold has failed in a way which the remaining requests cannot recover from.
+// Only counts and error-rates with an upper bound can be irrecoverably breached.
+func (t Threshold) Breached(s Stats, plannedRequests int) bool {
+	if t.Operator != "<" && t.Operator != "<=" && t.Operator != "==" {
+		return false
+	}
+	var actual float64
+	switch t.Metric {
+	case "count":
+		actual = t.actual(s)
+	case "error_rate":
+		if plannedRequests <= 0 {
+			return false
+		}
+		// Assume that all the remaining requests succeed
+		_, failed := s.counts()
+		actual = float64(failed) / float64(plannedRequests)
+	default:
+		return false
+	}
+	if t.Operator == "==" {
+		return actual > t.Value
+	}
+	return !t.compare(actual)
+}
+
+func EvaluateAll(ts []Threshold, s Stats) (results []Result, ok bool) {
+	ok = true
+	results = make([]Result, len(ts))
+	for i, t := range ts {
+		results[i] = t.Evaluate(s)
+		ok = ok && results[i].Ok
+	}
+	return results, ok
+}
+
+// AnyBreached returns the first threshold which is irrecoverably breached, if any
+func AnyBreached(ts []Threshold, s Stats, plannedRequests int) (Threshold, bool) {
+	for _, t := range ts {
+		if t.Breached(s, plannedRequests) {
+			return t, true
+		}
+	}
+	return Threshold{}, false
+}
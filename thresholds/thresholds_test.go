@@ -0,0 +1,128 @@
+package thresholds
+
+import (
+	"testing"
+	"time"
+
+	"github.com/runar-rkmedia/gabyoall/requests"
+)
+
+func ms(n ...int) []time.Duration {
+	d := make([]time.Duration, len(n))
+	for i, v := range n {
+		d[i] = time.Duration(v) * time.Millisecond
+	}
+	return d
+}
+
+// Builds the stats like the output does, from the durations of each ErrorType
+func statsOf(durations map[requests.ErrorType][]time.Duration, elapsed time.Duration) Stats {
+	s := Stats{
+		Count:      map[requests.ErrorType]int{},
+		Stats:      map[requests.ErrorType]*requests.Stats{},
+		Histograms: map[requests.ErrorType]*requests.Histogram{},
+		Overall:    &requests.Stats{},
+		Histogram:  requests.NewHistogram(),
+		Elapsed:    elapsed,
+	}
+	for errorType, list := range durations {
+		s.Stats[errorType] = &requests.Stats{}
+		s.Histograms[errorType] = requests.NewHistogram()
+		for _, d := range list {
+			s.Count[errorType]++
+			s.Stats[errorType].Add(d)
+			s.Histograms[errorType].Record(d)
+			s.Overall.Add(d)
+			s.Histogram.Record(d)
+		}
+	}
+	return s
+}
+
+func TestEvaluate(t *testing.T) {
+	stats := statsOf(map[requests.ErrorType][]time.Duration{
+		"":        ms(10, 20, 30, 40, 50, 60, 70, 80, 90, 100, 110, 120, 130, 140, 150, 160, 170, 180),
+		"Timeout": ms(1000, 2000),
+	}, 2*time.Second)
+	tests := []struct {
+		expression string
+		wantOk     bool
+		wantActual string
+	}{
+		{"p95 < 300ms", false, "1.003519s"},
+		{`p95{errorType=""} < 300ms`, true, "171.007ms"},
+		{"p50 <= 101", true, "100.351ms"},
+		{"med < 0.1s", false, "100.351ms"},
+		{"avg < 1s", true, "235.5ms"},
+		{"max > 1500ms", true, "2s"},
+		{"min >= 10ms", true, "10ms"},
+		{"error_rate < 1%", false, "10.00%"},
+		{"error_rate <= 0.1", true, "10.00%"},
+		{"rps > 5", true, "10.0"},
+		{`count{errorType="Timeout"} == 0`, false, "2"},
+		{`count{errorType="NonOK-500"} == 0`, true, "0"},
+		{"count != 20", false, "20"},
+		{`p99{errorType="NonOK-500"} < 1s`, false, "n/a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			th, err := Parse(tt.expression)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			got := th.Evaluate(stats)
+			if got.Ok != tt.wantOk || got.ActualString != tt.wantActual {
+				t.Errorf("Evaluate() = %v (%s), want %v (%s)", got.Ok, got.ActualString, tt.wantOk, tt.wantActual)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, expression := range []string{
+		"p95",
+		"p0 < 1s",
+		"p101 < 1s",
+		"latency < 1s",
+		"p95 < fast",
+		"p95 < 5%",
+		`error_rate{errorType="x"} < 1%`,
+		`count{status="500"} == 0`,
+	} {
+		t.Run(expression, func(t *testing.T) {
+			if _, err := Parse(expression); err == nil {
+				t.Errorf("Parse(%s) expected error", expression)
+			}
+		})
+	}
+}
+
+func TestBreached(t *testing.T) {
+	stats := statsOf(map[requests.ErrorType][]time.Duration{
+		"":        ms(10, 10, 10),
+		"Timeout": ms(1000, 1000),
+	}, 0)
+	tests := []struct {
+		expression string
+		planned    int
+		want       bool
+	}{
+		{`count{errorType="Timeout"} == 0`, 100, true},
+		{`count{errorType="Timeout"} < 3`, 100, false},
+		{"error_rate < 1%", 100, true},
+		{"error_rate < 5%", 100, false},
+		{"p95 < 1ms", 100, false},
+		{"count > 100", 100, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			th, err := Parse(tt.expression)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if got := th.Breached(stats, tt.planned); got != tt.want {
+				t.Errorf("Breached() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,265 @@
+// Package tracing exports a client-span for each request to an OpenTelemetry-collector,
+// so that the spans of a run can be found next to the spans of the server.
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/runar-rkmedia/gabyoall/logger"
+	"github.com/runar-rkmedia/gabyoall/requests"
+	"github.com/runar-rkmedia/gabyoall/utils"
+)
+
+const (
+	ServiceName = "gobyoall"
+	// Spans are sent in batches of this size, or at the DefaultFlushInterval, whichever comes first
+	DefaultBatchSize     = 512
+	DefaultFlushInterval = 5 * time.Second
+	// Batches which are waiting to be sent. If the collector cannot keep up, spans are dropped rather than slowing down the run.
+	maxPendingBatches = 16
+
+	spanKindClient  = 3
+	statusCodeOk    = 1
+	statusCodeError = 2
+)
+
+// Exporter sends spans to an OTLP/HTTP-endpoint, encoded as json
+type Exporter struct {
+	// The url which spans are posted to, like http://localhost:4318/v1/traces
+	Endpoint  string
+	RunID     string
+	BatchSize int
+	client    *http.Client
+	l         logger.AppLogger
+	spans     []span
+	batches   chan []span
+	done      chan struct{}
+	dropped   int
+	closed    bool
+	lock      sync.Mutex
+}
+
+// NewExporter starts an exporter. If the endpoint has no path, the default path /v1/traces is used.
+func NewExporter(l logger.AppLogger, endpoint, runID string) (*Exporter, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid otlp-endpoint: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("the otlp-endpoint must be an http(s)-url, got '%s'", endpoint)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/v1/traces"
+	}
+	e := &Exporter{
+		Endpoint:  u.String(),
+		RunID:     runID,
+		BatchSize: DefaultBatchSize,
+		client:    &http.Client{Timeout: 10 * time.Second},
+		l:         l,
+		batches:   make(chan []span, maxPendingBatches),
+		done:      make(chan struct{}),
+	}
+	go e.run(DefaultFlushInterval)
+	return e, nil
+}
+
+// Export adds the client-span of the request. A nil Exporter is a no-op.
+func (e *Exporter) Export(stat requests.RequestStat) {
+	if e == nil || stat.TraceContext.TraceID == "" {
+		return
+	}
+	s := newSpan(stat, e.RunID)
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if e.closed {
+		return
+	}
+	e.spans = append(e.spans, s)
+	if len(e.spans) >= e.BatchSize {
+		e.enqueue()
+	}
+}
+
+// Must be called with the lock held
+func (e *Exporter) enqueue() {
+	if len(e.spans) == 0 {
+		return
+	}
+	select {
+	case e.batches <- e.spans:
+	default:
+		e.dropped += len(e.spans)
+	}
+	e.spans = nil
+}
+
+func (e *Exporter) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	defer close(e.done)
+	for {
+		select {
+		case batch, ok := <-e.batches:
+			if !ok {
+				return
+			}
+			e.send(batch)
+		case <-ticker.C:
+			e.lock.Lock()
+			if !e.closed {
+				e.enqueue()
+			}
+			e.lock.Unlock()
+		}
+	}
+}
+
+// Close sends the remaining spans, and waits for them to be sent
+func (e *Exporter) Close() error {
+	if e == nil {
+		return nil
+	}
+	e.lock.Lock()
+	if e.closed {
+		e.lock.Unlock()
+		return nil
+	}
+	e.closed = true
+	e.enqueue()
+	close(e.batches)
+	dropped := e.dropped
+	e.lock.Unlock()
+	<-e.done
+	if dropped > 0 {
+		return fmt.Errorf("dropped %d spans, since the otlp-endpoint could not keep up", dropped)
+	}
+	return nil
+}
+
+func (e *Exporter) send(batch []span) {
+	b, err := json.Marshal(newTracesPayload(batch))
+	if err != nil {
+		e.l.Error().Err(err).Msg("Failed to marshal spans")
+		return
+	}
+	res, err := e.client.Post(e.Endpoint, "application/json", bytes.NewReader(b))
+	if err != nil {
+		e.l.Error().Err(err).Str("endpoint", utils.URLHost(e.Endpoint)).Msg("Failed to export spans")
+		return
+	}
+	body, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+	res.Body.Close()
+	if res.StatusCode >= 300 {
+		e.l.Error().Int("statusCode", res.StatusCode).Str("body", string(body)).Str("endpoint", utils.URLHost(e.Endpoint)).Msg("The otlp-endpoint rejected the spans")
+	}
+}
+
+// The types below are the json-encoding of OTLP (opentelemetry/proto/trace/v1)
+
+type tracesPayload struct {
+	ResourceSpans []resourceSpans `json:"resourceSpans"`
+}
+
+type resourceSpans struct {
+	Resource   resource     `json:"resource"`
+	ScopeSpans []scopeSpans `json:"scopeSpans"`
+}
+
+type resource struct {
+	Attributes []attribute `json:"attributes"`
+}
+
+type scopeSpans struct {
+	Scope scope  `json:"scope"`
+	Spans []span `json:"spans"`
+}
+
+type scope struct {
+	Name string `json:"name"`
+}
+
+type span struct {
+	TraceID           string      `json:"traceId"`
+	SpanID            string      `json:"spanId"`
+	ParentSpanID      string      `json:"parentSpanId,omitempty"`
+	Name              string      `json:"name"`
+	Kind              int         `json:"kind"`
+	StartTimeUnixNano string      `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string      `json:"endTimeUnixNano"`
+	Attributes        []attribute `json:"attributes"`
+	Status            status      `json:"status"`
+}
+
+type status struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type attribute struct {
+	Key   string         `json:"key"`
+	Value attributeValue `json:"value"`
+}
+
+type attributeValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	// 64-bit integers are encoded as strings in the json-encoding
+	IntValue *string `json:"intValue,omitempty"`
+}
+
+func stringAttribute(key, value string) attribute {
+	return attribute{key, attributeValue{StringValue: &value}}
+}
+
+func intAttribute(key string, value int) attribute {
+	s := strconv.Itoa(value)
+	return attribute{key, attributeValue{IntValue: &s}}
+}
+
+func newTracesPayload(spans []span) tracesPayload {
+	return tracesPayload{[]resourceSpans{{
+		Resource:   resource{[]attribute{stringAttribute("service.name", ServiceName)}},
+		ScopeSpans: []scopeSpans{{Scope: scope{ServiceName}, Spans: spans}},
+	}}}
+}
+
+func newSpan(stat requests.RequestStat, runID string) span {
+	s := span{
+		TraceID:           stat.TraceContext.TraceID,
+		SpanID:            stat.TraceContext.SpanID,
+		ParentSpanID:      stat.TraceContext.ParentSpanID,
+		Name:              "HTTP",
+		Kind:              spanKindClient,
+		StartTimeUnixNano: strconv.FormatInt(stat.Start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(stat.Start.Add(stat.Duration).UnixNano(), 10),
+		Attributes: []attribute{
+			stringAttribute("gobyoall.run_id", runID),
+			stringAttribute("gobyoall.request_id", stat.RequestID),
+			intAttribute("gobyoall.worker_id", stat.WorkerID),
+		},
+		Status: status{Code: statusCodeOk},
+	}
+	if t := stat.Trace; t != nil {
+		s.Name = "HTTP " + t.Method
+		s.Attributes = append(s.Attributes, stringAttribute("http.method", t.Method), stringAttribute("http.url", t.Url))
+	}
+	if stat.StatusCode != 0 {
+		s.Attributes = append(s.Attributes, intAttribute("http.status_code", int(stat.StatusCode)))
+	}
+	if stat.ErrorType != "" {
+		s.Attributes = append(s.Attributes, stringAttribute("gobyoall.error_type", string(stat.ErrorType)))
+		s.Status = status{Code: statusCodeError, Message: stat.Error}
+		if s.Status.Message == "" {
+			s.Status.Message = string(stat.ErrorType)
+		}
+	}
+	return s
+}
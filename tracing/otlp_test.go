@@ -0,0 +1,104 @@
+package tracing
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/runar-rkmedia/gabyoall/logger"
+	"github.com/runar-rkmedia/gabyoall/requests"
+)
+
+func TestExporter(t *testing.T) {
+	var lock sync.Mutex
+	var payloads []tracesPayload
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected request to %s with content-type %s", r.URL.Path, r.Header.Get("Content-Type"))
+		}
+		var p tracesPayload
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			t.Error(err)
+		}
+		lock.Lock()
+		payloads = append(payloads, p)
+		lock.Unlock()
+	}))
+	defer srv.Close()
+
+	e, err := NewExporter(logger.GetLogger("test"), srv.URL, "run-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.BatchSize = 2
+	start := time.Unix(1700000000, 0)
+	tc := requests.NewTraceContext().Child()
+	ok := requests.RequestStat{Start: start, Duration: 20 * time.Millisecond, RequestID: "a", TraceContext: tc, Trace: &requests.RequestTrace{Method: "POST", Url: "http://example.com/graphql"}}
+	ok.StatusCode = 200
+	failed := requests.RequestStat{Start: start, Duration: time.Second, RequestID: "b", ErrorType: "Timeout", TraceContext: requests.NewTraceContext()}
+	failed.Error = "context deadline exceeded"
+	for _, s := range []requests.RequestStat{ok, failed, ok} {
+		e.Export(s)
+	}
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+	e.Export(ok)
+
+	var spans []span
+	for _, p := range payloads {
+		if len(p.ResourceSpans) != 1 || *p.ResourceSpans[0].Resource.Attributes[0].Value.StringValue != ServiceName {
+			t.Fatalf("unexpected resource in %#v", p)
+		}
+		spans = append(spans, p.ResourceSpans[0].ScopeSpans[0].Spans...)
+	}
+	if len(payloads) != 2 || len(spans) != 3 {
+		t.Fatalf("expected 3 spans in 2 batches, got %d spans in %d batches", len(spans), len(payloads))
+	}
+	attributes := func(s span) map[string]string {
+		m := map[string]string{}
+		for _, a := range s.Attributes {
+			if a.Value.StringValue != nil {
+				m[a.Key] = *a.Value.StringValue
+			} else {
+				m[a.Key] = *a.Value.IntValue
+			}
+		}
+		return m
+	}
+	tests := []struct {
+		name       string
+		span       span
+		wantName   string
+		wantStatus int
+		wantAttrs  map[string]string
+	}{
+		{"successful", spans[0], "HTTP POST", statusCodeOk, map[string]string{"gobyoall.run_id": "run-1", "gobyoall.request_id": "a", "http.status_code": "200", "http.method": "POST"}},
+		{"failed", spans[1], "HTTP", statusCodeError, map[string]string{"gobyoall.run_id": "run-1", "gobyoall.error_type": "Timeout"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.span.Name != tt.wantName || tt.span.Status.Code != tt.wantStatus || tt.span.Kind != spanKindClient {
+				t.Errorf("got name %s, status %d and kind %d", tt.span.Name, tt.span.Status.Code, tt.span.Kind)
+			}
+			attrs := attributes(tt.span)
+			for k, v := range tt.wantAttrs {
+				if attrs[k] != v {
+					t.Errorf("attribute %s = %q, want %q", k, attrs[k], v)
+				}
+			}
+		})
+	}
+	if spans[0].TraceID != tc.TraceID || spans[0].SpanID != tc.SpanID || spans[0].ParentSpanID != tc.ParentSpanID {
+		t.Errorf("the span should have the ids of the traceparent")
+	}
+	if spans[1].ParentSpanID != "" {
+		t.Errorf("a span without a parent should be a root-span, got parent %s", spans[1].ParentSpanID)
+	}
+	if spans[0].StartTimeUnixNano != "1700000000000000000" || spans[0].EndTimeUnixNano != "1700000000020000000" {
+		t.Errorf("unexpected timestamps %s - %s", spans[0].StartTimeUnixNano, spans[0].EndTimeUnixNano)
+	}
+}
@@ -1,6 +1,11 @@
 package utils
 
-import "strings"
+import (
+	"net/url"
+	"strings"
+)
+
+const redacted = "**REDACTED**"
 
 func PrepareUrl(s string) string {
 	if !strings.HasPrefix(s, "http") {
@@ -15,3 +20,32 @@ func UrlsAreEqual(a, b string) bool {
 	B := PrepareUrl(b)
 	return A == B
 }
+
+// RedactURL replaces the userinfo and the query of the url, since they may hold credentials, like a token.
+// Urls which cannot be parsed are replaced entirely.
+func RedactURL(s string) string {
+	if s == "" {
+		return s
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return redacted
+	}
+	if u.User != nil {
+		// The asterisks would be escaped in the userinfo
+		u.User = url.User("REDACTED")
+	}
+	if u.RawQuery != "" {
+		u.RawQuery = redacted
+	}
+	return u.String()
+}
+
+// URLHost returns only the scheme and host of the url, like for logging
+func URLHost(s string) string {
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" {
+		return redacted
+	}
+	return u.Scheme + "://" + u.Host
+}
@@ -22,3 +22,12 @@ func PrettyDuration(d time.Duration) string {
 	return d.String()
 
 }
+
+// Truncate shortens the string to at most n characters, ending with an ellipsis if it was cut
+func Truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
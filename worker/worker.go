@@ -1,22 +1,26 @@
 package worker
 
 import (
+	"net/http"
 	"time"
 
 	"github.com/runar-rkmedia/gabyoall/cmd"
 	"github.com/runar-rkmedia/gabyoall/requests"
 )
 
-type WorkThing struct{}
+type WorkThing struct {
+	// Cookies to seed each virtual user with, for instance from a login-step in dynamic auth.
+	Cookies []*http.Cookie
+}
 
 func (w WorkThing) Run(endpoint requests.Endpoint, config cmd.Config, query requests.Request) (chan requests.RequestStat, chan Job) {
 	jobCh := make(chan Job, config.RequestCount)
 	resultCh := make(chan requests.RequestStat, config.RequestCount)
 	startTime := time.Now()
 	// Create work
-	for w := 0; w < config.Concurrency; w++ {
+	for i := 0; i < config.Concurrency; i++ {
 		// FIXME: this probably requires a lot of memory
-		go worker(startTime, w, resultCh, jobCh)
+		go worker(startTime, requests.NewVirtualUser(i, endpoint.Url, w.Cookies), resultCh, jobCh)
 	}
 
 	// Create
@@ -39,9 +43,11 @@ type Job struct {
 	query    *requests.Request
 }
 
-func worker(startTime time.Time, id int, ch chan requests.RequestStat, jobCh chan Job) {
+// Each worker acts as a virtual user, with its own cookies and variables.
+func worker(startTime time.Time, vu *requests.VirtualUser, ch chan requests.RequestStat, jobCh chan Job) {
 	for job := range jobCh {
-		_, stat, _ := job.endpoint.RunQuery(startTime, *job.query, job.config.OkStatusCodes)
+		vu.Next(job.config.ResetSessionEvery)
+		_, stat, _ := job.endpoint.RunQuery(startTime, *job.query, job.config.OkStatusCodes, vu)
 		ch <- stat
 	}
 }